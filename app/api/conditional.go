@@ -0,0 +1,197 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+// ConditionalResponse writes data as a 200 JSON response with a strong ETag
+// computed from the body and, when lastModified is set, a Last-Modified
+// header. It answers 304 Not Modified instead when the request's
+// If-None-Match or If-Modified-Since validators show the client is current.
+func ConditionalResponse(w http.ResponseWriter, r *http.Request, data any, lastModified time.Time) {
+	body, err := encode(data)
+	if err != nil {
+		ErrorResponse(w, r, http.StatusInternalServerError, CodeInternal, "failed to encode response")
+		return
+	}
+	sum := sha256.Sum256(body)
+	writeConditional(w, r, body, `"`+hex.EncodeToString(sum[:16])+`"`, lastModified)
+}
+
+// VersionedResponse is ConditionalResponse for a single resource whose ETag
+// is derived from row versions, see VersionETag, so that it can be sent back
+// in If-Match.
+func VersionedResponse(w http.ResponseWriter, r *http.Request, data any, etag string, lastModified time.Time) {
+	body, err := encode(data)
+	if err != nil {
+		ErrorResponse(w, r, http.StatusInternalServerError, CodeInternal, "failed to encode response")
+		return
+	}
+	writeConditional(w, r, body, etag, lastModified)
+}
+
+func encode(data any) ([]byte, error) {
+	body, err := json.Marshal(data)
+	return append(body, '\n'), err
+}
+
+func writeConditional(w http.ResponseWriter, r *http.Request, body []byte, etag string, lastModified time.Time) {
+	header := w.Header()
+	header.Set("ETag", etag)
+	if !lastModified.IsZero() {
+		header.Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if notModified(r, etag, lastModified) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	header.Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// VersionETag formats row versions as a strong entity tag. The first version
+// identifies the resource itself; the others cover data embedded from related
+// rows so that the tag changes with them.
+func VersionETag(versions ...uint) string {
+	parts := make([]string, len(versions))
+	for i, v := range versions {
+		parts[i] = strconv.FormatUint(uint64(v), 10)
+	}
+	return `"` + strings.Join(parts, ".") + `"`
+}
+
+// ErrPreconditionRequired is returned by IfMatchVersion when a request that
+// modifies a resource carries no If-Match header.
+var ErrPreconditionRequired = errors.New("If-Match header is required")
+
+// IfMatchVersion returns the resource version the request's If-Match header
+// expects, or models.AnyVersion for "*". Weak or malformed tags can never
+// match and fail with a precondition error.
+func IfMatchVersion(r *http.Request) (uint, error) {
+	ifMatch := strings.TrimSpace(r.Header.Get("If-Match"))
+	switch {
+	case ifMatch == "":
+		return 0, ErrPreconditionRequired
+	case ifMatch == "*":
+		return models.AnyVersion, nil
+	}
+
+	stale := &models.Error{Kind: models.ErrPreconditionFailed, Message: "If-Match does not name a current version of the resource"}
+	tag, ok := strings.CutPrefix(ifMatch, `"`)
+	if !ok {
+		return 0, stale
+	}
+	tag, ok = strings.CutSuffix(tag, `"`)
+	if !ok {
+		return 0, stale
+	}
+	first, _, _ := strings.Cut(tag, ".")
+	version, err := strconv.ParseUint(first, 10, 32)
+	if err != nil || version == 0 {
+		return 0, stale
+	}
+	return uint(version), nil
+}
+
+// notModified evaluates the validators of a GET or HEAD request as RFC 9110
+// section 13.2.2 orders them: If-None-Match takes precedence and
+// If-Modified-Since is only considered without it.
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return matchesETag(inm, etag)
+	}
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && !lastModified.IsZero() {
+		since, err := http.ParseTime(ims)
+		return err == nil && !lastModified.Truncate(time.Second).After(since)
+	}
+	return false
+}
+
+// matchesETag reports whether the If-None-Match list contains etag, using
+// the weak comparison the header calls for.
+func matchesETag(list, etag string) bool {
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// CacheControl sets the Cache-Control header of successful responses from
+// the policy configured for the matched route pattern, e.g. "GET /catalog",
+// whatever the API version. The policy is meant for what anyone may see:
+// responses to requests carrying credentials are only cached privately and
+// revalidated, and those including deleted data, which only admins see, are
+// not stored at all. Responses vary on the credential headers so shared
+// caches never serve one caller's response to another. It must sit below
+// every middleware that replaces the request so the pattern the mux stores
+// on it is visible.
+func CacheControl(policies map[string]string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(&cacheControlWriter{ResponseWriter: w, r: r, policies: policies}, r)
+		})
+	}
+}
+
+// credentialHeaders are the request headers the authenticator reads.
+var credentialHeaders = []string{"Authorization", "X-API-Key"}
+
+type cacheControlWriter struct {
+	http.ResponseWriter
+	r        *http.Request
+	policies map[string]string
+	written  bool
+}
+
+func (c *cacheControlWriter) WriteHeader(status int) {
+	if !c.written {
+		c.written = true
+		header := c.Header()
+		if policy, ok := c.policies[RoutePattern(c.r)]; ok && status < http.StatusBadRequest && header.Get("Cache-Control") == "" {
+			header.Set("Cache-Control", c.policy(policy))
+			header.Add("Vary", strings.Join(credentialHeaders, ", "))
+		}
+	}
+	c.ResponseWriter.WriteHeader(status)
+}
+
+// policy narrows the configured policy to what the request may share.
+func (c *cacheControlWriter) policy(configured string) string {
+	if includeDeleted, _ := strconv.ParseBool(c.r.URL.Query().Get("include_deleted")); includeDeleted {
+		return "no-store"
+	}
+	for _, name := range credentialHeaders {
+		if c.r.Header.Get(name) != "" {
+			return "private, no-cache"
+		}
+	}
+	return configured
+}
+
+func (c *cacheControlWriter) Write(b []byte) (int, error) {
+	if !c.written {
+		c.WriteHeader(http.StatusOK)
+	}
+	return c.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (c *cacheControlWriter) Unwrap() http.ResponseWriter { return c.ResponseWriter }
@@ -0,0 +1,172 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mytheresa/go-hiring-challenge/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConditionalResponse(t *testing.T) {
+	modified := time.Date(2025, 3, 1, 12, 30, 15, 500, time.UTC)
+	data := map[string]string{"code": "PROD001"}
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		ConditionalResponse(recorder, req, data, modified)
+		return recorder
+	}
+
+	first := serve(httptest.NewRequest("GET", "/catalog/PROD001", nil))
+	etag := first.Header().Get("ETag")
+
+	t.Run("sets validators on a full response", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, first.Code)
+		assert.Equal(t, "application/json", first.Header().Get("Content-Type"))
+		assert.Regexp(t, `^"[0-9a-f]{32}"$`, etag)
+		assert.Equal(t, "Sat, 01 Mar 2025 12:30:15 GMT", first.Header().Get("Last-Modified"))
+		assert.JSONEq(t, `{"code":"PROD001"}`, first.Body.String())
+	})
+
+	t.Run("answers 304 when If-None-Match matches", func(t *testing.T) {
+		for _, inm := range []string{etag, "W/" + etag, `"other", ` + etag, "*"} {
+			req := httptest.NewRequest("GET", "/catalog/PROD001", nil)
+			req.Header.Set("If-None-Match", inm)
+
+			recorder := serve(req)
+
+			assert.Equal(t, http.StatusNotModified, recorder.Code, inm)
+			assert.Empty(t, recorder.Body.String())
+			assert.Equal(t, etag, recorder.Header().Get("ETag"))
+		}
+	})
+
+	t.Run("answers 200 when the ETag changed, even if the date matches", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/catalog/PROD001", nil)
+		req.Header.Set("If-None-Match", `"stale"`)
+		req.Header.Set("If-Modified-Since", modified.Add(time.Hour).Format(http.TimeFormat))
+
+		assert.Equal(t, http.StatusOK, serve(req).Code)
+	})
+
+	t.Run("compares If-Modified-Since at second precision", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/catalog/PROD001", nil)
+		req.Header.Set("If-Modified-Since", first.Header().Get("Last-Modified"))
+		assert.Equal(t, http.StatusNotModified, serve(req).Code)
+
+		req.Header.Set("If-Modified-Since", modified.Add(-time.Second).Format(http.TimeFormat))
+		assert.Equal(t, http.StatusOK, serve(req).Code)
+	})
+
+	t.Run("omits Last-Modified when unknown", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		ConditionalResponse(recorder, httptest.NewRequest("GET", "/categories", nil), []string{}, time.Time{})
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Empty(t, recorder.Header().Get("Last-Modified"))
+	})
+}
+
+func TestCacheControl(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /catalog", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has("fail") {
+			ErrorResponse(w, r, http.StatusBadRequest, CodeBadRequest, "bad")
+			return
+		}
+		w.Write([]byte("ok"))
+	})
+	mux.HandleFunc("GET /v2/catalog", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("ok")) })
+	mux.HandleFunc("GET /metrics", func(w http.ResponseWriter, r *http.Request) {})
+	handler := CacheControl(map[string]string{"GET /catalog": "max-age=60"})(mux)
+
+	serve := func(target string, headers ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", target, nil)
+		for i := 0; i < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	t.Run("applies the policy of the matched route", func(t *testing.T) {
+		recorder := serve("/catalog")
+
+		require.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "max-age=60", recorder.Header().Get("Cache-Control"))
+		assert.Equal(t, "Authorization, X-API-Key", recorder.Header().Get("Vary"))
+	})
+
+	t.Run("keeps responses to authenticated requests out of shared caches", func(t *testing.T) {
+		for _, header := range []string{"Authorization", "X-API-Key"} {
+			recorder := serve("/catalog", header, "secret")
+
+			assert.Equal(t, "private, no-cache", recorder.Header().Get("Cache-Control"), header)
+			assert.Equal(t, "Authorization, X-API-Key", recorder.Header().Get("Vary"), header)
+		}
+	})
+
+	t.Run("never stores responses including deleted data", func(t *testing.T) {
+		assert.Equal(t, "no-store", serve("/catalog?include_deleted=true", "X-API-Key", "secret").Header().Get("Cache-Control"))
+		assert.Equal(t, "max-age=60", serve("/catalog?include_deleted=false").Header().Get("Cache-Control"))
+	})
+
+	t.Run("applies the policy to every API version of the route", func(t *testing.T) {
+		assert.Equal(t, "max-age=60", serve("/v2/catalog").Header().Get("Cache-Control"))
+	})
+
+	t.Run("leaves errors and unconfigured routes alone", func(t *testing.T) {
+		assert.Empty(t, serve("/catalog?fail=1").Header().Get("Cache-Control"))
+		assert.Empty(t, serve("/metrics").Header().Get("Cache-Control"))
+	})
+}
+
+func TestIfMatchVersion(t *testing.T) {
+	ifMatch := func(value string) (uint, error) {
+		req := httptest.NewRequest("PUT", "/categories/shoes", nil)
+		if value != "" {
+			req.Header.Set("If-Match", value)
+		}
+		return IfMatchVersion(req)
+	}
+
+	t.Run("reads the resource version from the first component of the tag", func(t *testing.T) {
+		for value, expected := range map[string]uint{`"3"`: 3, `"7.2"`: 7, VersionETag(12, 4): 12, "*": models.AnyVersion} {
+			version, err := ifMatch(value)
+			require.NoError(t, err, value)
+			assert.Equal(t, expected, version, value)
+		}
+	})
+
+	t.Run("requires the header", func(t *testing.T) {
+		_, err := ifMatch("")
+		assert.ErrorIs(t, err, ErrPreconditionRequired)
+	})
+
+	t.Run("rejects weak and malformed tags as failed preconditions", func(t *testing.T) {
+		for _, value := range []string{`W/"3"`, `3`, `"abc"`, `"0"`, `"3`} {
+			_, err := ifMatch(value)
+			assert.ErrorIs(t, err, models.ErrPreconditionFailed, value)
+		}
+	})
+}
+
+func TestVersionedResponse(t *testing.T) {
+	req := httptest.NewRequest("GET", "/categories/shoes", nil)
+	req.Header.Set("If-None-Match", `"4"`)
+
+	recorder := httptest.NewRecorder()
+	VersionedResponse(recorder, req, map[string]string{"code": "shoes"}, VersionETag(4), time.Time{})
+	assert.Equal(t, http.StatusNotModified, recorder.Code)
+	assert.Equal(t, `"4"`, recorder.Header().Get("ETag"))
+
+	recorder = httptest.NewRecorder()
+	VersionedResponse(recorder, req, map[string]string{"code": "shoes"}, VersionETag(5), time.Time{})
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, `"5"`, recorder.Header().Get("ETag"))
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+// StatusFromError maps domain errors from the models package onto HTTP status codes.
+func StatusFromError(err error) int {
+	status, _ := classify(err)
+	return status
+}
+
+// CodeFromError maps domain errors from the models package onto the stable
+// codes of problem responses, for errors reported outside of them.
+func CodeFromError(err error) string {
+	_, code := classify(err)
+	return code
+}
+
+func classify(err error) (status int, code string) {
+	switch {
+	case errors.Is(err, models.ErrNotFound):
+		return http.StatusNotFound, CodeNotFound
+	case errors.Is(err, models.ErrConflict):
+		return http.StatusConflict, CodeConflict
+	case errors.Is(err, models.ErrValidation):
+		return http.StatusUnprocessableEntity, CodeValidationFailed
+	case errors.Is(err, models.ErrPreconditionFailed):
+		return http.StatusPreconditionFailed, CodePreconditionFailed
+	case errors.Is(err, models.ErrUnavailable):
+		return http.StatusServiceUnavailable, CodeUnavailable
+	default:
+		return http.StatusInternalServerError, CodeInternal
+	}
+}
+
+// HandleError writes the problem response for a failed request. Decoding and
+// validation errors and domain errors carry a client-safe message; anything
+// else is reported with fallback.
+func HandleError(w http.ResponseWriter, r *http.Request, err error, fallback string) {
+	var maxBytesErr *http.MaxBytesError
+	var decodeErr *DecodeError
+	var validationErr *ValidationError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		ErrorResponse(w, r, http.StatusRequestEntityTooLarge, CodeBodyTooLarge,
+			fmt.Sprintf("request body must not exceed %d bytes", maxBytesErr.Limit))
+		return
+	case errors.As(err, &decodeErr):
+		ErrorResponse(w, r, http.StatusBadRequest, CodeInvalidBody, decodeErr.Detail)
+		return
+	case errors.Is(err, ErrPreconditionRequired):
+		ErrorResponse(w, r, http.StatusPreconditionRequired, CodePreconditionRequired,
+			"send the ETag of the current version in If-Match")
+		return
+	case errors.As(err, &validationErr):
+		problem := NewProblem(http.StatusBadRequest, CodeValidationFailed, "request has invalid fields")
+		problem.Errors = validationErr.Errors
+		ProblemResponse(w, r, problem)
+		return
+	}
+
+	status, code := classify(err)
+
+	detail := fallback
+	var domainErr *models.Error
+	if errors.As(err, &domainErr) && status != http.StatusInternalServerError {
+		detail = domainErr.Message
+	}
+
+	if status == http.StatusServiceUnavailable {
+		w.Header().Set("Retry-After", "5")
+	}
+	ErrorResponse(w, r, status, code, detail)
+}
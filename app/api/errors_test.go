@@ -0,0 +1,47 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mytheresa/go-hiring-challenge/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleError(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		status  int
+		code    string
+		message string
+	}{
+		{"not found", &models.Error{Kind: models.ErrNotFound, Message: "product not found"}, http.StatusNotFound, CodeNotFound, "product not found"},
+		{"conflict", &models.Error{Kind: models.ErrConflict, Message: "category already exists"}, http.StatusConflict, CodeConflict, "category already exists"},
+		{"validation", &models.Error{Kind: models.ErrValidation, Message: "invalid category"}, http.StatusUnprocessableEntity, CodeValidationFailed, "invalid category"},
+		{"unavailable", &models.Error{Kind: models.ErrUnavailable, Message: "database unavailable"}, http.StatusServiceUnavailable, CodeUnavailable, "database unavailable"},
+		{"stale version", &models.Error{Kind: models.ErrPreconditionFailed, Message: "product has been modified since it was read"}, http.StatusPreconditionFailed, CodePreconditionFailed, "product has been modified since it was read"},
+		{"missing If-Match", ErrPreconditionRequired, http.StatusPreconditionRequired, CodePreconditionRequired, "send the ETag of the current version in If-Match"},
+		{"unknown", errors.New("boom"), http.StatusInternalServerError, CodeInternal, "failed to fetch"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			HandleError(recorder, httptest.NewRequest("GET", "/catalog/PROD001", nil), tt.err, "failed to fetch")
+
+			assert.Equal(t, tt.status, recorder.Code)
+			var problem Problem
+			require.NoError(t, json.NewDecoder(recorder.Body).Decode(&problem))
+			assert.Equal(t, tt.code, problem.Code)
+			assert.Equal(t, tt.message, problem.Detail)
+			if tt.err != ErrPreconditionRequired {
+				assert.Equal(t, tt.code, CodeFromError(tt.err))
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChain(t *testing.T) {
+	var order []string
+	mw := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	h := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		order = append(order, "handler")
+	}), mw("first"), mw("second"))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	assert.Equal(t, []string{"first", "second", "handler"}, order)
+}
+
+func TestAssignRequestID(t *testing.T) {
+	var seen string
+	h := AssignRequestID()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestID(r.Context())
+	}))
+
+	t.Run("propagates a well-formed incoming id", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set(RequestIDHeader, "abc-123")
+		recorder := httptest.NewRecorder()
+
+		h.ServeHTTP(recorder, req)
+
+		assert.Equal(t, "abc-123", seen)
+		assert.Equal(t, "abc-123", recorder.Header().Get(RequestIDHeader))
+	})
+
+	t.Run("generates an id when missing or malformed", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set(RequestIDHeader, "has spaces\n")
+		recorder := httptest.NewRecorder()
+
+		h.ServeHTTP(recorder, req)
+
+		assert.Len(t, seen, 32)
+		assert.Equal(t, seen, recorder.Header().Get(RequestIDHeader))
+	})
+}
+
+func TestAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /catalog/{code}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+		w.Write([]byte("short"))
+	})
+	h := Chain(mux, AssignRequestID(), AccessLog(logger))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/catalog/PROD001", nil))
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "request", entry["msg"])
+	assert.Equal(t, "GET", entry["method"])
+	assert.Equal(t, "GET /catalog/{code}", entry["route"])
+	assert.Equal(t, "/catalog/PROD001", entry["path"])
+	assert.EqualValues(t, http.StatusTeapot, entry["status"])
+	assert.EqualValues(t, 5, entry["bytes"])
+	assert.NotEmpty(t, entry["request_id"])
+	assert.Contains(t, entry, "latency")
+}
+
+func TestRecover(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	h := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}), AssignRequestID(), Recover(logger))
+
+	req := httptest.NewRequest("GET", "/catalog", nil)
+	req.Header.Set(RequestIDHeader, "req-1")
+	recorder := httptest.NewRecorder()
+
+	h.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	assert.Equal(t, ProblemContentType, recorder.Header().Get("Content-Type"))
+	assert.Contains(t, recorder.Body.String(), `"request_id":"req-1"`)
+	assert.True(t, strings.Contains(buf.String(), `"panic":"boom"`), buf.String())
+}
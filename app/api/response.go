@@ -6,13 +6,15 @@ import (
 )
 
 func OKResponse(w http.ResponseWriter, data any) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(data)
+	JSONResponse(w, http.StatusOK, data)
 }
 
-func ErrorResponse(w http.ResponseWriter, status int, message string) {
+func JSONResponse(w http.ResponseWriter, status int, data any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(map[string]string{"error": message})
+	json.NewEncoder(w).Encode(data)
+}
+
+func ErrorResponse(w http.ResponseWriter, r *http.Request, status int, code, detail string) {
+	ProblemResponse(w, r, NewProblem(status, code, detail))
 }
@@ -29,14 +29,79 @@ func TestOKResponse(t *testing.T) {
 }
 
 func TestErrorResponse(t *testing.T) {
-	t.Run("json response for a given http status code", func(t *testing.T) {
+	t.Run("problem json response for a given http status code", func(t *testing.T) {
 		recorder := httptest.NewRecorder()
-		ErrorResponse(recorder, http.StatusInternalServerError, "Some error occurred")
+		req := httptest.NewRequest("GET", "/catalog", nil)
+		req.Header.Set(RequestIDHeader, "req-123")
+		ErrorResponse(recorder, req, http.StatusInternalServerError, CodeInternal, "Some error occurred")
 
 		assert.Equal(t, http.StatusInternalServerError, recorder.Code, "Expected status code 500 Internal Server Error")
-		assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"), "Expected Content-Type to be application/json")
+		assert.Equal(t, "application/problem+json", recorder.Header().Get("Content-Type"), "Expected Content-Type to be application/problem+json")
+
+		expected := `{
+			"type": "/problems/internal-error",
+			"title": "Internal Server Error",
+			"status": 500,
+			"detail": "Some error occurred",
+			"instance": "/catalog",
+			"code": "internal_error",
+			"request_id": "req-123"
+		}`
+		assert.JSONEq(t, expected, recorder.Body.String(), "Response body does not match expected")
+	})
+
+	t.Run("includes per-field errors and the request id from the context", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/categories", nil)
+		req = req.WithContext(WithRequestID(req.Context(), "ctx-id"))
+
+		problem := NewProblem(http.StatusBadRequest, CodeValidationFailed, "request is invalid")
+		problem.Errors = []FieldError{{Field: "code", Code: "required", Message: "code is required"}}
+		ProblemResponse(recorder, req, problem)
 
-		expected := `{"error":"Some error occurred"}`
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		expected := `{
+			"type": "/problems/validation-failed",
+			"title": "Bad Request",
+			"status": 400,
+			"detail": "request is invalid",
+			"instance": "/categories",
+			"code": "validation_failed",
+			"errors": [{"field": "code", "code": "required", "message": "code is required"}],
+			"request_id": "ctx-id"
+		}`
 		assert.JSONEq(t, expected, recorder.Body.String(), "Response body does not match expected")
 	})
+
+	t.Run("merges extension members without overriding standard ones", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/categories", nil)
+
+		problem := NewProblem(http.StatusForbidden, CodeForbidden, "missing permission categories:write")
+		problem.Extensions = map[string]any{"missing_permission": "categories:write", "status": 200}
+		ProblemResponse(recorder, req, problem)
+
+		assert.Equal(t, http.StatusForbidden, recorder.Code)
+		expected := `{
+			"type": "/problems/forbidden",
+			"title": "Forbidden",
+			"status": 403,
+			"detail": "missing permission categories:write",
+			"instance": "/categories",
+			"code": "forbidden",
+			"missing_permission": "categories:write"
+		}`
+		assert.JSONEq(t, expected, recorder.Body.String(), "Response body does not match expected")
+	})
+}
+
+func TestJSONResponse(t *testing.T) {
+	t.Run("json response with a custom status code", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		JSONResponse(recorder, http.StatusServiceUnavailable, map[string]string{"status": "draining"})
+
+		assert.Equal(t, http.StatusServiceUnavailable, recorder.Code, "Expected status code 503 Service Unavailable")
+		assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"), "Expected Content-Type to be application/json")
+		assert.JSONEq(t, `{"status":"draining"}`, recorder.Body.String(), "Response body does not match expected")
+	})
 }
@@ -0,0 +1,243 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/shopspring/decimal"
+)
+
+// DecodeError reports a request body that is not a single well-formed JSON
+// document matching the target type.
+type DecodeError struct {
+	Detail string
+	Err    error
+}
+
+func (e *DecodeError) Error() string { return e.Detail }
+func (e *DecodeError) Unwrap() error { return e.Err }
+
+// ValidationError lists every field that broke a rule.
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		messages[i] = fe.Message
+	}
+	return "validation failed: " + strings.Join(messages, "; ")
+}
+
+// DecodeJSON decodes the request body into dst, rejecting unknown fields and
+// anything after the first JSON value. Oversized bodies surface as *http.MaxBytesError.
+func DecodeJSON(r *http.Request, dst any) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(dst); err != nil {
+		return decodeError(err)
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return err
+		}
+		return &DecodeError{Detail: "request body must contain a single JSON object", Err: err}
+	}
+	return nil
+}
+
+func decodeError(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return err
+	case errors.Is(err, io.EOF):
+		return &DecodeError{Detail: "request body must not be empty", Err: err}
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		return &DecodeError{Detail: "request body contains malformed JSON", Err: err}
+	case errors.As(err, &typeErr):
+		return &DecodeError{Detail: fmt.Sprintf("field %q must be of type %s", typeErr.Field, typeErr.Type), Err: err}
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.TrimPrefix(err.Error(), "json: unknown field ")
+		return &DecodeError{Detail: "request body contains unknown field " + field, Err: err}
+	default:
+		return &DecodeError{Detail: "invalid request body", Err: err}
+	}
+}
+
+// Rule checks a single value. It returns nil when the value is acceptable.
+// Every rule except Required accepts the empty string, so optional fields
+// are only checked when present.
+type Rule func(value string) *FieldError
+
+// Validator collects the violations of every field before reporting them together.
+type Validator struct {
+	errors []FieldError
+}
+
+// Field checks value against rules in order and records the first violation.
+func (v *Validator) Field(name, value string, rules ...Rule) {
+	for _, rule := range rules {
+		if fe := rule(value); fe != nil {
+			fe.Field = name
+			fe.Message = name + " " + fe.Message
+			v.errors = append(v.errors, *fe)
+			return
+		}
+	}
+}
+
+// Err returns a *ValidationError listing every violation, or nil.
+func (v *Validator) Err() error {
+	if len(v.errors) == 0 {
+		return nil
+	}
+	return &ValidationError{Errors: v.errors}
+}
+
+func Required() Rule {
+	return func(value string) *FieldError {
+		if value == "" {
+			return &FieldError{Code: "required", Message: "is required"}
+		}
+		return nil
+	}
+}
+
+// Length requires between min and max characters.
+func Length(min, max int) Rule {
+	return func(value string) *FieldError {
+		n := utf8.RuneCountInString(value)
+		if value != "" && (n < min || n > max) {
+			return &FieldError{Code: "length", Message: fmt.Sprintf("must be between %d and %d characters", min, max)}
+		}
+		return nil
+	}
+}
+
+// Pattern requires the value to match re; description explains the format to clients.
+func Pattern(re *regexp.Regexp, description string) Rule {
+	return func(value string) *FieldError {
+		if value != "" && !re.MatchString(value) {
+			return &FieldError{Code: "pattern", Message: "must be " + description}
+		}
+		return nil
+	}
+}
+
+// OneOf requires the value to be one of allowed.
+func OneOf(allowed ...string) Rule {
+	return func(value string) *FieldError {
+		if value != "" && !slices.Contains(allowed, value) {
+			return &FieldError{Code: "enum", Message: "must be one of " + strings.Join(allowed, ", ")}
+		}
+		return nil
+	}
+}
+
+// IntRange requires a base-10 integer between min and max inclusive.
+func IntRange(min, max int) Rule {
+	return func(value string) *FieldError {
+		if value == "" {
+			return nil
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return &FieldError{Code: "type", Message: "must be an integer"}
+		}
+		if n < min || n > max {
+			return &FieldError{Code: "range", Message: fmt.Sprintf("must be between %d and %d", min, max)}
+		}
+		return nil
+	}
+}
+
+// Bool requires a boolean such as true, false, 1 or 0.
+func Bool() Rule {
+	return func(value string) *FieldError {
+		if _, err := strconv.ParseBool(value); value != "" && err != nil {
+			return &FieldError{Code: "type", Message: "must be a boolean"}
+		}
+		return nil
+	}
+}
+
+// DecimalRange requires a decimal number between min and max inclusive.
+func DecimalRange(min, max decimal.Decimal) Rule {
+	return func(value string) *FieldError {
+		if value == "" {
+			return nil
+		}
+		d, err := decimal.NewFromString(value)
+		if err != nil {
+			return &FieldError{Code: "type", Message: "must be a decimal number"}
+		}
+		if d.LessThan(min) || d.GreaterThan(max) {
+			return &FieldError{Code: "range", Message: fmt.Sprintf("must be between %s and %s", min, max)}
+		}
+		return nil
+	}
+}
+
+// DecimalPlaces requires a decimal number with at most places fraction
+// digits, so it is stored without rounding. Trailing zeros do not count.
+func DecimalPlaces(places int32) Rule {
+	return func(value string) *FieldError {
+		if value == "" {
+			return nil
+		}
+		d, err := decimal.NewFromString(value)
+		if err != nil {
+			return &FieldError{Code: "type", Message: "must be a decimal number"}
+		}
+		if !d.Equal(d.Truncate(places)) {
+			return &FieldError{Code: "scale", Message: fmt.Sprintf("must have at most %d decimal places", places)}
+		}
+		return nil
+	}
+}
+
+// HTTPURL requires an absolute http or https URL.
+func HTTPURL() Rule {
+	return func(value string) *FieldError {
+		if value == "" {
+			return nil
+		}
+		u, err := url.Parse(value)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return &FieldError{Code: "format", Message: "must be an absolute http or https URL"}
+		}
+		return nil
+	}
+}
+
+// BoolQuery reads the optional boolean query parameter name; malformed values
+// are reported as a *ValidationError.
+func BoolQuery(r *http.Request, name string) (bool, error) {
+	value := r.URL.Query().Get(name)
+	var v Validator
+	v.Field(name, value, Bool())
+	if err := v.Err(); err != nil {
+		return false, err
+	}
+	on, _ := strconv.ParseBool(value)
+	return on, nil
+}
+
+// SlugPattern matches lowercase, hyphen-separated identifiers such as "home-decor".
+var SlugPattern = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
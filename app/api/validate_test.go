@@ -0,0 +1,122 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	type payload struct {
+		Code string `json:"code"`
+	}
+
+	tests := []struct {
+		name   string
+		body   string
+		detail string
+	}{
+		{"empty body", ``, "request body must not be empty"},
+		{"malformed", `{"code":`, "request body contains malformed JSON"},
+		{"wrong type", `{"code":1}`, `field "code" must be of type string`},
+		{"unknown field", `{"code":"a","extra":true}`, `request body contains unknown field "extra"`},
+		{"trailing data", `{"code":"a"}garbage`, "request body must contain a single JSON object"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var dst payload
+			err := DecodeJSON(httptest.NewRequest("POST", "/", strings.NewReader(tt.body)), &dst)
+
+			var decodeErr *DecodeError
+			require.ErrorAs(t, err, &decodeErr)
+			assert.Equal(t, tt.detail, decodeErr.Detail)
+		})
+	}
+
+	t.Run("decodes a single object", func(t *testing.T) {
+		var dst payload
+		err := DecodeJSON(httptest.NewRequest("POST", "/", strings.NewReader(`{"code":"a"}`+"\n")), &dst)
+
+		require.NoError(t, err)
+		assert.Equal(t, "a", dst.Code)
+	})
+
+	t.Run("surfaces oversized bodies", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/", strings.NewReader(`{"code":"abcdefghij"}`))
+		req.Body = http.MaxBytesReader(httptest.NewRecorder(), req.Body, 5)
+
+		var dst payload
+		var maxBytesErr *http.MaxBytesError
+		assert.ErrorAs(t, DecodeJSON(req, &dst), &maxBytesErr)
+	})
+}
+
+func TestValidator(t *testing.T) {
+	t.Run("reports every violation at once", func(t *testing.T) {
+		var v Validator
+		v.Field("code", "", Required(), Length(1, 32))
+		v.Field("slug", "Not A Slug", Pattern(SlugPattern, "a lowercase slug"))
+		v.Field("size", "xl", OneOf("s", "m", "l"))
+		v.Field("limit", "0", IntRange(1, 100))
+		v.Field("price", "-1", DecimalRange(decimal.Zero, decimal.NewFromInt(10)))
+		v.Field("name", strings.Repeat("a", 33), Length(1, 32))
+		v.Field("include_deleted", "yes", Bool())
+		v.Field("url", "ftp://example.com", HTTPURL())
+
+		var validationErr *ValidationError
+		require.ErrorAs(t, v.Err(), &validationErr)
+		assert.Equal(t, []FieldError{
+			{Field: "code", Code: "required", Message: "code is required"},
+			{Field: "slug", Code: "pattern", Message: "slug must be a lowercase slug"},
+			{Field: "size", Code: "enum", Message: "size must be one of s, m, l"},
+			{Field: "limit", Code: "range", Message: "limit must be between 1 and 100"},
+			{Field: "price", Code: "range", Message: "price must be between 0 and 10"},
+			{Field: "name", Code: "length", Message: "name must be between 1 and 32 characters"},
+			{Field: "include_deleted", Code: "type", Message: "include_deleted must be a boolean"},
+			{Field: "url", Code: "format", Message: "url must be an absolute http or https URL"},
+		}, validationErr.Errors)
+	})
+
+	t.Run("limits decimal places without counting trailing zeros", func(t *testing.T) {
+		rule := DecimalPlaces(2)
+
+		assert.Nil(t, rule("12"))
+		assert.Nil(t, rule("12.34"))
+		assert.Nil(t, rule("12.3400"))
+		assert.Equal(t, &FieldError{Code: "scale", Message: "must have at most 2 decimal places"}, rule("12.345"))
+	})
+
+	t.Run("skips optional empty values", func(t *testing.T) {
+		var v Validator
+		v.Field("limit", "", IntRange(1, 100))
+		v.Field("category", "", Pattern(SlugPattern, "a lowercase slug"))
+		v.Field("include_deleted", "", Bool())
+		v.Field("url", "", HTTPURL())
+
+		assert.NoError(t, v.Err())
+	})
+
+	t.Run("maps to a 400 problem with field errors", func(t *testing.T) {
+		var v Validator
+		v.Field("limit", "abc", IntRange(1, 100))
+
+		recorder := httptest.NewRecorder()
+		HandleError(recorder, httptest.NewRequest("GET", "/catalog", nil), v.Err(), "invalid query")
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"field":"limit","code":"type"`)
+	})
+
+	t.Run("error message lists fields", func(t *testing.T) {
+		err := &ValidationError{Errors: []FieldError{{Field: "code", Message: "code is required"}}}
+		assert.True(t, errors.As(err, new(*ValidationError)))
+		assert.Equal(t, "validation failed: code is required", err.Error())
+	})
+}
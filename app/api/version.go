@@ -0,0 +1,94 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Router registers routes; it is implemented by *http.ServeMux, *Group and Groups.
+type Router interface {
+	Handle(pattern string, h http.Handler)
+}
+
+// Group registers routes on a mux under a path prefix, such as an API
+// version, wrapping each of them in the group's middleware.
+type Group struct {
+	mux    *http.ServeMux
+	prefix string
+	mws    []Middleware
+}
+
+// NewGroup creates a group of routes under prefix, e.g. "/v1", or at the
+// root for an empty prefix.
+func NewGroup(mux *http.ServeMux, prefix string, mws ...Middleware) *Group {
+	return &Group{
+		mux:    mux,
+		prefix: prefix,
+		mws:    mws,
+	}
+}
+
+// Handle registers h for a pattern such as "GET /catalog" under the group's prefix.
+func (g *Group) Handle(pattern string, h http.Handler) {
+	method, path, ok := strings.Cut(pattern, " ")
+	if !ok {
+		method, path = "", pattern
+	}
+	g.mux.Handle(strings.TrimSpace(method+" "+g.prefix+path), Chain(h, g.mws...))
+}
+
+// HandleFunc is Handle for a handler function.
+func (g *Group) HandleFunc(pattern string, h http.HandlerFunc) {
+	g.Handle(pattern, h)
+}
+
+// Groups registers the same route on several groups, for routes whose
+// behaviour does not differ between API versions.
+type Groups []*Group
+
+func (gs Groups) Handle(pattern string, h http.Handler) {
+	for _, g := range gs {
+		g.Handle(pattern, h)
+	}
+}
+
+func (gs Groups) HandleFunc(pattern string, h http.HandlerFunc) {
+	gs.Handle(pattern, h)
+}
+
+var versionPrefix = regexp.MustCompile(`^((?:[A-Z]+ )?)/v[0-9]+(/.*)?$`)
+
+// RoutePattern returns the pattern the mux matched without its API version
+// prefix, so that every version of a route shares its cache and rate limit
+// settings. Patterns are always unversioned in configuration.
+func RoutePattern(r *http.Request) string {
+	match := versionPrefix.FindStringSubmatch(r.Pattern)
+	if match == nil {
+		return r.Pattern
+	}
+	if match[2] == "" {
+		return match[1] + "/"
+	}
+	return match[1] + match[2]
+}
+
+// Deprecated announces on every response that a route is deprecated since
+// the given time (RFC 9745) and, when sunset is set, when it will stop being
+// served (RFC 8594). It links to the same path under successor.
+func Deprecated(since, sunset time.Time, successor string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := w.Header()
+			header.Set("Deprecation", "@"+strconv.FormatInt(since.Unix(), 10))
+			if !sunset.IsZero() {
+				header.Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+			}
+			header.Add("Link", fmt.Sprintf(`<%s%s>; rel="successor-version"`, successor, r.URL.EscapedPath()))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroup(t *testing.T) {
+	mux := http.NewServeMux()
+	tag := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Add("X-Group", name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	root := NewGroup(mux, "", tag("root"))
+	v1 := NewGroup(mux, "/v1", tag("v1"))
+	v2 := NewGroup(mux, "/v2")
+
+	var pattern, route string
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pattern, route = r.Pattern, RoutePattern(r)
+	})
+	Groups{root, v1}.HandleFunc("GET /catalog/{code}", h)
+	v2.Handle("GET /catalog/{code}", h)
+
+	tests := []struct {
+		path    string
+		pattern string
+		group   string
+	}{
+		{"/catalog/shirt", "GET /catalog/{code}", "root"},
+		{"/v1/catalog/shirt", "GET /v1/catalog/{code}", "v1"},
+		{"/v2/catalog/shirt", "GET /v2/catalog/{code}", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			res := httptest.NewRecorder()
+			mux.ServeHTTP(res, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			assert.Equal(t, http.StatusOK, res.Code)
+			assert.Equal(t, tt.pattern, pattern)
+			assert.Equal(t, "GET /catalog/{code}", route)
+			assert.Equal(t, tt.group, res.Header().Get("X-Group"))
+		})
+	}
+
+	t.Run("does not register other versions", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		mux.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/v3/catalog/shirt", nil))
+
+		assert.Equal(t, http.StatusNotFound, res.Code)
+	})
+}
+
+func TestRoutePattern(t *testing.T) {
+	tests := map[string]string{
+		"GET /v1/catalog":   "GET /catalog",
+		"GET /v12/catalog":  "GET /catalog",
+		"/v2/catalog":       "/catalog",
+		"GET /v2":           "GET /",
+		"GET /catalog":      "GET /catalog",
+		"GET /video/{code}": "GET /video/{code}",
+		"":                  "",
+	}
+	for pattern, want := range tests {
+		assert.Equal(t, want, RoutePattern(&http.Request{Pattern: pattern}), pattern)
+	}
+}
+
+func TestDeprecated(t *testing.T) {
+	since := time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2027, 4, 30, 0, 0, 0, 0, time.UTC)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	t.Run("announces deprecation, sunset and successor", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		Deprecated(since, sunset, "/v1")(next).ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/catalog/red%20shirt?limit=5", nil))
+
+		assert.Equal(t, http.StatusNoContent, res.Code)
+		assert.Equal(t, "@1792281600", res.Header().Get("Deprecation"))
+		assert.Equal(t, "Fri, 30 Apr 2027 00:00:00 GMT", res.Header().Get("Sunset"))
+		assert.Equal(t, `</v1/catalog/red%20shirt>; rel="successor-version"`, res.Header().Get("Link"))
+	})
+
+	t.Run("omits the sunset when none is planned", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		Deprecated(since, time.Time{}, "/v1")(next).ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/catalog", nil))
+
+		assert.NotEmpty(t, res.Header().Get("Deprecation"))
+		assert.Empty(t, res.Header().Get("Sunset"))
+	})
+}
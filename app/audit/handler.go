@@ -0,0 +1,99 @@
+package audit
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mytheresa/go-hiring-challenge/app/api"
+	"github.com/mytheresa/go-hiring-challenge/app/auth"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+type Response struct {
+	Entries []EntryResponse `json:"entries"`
+	Total   int64           `json:"total"`
+}
+
+type EntryResponse struct {
+	Entity    string          `json:"entity"`
+	Code      string          `json:"code"`
+	Action    string          `json:"action"`
+	Actor     string          `json:"actor"`
+	RequestID string          `json:"request_id"`
+	Changes   json.RawMessage `json:"changes"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type Handler struct {
+	repo models.AuditEntryRepository
+}
+
+func NewHandler(r models.AuditEntryRepository) *Handler {
+	return &Handler{
+		repo: r,
+	}
+}
+
+// HandleGet lists audit entries newest first, optionally for one entity and code.
+func (h *Handler) HandleGet(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var v api.Validator
+	v.Field("entity", query.Get("entity"), api.OneOf(models.AuditEntityProduct, models.AuditEntityVariant, models.AuditEntityCategory))
+	v.Field("code", query.Get("code"), api.Length(1, 32))
+	v.Field("offset", query.Get("offset"), api.IntRange(0, math.MaxInt32))
+	v.Field("limit", query.Get("limit"), api.IntRange(1, 100))
+	if err := v.Err(); err != nil {
+		api.HandleError(w, r, err, "invalid query parameters")
+		return
+	}
+
+	offset := 0
+	if offsetStr := query.Get("offset"); offsetStr != "" {
+		offset, _ = strconv.Atoi(offsetStr)
+	}
+
+	limit := 10
+	if limitStr := query.Get("limit"); limitStr != "" {
+		limit, _ = strconv.Atoi(limitStr)
+	}
+
+	entries, total, err := h.repo.GetAll(r.Context(), offset, limit, query.Get("entity"), query.Get("code"))
+	if err != nil {
+		api.HandleError(w, r, err, "failed to fetch audit entries")
+		return
+	}
+
+	responses := make([]EntryResponse, len(entries))
+	for i, e := range entries {
+		responses[i] = EntryResponse{
+			Entity:    e.Entity,
+			Code:      e.Code,
+			Action:    e.Action,
+			Actor:     e.Actor,
+			RequestID: e.RequestID,
+			Changes:   e.Changes,
+			CreatedAt: e.CreatedAt,
+		}
+	}
+
+	api.OKResponse(w, Response{
+		Entries: responses,
+		Total:   total,
+	})
+}
+
+// Attribute makes the repository writes of h record the authenticated caller
+// and the request ID in the audit log. It must run after authentication.
+func Attribute(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		actor := models.Actor{RequestID: api.RequestIDFromRequest(r)}
+		if p := auth.PrincipalFromContext(r.Context()); p != nil {
+			actor.Subject = p.Subject
+		}
+		h(w, r.WithContext(models.WithActor(r.Context(), actor)))
+	}
+}
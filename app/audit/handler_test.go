@@ -0,0 +1,77 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mytheresa/go-hiring-challenge/app/api"
+	"github.com/mytheresa/go-hiring-challenge/app/auth"
+	"github.com/mytheresa/go-hiring-challenge/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockAuditEntryRepository struct {
+	mock.Mock
+}
+
+func (m *MockAuditEntryRepository) GetAll(ctx context.Context, offset, limit int, entity, code string) ([]models.AuditEntry, int64, error) {
+	args := m.Called(ctx, offset, limit, entity, code)
+	return args.Get(0).([]models.AuditEntry), args.Get(1).(int64), args.Error(2)
+}
+
+func TestHandler_HandleGet(t *testing.T) {
+	t.Run("returns the entries of one product", func(t *testing.T) {
+		mockRepo := new(MockAuditEntryRepository)
+		handler := NewHandler(mockRepo)
+		mockRepo.On("GetAll", mock.Anything, 20, 10, "product", "PROD001").Return([]models.AuditEntry{{
+			Entity:    models.AuditEntityProduct,
+			Code:      "PROD001",
+			Action:    models.AuditActionUpdate,
+			Actor:     "alice",
+			RequestID: "req-1",
+			Changes:   json.RawMessage(`{"price":{"before":"10.00","after":"12.00"}}`),
+			CreatedAt: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		}}, int64(21), nil)
+
+		recorder := httptest.NewRecorder()
+		handler.HandleGet(recorder, httptest.NewRequest("GET", "/audit?entity=product&code=PROD001&offset=20", nil))
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.JSONEq(t, `{"entries":[{"entity":"product","code":"PROD001","action":"update","actor":"alice","request_id":"req-1",
+			"changes":{"price":{"before":"10.00","after":"12.00"}},"created_at":"2024-03-01T12:00:00Z"}],"total":21}`, recorder.Body.String())
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("rejects unknown entities and bad pagination", func(t *testing.T) {
+		mockRepo := new(MockAuditEntryRepository)
+		handler := NewHandler(mockRepo)
+
+		recorder := httptest.NewRecorder()
+		handler.HandleGet(recorder, httptest.NewRequest("GET", "/audit?entity=order&limit=0", nil))
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"field":"entity"`)
+		assert.Contains(t, recorder.Body.String(), `"field":"limit"`)
+		mockRepo.AssertNotCalled(t, "GetAll", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestAttribute(t *testing.T) {
+	var seen models.Actor
+	handler := Attribute(func(w http.ResponseWriter, r *http.Request) {
+		seen = models.ActorFromContext(r.Context())
+	})
+
+	req := httptest.NewRequest("PATCH", "/catalog/PROD001", nil)
+	ctx := api.WithRequestID(req.Context(), "req-1")
+	ctx = auth.WithPrincipal(ctx, &auth.Principal{Subject: "alice"})
+	handler(httptest.NewRecorder(), req.WithContext(ctx))
+
+	require.Equal(t, models.Actor{Subject: "alice", RequestID: "req-1"}, seen)
+}
@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/mytheresa/go-hiring-challenge/app/api"
+	"github.com/mytheresa/go-hiring-challenge/app/config"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+// APIKeyHeader carries static API keys; JWTs use "Authorization: Bearer".
+const APIKeyHeader = "X-API-Key"
+
+// ErrInvalidCredentials is returned when a request presents credentials that cannot be verified.
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+type claims struct {
+	jwt.RegisteredClaims
+	Roles []string `json:"roles"`
+}
+
+// Authenticator verifies API keys and JWTs and attaches the resulting Principal to the request.
+type Authenticator struct {
+	keys           models.APIKeyRepository
+	jwks           *JWKS
+	parser         *jwt.Parser
+	anonymousReads bool
+}
+
+// NewAuthenticator creates an authenticator. A nil jwks disables JWT authentication.
+func NewAuthenticator(keys models.APIKeyRepository, jwks *JWKS, cfg config.AuthConfig) *Authenticator {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"HS256", "RS256"}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(30 * time.Second),
+	}
+	if cfg.JWTIssuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.JWTIssuer))
+	}
+	if cfg.JWTAudience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.JWTAudience))
+	}
+
+	return &Authenticator{
+		keys:           keys,
+		jwks:           jwks,
+		parser:         jwt.NewParser(opts...),
+		anonymousReads: cfg.AnonymousReads,
+	}
+}
+
+// Authenticate verifies the credentials on r. It returns a nil Principal and
+// nil error when the request carries no credentials at all.
+func (a *Authenticator) Authenticate(r *http.Request) (*Principal, error) {
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		return a.authenticateAPIKey(r, key)
+	}
+
+	authorization := r.Header.Get("Authorization")
+	if authorization == "" {
+		return nil, nil
+	}
+	scheme, token, ok := strings.Cut(authorization, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return nil, ErrInvalidCredentials
+	}
+	return a.authenticateJWT(token)
+}
+
+func (a *Authenticator) authenticateAPIKey(r *http.Request, key string) (*Principal, error) {
+	apiKey, err := a.keys.GetActiveByHash(r.Context(), HashAPIKey(key))
+	if errors.Is(err, models.ErrNotFound) {
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Principal{
+		Subject: "api-key:" + apiKey.Name,
+		Method:  MethodAPIKey,
+		Roles:   apiKey.Roles,
+	}, nil
+}
+
+func (a *Authenticator) authenticateJWT(token string) (*Principal, error) {
+	if a.jwks == nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	var c claims
+	if _, err := a.parser.ParseWithClaims(token, &c, a.jwks.keyfunc); err != nil {
+		return nil, errors.Join(ErrInvalidCredentials, err)
+	}
+	if c.Subject == "" {
+		return nil, ErrInvalidCredentials
+	}
+	return &Principal{
+		Subject: c.Subject,
+		Method:  MethodJWT,
+		Roles:   c.Roles,
+	}, nil
+}
+
+// Require rejects requests without valid credentials.
+func (a *Authenticator) Require(next http.Handler) http.Handler {
+	return a.middleware(next, true)
+}
+
+// Read guards read-only routes: credentials are verified when present, and are
+// only mandatory when anonymous reads are disabled.
+func (a *Authenticator) Read(next http.Handler) http.Handler {
+	return a.middleware(next, !a.anonymousReads)
+}
+
+func (a *Authenticator) middleware(next http.Handler, required bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, err := a.Authenticate(r)
+		if errors.Is(err, ErrInvalidCredentials) {
+			unauthenticated(w, r, "credentials are invalid or expired")
+			return
+		}
+		if err != nil {
+			api.HandleError(w, r, err, "failed to verify credentials")
+			return
+		}
+		if principal == nil {
+			if required {
+				unauthenticated(w, r, "authentication is required")
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+	})
+}
+
+func unauthenticated(w http.ResponseWriter, r *http.Request, detail string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="catalog"`)
+	api.ErrorResponse(w, r, http.StatusUnauthorized, api.CodeUnauthenticated, detail)
+}
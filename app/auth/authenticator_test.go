@@ -0,0 +1,224 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/mytheresa/go-hiring-challenge/app/config"
+	"github.com/mytheresa/go-hiring-challenge/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockAPIKeyRepository struct {
+	mock.Mock
+}
+
+func (m *MockAPIKeyRepository) GetActiveByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	args := m.Called(ctx, hash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.APIKey), args.Error(1)
+}
+
+var hmacSecret = []byte("0123456789abcdef0123456789abcdef")
+
+func testJWKS(t *testing.T, rsaKey *rsa.PublicKey) *JWKS {
+	t.Helper()
+	content, err := json.Marshal(map[string]any{"keys": []map[string]string{
+		{"kty": "oct", "kid": "hmac", "alg": "HS256", "k": base64.RawURLEncoding.EncodeToString(hmacSecret)},
+		{"kty": "RSA", "kid": "rsa", "alg": "RS256",
+			"n": base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes()),
+			"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(rsaKey.E)).Bytes())},
+	}})
+	require.NoError(t, err)
+	jwks, err := ParseJWKS(content)
+	require.NoError(t, err)
+	return jwks
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key any, c jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, c)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func TestAuthenticator(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	jwks := testJWKS(t, &rsaKey.PublicKey)
+
+	valid := jwt.MapClaims{
+		"sub":   "alice",
+		"iss":   "https://idp.example",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"roles": []string{"merchandiser"},
+	}
+
+	var seen *Principal
+	protected := func(a *Authenticator, read bool) http.Handler {
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			seen = PrincipalFromContext(r.Context())
+		})
+		if read {
+			return a.Read(next)
+		}
+		return a.Require(next)
+	}
+
+	newAuthenticator := func(repo *MockAPIKeyRepository, anonymousReads bool) *Authenticator {
+		return NewAuthenticator(repo, jwks, config.AuthConfig{AnonymousReads: anonymousReads, JWTIssuer: "https://idp.example"})
+	}
+
+	t.Run("accepts a valid API key", func(t *testing.T) {
+		repo := new(MockAPIKeyRepository)
+		repo.On("GetActiveByHash", mock.Anything, HashAPIKey("cat_secret")).
+			Return(&models.APIKey{Name: "ci", Roles: []string{"admin"}}, nil)
+
+		req := httptest.NewRequest("POST", "/categories", nil)
+		req.Header.Set(APIKeyHeader, "cat_secret")
+		recorder := httptest.NewRecorder()
+
+		protected(newAuthenticator(repo, true), false).ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, &Principal{Subject: "api-key:ci", Method: MethodAPIKey, Roles: []string{"admin"}}, seen)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("rejects an unknown API key", func(t *testing.T) {
+		repo := new(MockAPIKeyRepository)
+		repo.On("GetActiveByHash", mock.Anything, mock.Anything).
+			Return(nil, &models.Error{Kind: models.ErrNotFound, Message: "api key not found"})
+
+		req := httptest.NewRequest("POST", "/categories", nil)
+		req.Header.Set(APIKeyHeader, "cat_revoked")
+		recorder := httptest.NewRecorder()
+
+		protected(newAuthenticator(repo, true), false).ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+		assert.NotEmpty(t, recorder.Header().Get("WWW-Authenticate"))
+	})
+
+	t.Run("accepts HS256 and RS256 tokens", func(t *testing.T) {
+		for _, token := range []string{
+			sign(t, jwt.SigningMethodHS256, "hmac", hmacSecret, valid),
+			sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, valid),
+		} {
+			seen = nil
+			req := httptest.NewRequest("POST", "/categories", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			recorder := httptest.NewRecorder()
+
+			protected(newAuthenticator(new(MockAPIKeyRepository), true), false).ServeHTTP(recorder, req)
+
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			require.NotNil(t, seen)
+			assert.Equal(t, "alice", seen.Subject)
+			assert.Equal(t, []string{"merchandiser"}, seen.Roles)
+		}
+	})
+
+	t.Run("rejects expired, mis-signed and wrong-issuer tokens", func(t *testing.T) {
+		expired := jwt.MapClaims{"sub": "alice", "iss": "https://idp.example", "exp": time.Now().Add(-time.Hour).Unix()}
+		otherIssuer := jwt.MapClaims{"sub": "alice", "iss": "https://evil.example", "exp": time.Now().Add(time.Hour).Unix()}
+		for name, token := range map[string]string{
+			"expired":        sign(t, jwt.SigningMethodHS256, "hmac", hmacSecret, expired),
+			"wrong secret":   sign(t, jwt.SigningMethodHS256, "hmac", []byte("another-secret-another-secret-xx"), valid),
+			"alg mismatch":   sign(t, jwt.SigningMethodHS256, "rsa", hmacSecret, valid),
+			"wrong issuer":   sign(t, jwt.SigningMethodHS256, "hmac", hmacSecret, otherIssuer),
+			"not a jwt":      "garbage",
+			"unknown key id": sign(t, jwt.SigningMethodHS256, "other", hmacSecret, valid),
+		} {
+			req := httptest.NewRequest("POST", "/categories", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			recorder := httptest.NewRecorder()
+
+			protected(newAuthenticator(new(MockAPIKeyRepository), true), false).ServeHTTP(recorder, req)
+
+			assert.Equal(t, http.StatusUnauthorized, recorder.Code, name)
+		}
+	})
+
+	t.Run("requires credentials on write routes", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		protected(newAuthenticator(new(MockAPIKeyRepository), true), false).
+			ServeHTTP(recorder, httptest.NewRequest("POST", "/categories", nil))
+
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	})
+
+	t.Run("allows anonymous reads when enabled", func(t *testing.T) {
+		seen = &Principal{}
+		recorder := httptest.NewRecorder()
+		protected(newAuthenticator(new(MockAPIKeyRepository), true), true).
+			ServeHTTP(recorder, httptest.NewRequest("GET", "/catalog", nil))
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Nil(t, seen)
+	})
+
+	t.Run("requires credentials on reads when anonymous reads are disabled", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		protected(newAuthenticator(new(MockAPIKeyRepository), false), true).
+			ServeHTTP(recorder, httptest.NewRequest("GET", "/catalog", nil))
+
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	})
+
+	t.Run("reports 503 when keys cannot be looked up", func(t *testing.T) {
+		repo := new(MockAPIKeyRepository)
+		repo.On("GetActiveByHash", mock.Anything, mock.Anything).
+			Return(nil, &models.Error{Kind: models.ErrUnavailable, Message: "database unavailable"})
+
+		req := httptest.NewRequest("GET", "/catalog", nil)
+		req.Header.Set(APIKeyHeader, "cat_secret")
+		recorder := httptest.NewRecorder()
+
+		protected(newAuthenticator(repo, true), true).ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	})
+}
+
+func TestParseJWKS(t *testing.T) {
+	t.Run("rejects short HMAC secrets", func(t *testing.T) {
+		_, err := ParseJWKS([]byte(`{"keys":[{"kty":"oct","kid":"a","k":"c2hvcnQ"}]}`))
+		assert.Error(t, err)
+	})
+
+	t.Run("rejects unsupported key types", func(t *testing.T) {
+		_, err := ParseJWKS([]byte(`{"keys":[{"kty":"EC","kid":"a"}]}`))
+		assert.Error(t, err)
+	})
+
+	t.Run("rejects empty sets", func(t *testing.T) {
+		_, err := ParseJWKS([]byte(`{"keys":[]}`))
+		assert.Error(t, err)
+	})
+}
+
+func TestGenerateAPIKey(t *testing.T) {
+	key, prefix, hash, err := GenerateAPIKey()
+
+	require.NoError(t, err)
+	assert.Regexp(t, `^cat_[a-z2-7]{52}$`, key)
+	assert.Equal(t, key[:12], prefix)
+	assert.Equal(t, HashAPIKey(key), hash)
+	assert.Len(t, hash, 64)
+}
@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWKS holds the keys accepted for verifying JWTs, indexed by key ID.
+type JWKS struct {
+	keys map[string]jwk
+}
+
+type jwk struct {
+	alg string
+	key any
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	K   string `json:"k"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// LoadJWKS reads a JSON Web Key Set from path. Symmetric "oct" keys are used
+// for HS256 and "RSA" keys for RS256.
+func LoadJWKS(path string) (*JWKS, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseJWKS(content)
+}
+
+func ParseJWKS(content []byte) (*JWKS, error) {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(content, &set); err != nil {
+		return nil, fmt.Errorf("parsing JWKS: %w", err)
+	}
+	if len(set.Keys) == 0 {
+		return nil, errors.New("JWKS contains no keys")
+	}
+
+	jwks := &JWKS{keys: make(map[string]jwk, len(set.Keys))}
+	for i, k := range set.Keys {
+		parsed, err := parseJWK(k)
+		if err != nil {
+			return nil, fmt.Errorf("key %d (%q): %w", i, k.Kid, err)
+		}
+		jwks.keys[k.Kid] = parsed
+	}
+	return jwks, nil
+}
+
+func parseJWK(k jsonWebKey) (jwk, error) {
+	switch k.Kty {
+	case "oct":
+		if k.Alg != "" && k.Alg != "HS256" {
+			return jwk{}, fmt.Errorf("unsupported algorithm %q for oct key", k.Alg)
+		}
+		secret, err := base64.RawURLEncoding.DecodeString(k.K)
+		if err != nil || len(secret) < 32 {
+			return jwk{}, errors.New("oct key must be at least 256 bits of base64url data")
+		}
+		return jwk{alg: "HS256", key: secret}, nil
+	case "RSA":
+		if k.Alg != "" && k.Alg != "RS256" {
+			return jwk{}, fmt.Errorf("unsupported algorithm %q for RSA key", k.Alg)
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil || len(n) == 0 || len(e) == 0 {
+			return jwk{}, errors.New("RSA key has invalid modulus or exponent")
+		}
+		return jwk{alg: "RS256", key: &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}}, nil
+	default:
+		return jwk{}, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+// keyfunc selects the verification key by the token's kid header, falling back
+// to the only key when the set has one, and refuses algorithm mismatches.
+func (s *JWKS) keyfunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	k, ok := s.keys[kid]
+	if !ok && kid == "" && len(s.keys) == 1 {
+		for _, only := range s.keys {
+			k, ok = only, true
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	if token.Method.Alg() != k.alg {
+		return nil, fmt.Errorf("algorithm %s does not match key", token.Method.Alg())
+	}
+	return k.key, nil
+}
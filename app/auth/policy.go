@@ -0,0 +1,157 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/mytheresa/go-hiring-challenge/app/api"
+	"github.com/mytheresa/go-hiring-challenge/app/config"
+)
+
+// Permission names a single action on the catalog.
+type Permission string
+
+const (
+	PermCatalogRead     Permission = "catalog:read"
+	PermCatalogWrite    Permission = "catalog:write"
+	PermCategoriesWrite Permission = "categories:write"
+	PermPricesWrite     Permission = "prices:write"
+	// PermCatalogAdmin covers deleted data: listing it and restoring it.
+	PermCatalogAdmin Permission = "catalog:admin"
+	PermAuditRead    Permission = "audit:read"
+	// PermWebhooksManage covers webhook subscriptions and their delivery logs.
+	PermWebhooksManage Permission = "webhooks:manage"
+)
+
+// DefaultRoles grants viewers read access, merchandisers catalog editing and
+// admins everything, including deleted data, the audit log and webhooks.
+var DefaultRoles = map[string][]Permission{
+	"viewer":       {PermCatalogRead},
+	"merchandiser": {PermCatalogRead, PermCatalogWrite, PermCategoriesWrite, PermPricesWrite},
+	"admin":        {PermCatalogRead, PermCatalogWrite, PermCategoriesWrite, PermPricesWrite, PermCatalogAdmin, PermAuditRead, PermWebhooksManage},
+}
+
+// PolicyFromConfig builds the policy for cfg, falling back to DefaultRoles.
+func PolicyFromConfig(cfg config.AuthConfig) *Policy {
+	roles := DefaultRoles
+	if len(cfg.Roles) > 0 {
+		roles = make(map[string][]Permission, len(cfg.Roles))
+		for role, perms := range cfg.Roles {
+			for _, perm := range perms {
+				roles[role] = append(roles[role], Permission(perm))
+			}
+		}
+	}
+
+	var anonymous []Permission
+	if cfg.AnonymousReads {
+		anonymous = append(anonymous, PermCatalogRead)
+	}
+	return NewPolicy(roles, anonymous...)
+}
+
+// ErrUnauthenticated is returned when a permission is checked for an anonymous caller.
+var ErrUnauthenticated = errors.New("authentication is required")
+
+// ForbiddenError reports the permission the caller lacks.
+type ForbiddenError struct {
+	Missing Permission
+}
+
+func (e *ForbiddenError) Error() string {
+	return fmt.Sprintf("missing permission %s", e.Missing)
+}
+
+// Policy maps roles onto permissions. It has no HTTP dependencies so it can be tested on its own.
+type Policy struct {
+	roles     map[string][]Permission
+	anonymous []Permission
+}
+
+// NewPolicy creates a policy from role grants. Anonymous callers are granted
+// the anonymous permissions only.
+func NewPolicy(roles map[string][]Permission, anonymous ...Permission) *Policy {
+	return &Policy{roles: roles, anonymous: anonymous}
+}
+
+// Check returns nil when p holds perm, ErrUnauthenticated when p is nil and
+// anonymous callers lack perm, and a *ForbiddenError otherwise.
+func (pol *Policy) Check(p *Principal, perm Permission) error {
+	if p == nil {
+		if slices.Contains(pol.anonymous, perm) {
+			return nil
+		}
+		return ErrUnauthenticated
+	}
+	for _, role := range p.Roles {
+		if slices.Contains(pol.roles[role], perm) {
+			return nil
+		}
+	}
+	return &ForbiddenError{Missing: perm}
+}
+
+// Require rejects requests whose principal lacks perm with 401 or 403.
+// It must run after the Authenticator has attached the principal.
+func (pol *Policy) Require(perm Permission) api.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if pol.Deny(w, r, perm) {
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Deny writes a 401 or 403 response and returns true when the request's
+// principal lacks perm. Handlers use it for permissions that depend on the
+// request body, which route middleware cannot see.
+func (pol *Policy) Deny(w http.ResponseWriter, r *http.Request, perm Permission) bool {
+	err := pol.Check(PrincipalFromContext(r.Context()), perm)
+
+	var forbidden *ForbiddenError
+	switch {
+	case errors.Is(err, ErrUnauthenticated):
+		unauthenticated(w, r, "authentication is required")
+	case errors.As(err, &forbidden):
+		problem := api.NewProblem(http.StatusForbidden, api.CodeForbidden, forbidden.Error())
+		problem.Extensions = map[string]any{"missing_permission": forbidden.Missing}
+		api.ProblemResponse(w, r, problem)
+	default:
+		return false
+	}
+	return true
+}
+
+// Guard combines authentication and authorization for routes registered on the mux.
+type Guard struct {
+	Authenticator *Authenticator
+	Policy        *Policy
+}
+
+// Read protects a read-only route: anonymous callers pass only when anonymous reads are enabled.
+func (g Guard) Read(perm Permission, h http.HandlerFunc) http.Handler {
+	return g.Authenticator.Read(g.Policy.Require(perm)(h))
+}
+
+// Write protects a mutating route: credentials are always required.
+func (g Guard) Write(perm Permission, h http.HandlerFunc) http.Handler {
+	return g.Authenticator.Require(g.Policy.Require(perm)(h))
+}
+
+// Flag additionally requires perm from requests that turn on the boolean
+// query parameter param, such as include_deleted. It must be wrapped by Read or
+// Write so the principal is known; values that are not booleans are left for
+// the handler to reject.
+func (g Guard) Flag(param string, perm Permission, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if on, _ := strconv.ParseBool(r.URL.Query().Get(param)); on && g.Policy.Deny(w, r, perm) {
+			return
+		}
+		h(w, r)
+	}
+}
@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mytheresa/go-hiring-challenge/app/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicy_Check(t *testing.T) {
+	policy := NewPolicy(DefaultRoles, PermCatalogRead)
+
+	t.Run("viewers can only read", func(t *testing.T) {
+		viewer := &Principal{Subject: "bob", Roles: []string{"viewer"}}
+
+		assert.NoError(t, policy.Check(viewer, PermCatalogRead))
+
+		err := policy.Check(viewer, PermCategoriesWrite)
+		var forbidden *ForbiddenError
+		require.ErrorAs(t, err, &forbidden)
+		assert.Equal(t, PermCategoriesWrite, forbidden.Missing)
+	})
+
+	t.Run("merchandisers edit prices and categories", func(t *testing.T) {
+		merchandiser := &Principal{Subject: "alice", Roles: []string{"merchandiser"}}
+
+		for _, perm := range []Permission{PermCatalogRead, PermCatalogWrite, PermCategoriesWrite, PermPricesWrite} {
+			assert.NoError(t, policy.Check(merchandiser, perm), perm)
+		}
+	})
+
+	t.Run("unknown roles grant nothing", func(t *testing.T) {
+		err := policy.Check(&Principal{Subject: "eve", Roles: []string{"superuser"}}, PermCatalogRead)
+
+		assert.IsType(t, &ForbiddenError{}, err)
+	})
+
+	t.Run("anonymous callers get the anonymous grants only", func(t *testing.T) {
+		assert.NoError(t, policy.Check(nil, PermCatalogRead))
+		assert.ErrorIs(t, policy.Check(nil, PermCatalogWrite), ErrUnauthenticated)
+		assert.ErrorIs(t, NewPolicy(DefaultRoles).Check(nil, PermCatalogRead), ErrUnauthenticated)
+	})
+}
+
+func TestPolicyFromConfig(t *testing.T) {
+	t.Run("configured roles replace the defaults", func(t *testing.T) {
+		policy := PolicyFromConfig(config.AuthConfig{Roles: map[string][]string{"pricing": {"prices:write"}}})
+
+		assert.NoError(t, policy.Check(&Principal{Roles: []string{"pricing"}}, PermPricesWrite))
+		assert.Error(t, policy.Check(&Principal{Roles: []string{"merchandiser"}}, PermPricesWrite))
+		assert.ErrorIs(t, policy.Check(nil, PermCatalogRead), ErrUnauthenticated)
+	})
+
+	t.Run("anonymous reads grant catalog:read", func(t *testing.T) {
+		policy := PolicyFromConfig(config.AuthConfig{AnonymousReads: true})
+
+		assert.NoError(t, policy.Check(nil, PermCatalogRead))
+	})
+}
+
+func TestPolicy_Require(t *testing.T) {
+	policy := NewPolicy(DefaultRoles)
+	handler := policy.Require(PermCategoriesWrite)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	t.Run("denied requests get 403 with the missing permission", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/categories", nil)
+		req = req.WithContext(WithPrincipal(req.Context(), &Principal{Subject: "bob", Roles: []string{"viewer"}}))
+		recorder := httptest.NewRecorder()
+
+		handler.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusForbidden, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"code":"forbidden"`)
+		assert.Contains(t, recorder.Body.String(), `"missing_permission":"categories:write"`)
+	})
+
+	t.Run("anonymous requests get 401", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+
+		handler.ServeHTTP(recorder, httptest.NewRequest("POST", "/categories", nil))
+
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	})
+
+	t.Run("permitted requests reach the handler", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/categories", nil)
+		req = req.WithContext(WithPrincipal(req.Context(), &Principal{Subject: "alice", Roles: []string{"merchandiser"}}))
+		recorder := httptest.NewRecorder()
+
+		handler.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	})
+}
+
+func TestGuard_Flag(t *testing.T) {
+	guard := Guard{Policy: NewPolicy(DefaultRoles, PermCatalogRead)}
+	handler := guard.Flag("include_deleted", PermCatalogAdmin, func(w http.ResponseWriter, r *http.Request) {})
+
+	serve := func(target string, p *Principal) int {
+		req := httptest.NewRequest("GET", target, nil)
+		if p != nil {
+			req = req.WithContext(WithPrincipal(req.Context(), p))
+		}
+		recorder := httptest.NewRecorder()
+		handler(recorder, req)
+		return recorder.Code
+	}
+
+	t.Run("requests without the flag need nothing more", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, serve("/catalog", nil))
+		assert.Equal(t, http.StatusOK, serve("/catalog?include_deleted=false", nil))
+	})
+
+	t.Run("the flag requires the permission", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, serve("/catalog?include_deleted=true", nil))
+		assert.Equal(t, http.StatusForbidden, serve("/catalog?include_deleted=1", &Principal{Roles: []string{"merchandiser"}}))
+		assert.Equal(t, http.StatusOK, serve("/catalog?include_deleted=true", &Principal{Roles: []string{"admin"}}))
+	})
+}
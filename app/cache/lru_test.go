@@ -0,0 +1,55 @@
+package cache
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRU(t *testing.T) {
+	t.Run("evicts the least recently used entry when full", func(t *testing.T) {
+		lru := NewLRU[int](2)
+		lru.Set("a", 1, time.Minute)
+		lru.Set("b", 2, time.Minute)
+		lru.Get("a")
+		lru.Set("c", 3, time.Minute)
+
+		_, hasB := lru.Get("b")
+		a, hasA := lru.Get("a")
+		assert.False(t, hasB)
+		assert.True(t, hasA)
+		assert.Equal(t, 1, a)
+		assert.Equal(t, 2, lru.Len())
+	})
+
+	t.Run("expires entries after their TTL", func(t *testing.T) {
+		now := time.Unix(1000, 0)
+		lru := NewLRU[int](2)
+		lru.now = func() time.Time { return now }
+		lru.Set("short", 1, time.Second)
+		lru.Set("long", 2, time.Hour)
+
+		now = now.Add(time.Second)
+
+		_, hasShort := lru.Get("short")
+		_, hasLong := lru.Get("long")
+		assert.False(t, hasShort)
+		assert.True(t, hasLong)
+		assert.Equal(t, 1, lru.Len())
+	})
+
+	t.Run("removes matching keys", func(t *testing.T) {
+		lru := NewLRU[int](10)
+		lru.Set("list:0", 1, time.Minute)
+		lru.Set("list:10", 2, time.Minute)
+		lru.Set("code:PROD001", 3, time.Minute)
+
+		lru.Remove(func(key string) bool { return strings.HasPrefix(key, "list:") })
+
+		assert.Equal(t, 1, lru.Len())
+		_, ok := lru.Get("code:PROD001")
+		assert.True(t, ok)
+	})
+}
@@ -0,0 +1,289 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mytheresa/go-hiring-challenge/app/config"
+	"github.com/mytheresa/go-hiring-challenge/models"
+	"github.com/shopspring/decimal"
+	"golang.org/x/sync/singleflight"
+)
+
+// readThrough serves cached values and de-duplicates concurrent loads of the
+// same key. Values are shared between callers, who must treat them as read-only.
+type readThrough struct {
+	lru   *LRU[any]
+	group singleflight.Group
+
+	mu         sync.Mutex
+	generation uint64
+}
+
+func newReadThrough(capacity int) *readThrough {
+	return &readThrough{lru: NewLRU[any](capacity)}
+}
+
+// get returns the value cached under key or loads it with fetch. The load
+// runs detached from the caller's cancellation so one client going away does
+// not fail the others waiting on it; each caller still stops waiting when
+// its own context ends. Loads that overlap an invalidation are not stored.
+func get[V any](ctx context.Context, c *readThrough, key string, ttl time.Duration, fetch func(context.Context) (V, error)) (V, error) {
+	var zero V
+	if v, ok := c.lru.Get(key); ok {
+		return v.(V), nil
+	}
+
+	c.mu.Lock()
+	generation := c.generation
+	c.mu.Unlock()
+
+	loaded := c.group.DoChan(key+"@"+strconv.FormatUint(generation, 10), func() (any, error) {
+		v, err := fetch(context.WithoutCancel(ctx))
+		if err != nil {
+			return nil, err
+		}
+		c.mu.Lock()
+		if c.generation == generation {
+			c.lru.Set(key, v, ttl)
+		}
+		c.mu.Unlock()
+		return v, nil
+	})
+
+	select {
+	case res := <-loaded:
+		if res.Err != nil {
+			return zero, res.Err
+		}
+		return res.Val.(V), nil
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+}
+
+// invalidate drops the entries whose key matches and discards loads in flight.
+func (c *readThrough) invalidate(match func(key string) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	c.lru.Remove(match)
+}
+
+type page struct {
+	products []models.Product
+	total    int64
+}
+
+// ProductRepository caches product lists and details from the wrapped repository.
+type ProductRepository struct {
+	next    models.ProductRepository
+	cache   *readThrough
+	listTTL time.Duration
+	itemTTL time.Duration
+}
+
+func NewProductRepository(next models.ProductRepository, cfg config.CacheConfig) *ProductRepository {
+	return &ProductRepository{
+		next:    next,
+		cache:   newReadThrough(cfg.MaxEntries),
+		listTTL: cfg.ListTTL,
+		itemTTL: cfg.ItemTTL,
+	}
+}
+
+// GetAll serves product lists from the cache. Lists that include deleted
+// products are admin views and always come from the wrapped repository.
+func (r *ProductRepository) GetAll(ctx context.Context, offset, limit int, categoryCode string, priceLessThan *decimal.Decimal, includeDeleted bool) ([]models.Product, int64, error) {
+	if includeDeleted {
+		return r.next.GetAll(ctx, offset, limit, categoryCode, priceLessThan, true)
+	}
+
+	price := ""
+	if priceLessThan != nil {
+		price = priceLessThan.String()
+	}
+	key := "list:" + strconv.Itoa(offset) + ":" + strconv.Itoa(limit) + ":" + categoryCode + ":" + price
+
+	p, err := get(ctx, r.cache, key, r.listTTL, func(ctx context.Context) (page, error) {
+		products, total, err := r.next.GetAll(ctx, offset, limit, categoryCode, priceLessThan, false)
+		return page{products: products, total: total}, err
+	})
+	return p.products, p.total, err
+}
+
+func (r *ProductRepository) GetByCode(ctx context.Context, code string, includeDeleted bool) (*models.Product, error) {
+	if includeDeleted {
+		return r.next.GetByCode(ctx, code, true)
+	}
+	return get(ctx, r.cache, "code:"+code, r.itemTTL, func(ctx context.Context) (*models.Product, error) {
+		return r.next.GetByCode(ctx, code, false)
+	})
+}
+
+// Find is not cached; it backs batched loads whose keys rarely repeat.
+func (r *ProductRepository) Find(ctx context.Context, filter models.ProductFilter, offset, limit int) ([]models.Product, int64, error) {
+	return r.next.Find(ctx, filter, offset, limit)
+}
+
+// GetVariantsByProductIDs is not cached, for the same reason as Find.
+func (r *ProductRepository) GetVariantsByProductIDs(ctx context.Context, productIDs []uint) ([]models.Variant, error) {
+	return r.next.GetVariantsByProductIDs(ctx, productIDs)
+}
+
+// Update changes a product and drops its cached details and every cached list.
+func (r *ProductRepository) Update(ctx context.Context, code string, version uint, changes models.ProductChanges) (*models.Product, error) {
+	product, err := r.next.Update(ctx, code, version, changes)
+	if err == nil {
+		r.invalidateProduct(code)
+	}
+	return product, err
+}
+
+func (r *ProductRepository) Delete(ctx context.Context, code string, version uint) error {
+	err := r.next.Delete(ctx, code, version)
+	if err == nil {
+		r.invalidateProduct(code)
+	}
+	return err
+}
+
+func (r *ProductRepository) Restore(ctx context.Context, code string) (*models.Product, error) {
+	product, err := r.next.Restore(ctx, code)
+	if err == nil {
+		r.invalidateProduct(code)
+	}
+	return product, err
+}
+
+// GetVariant is not cached; variants are read on their own only to be edited.
+func (r *ProductRepository) GetVariant(ctx context.Context, code, sku string, includeDeleted bool) (*models.Variant, error) {
+	return r.next.GetVariant(ctx, code, sku, includeDeleted)
+}
+
+func (r *ProductRepository) UpdateVariant(ctx context.Context, code, sku string, version uint, changes models.VariantChanges) (*models.Variant, error) {
+	variant, err := r.next.UpdateVariant(ctx, code, sku, version, changes)
+	if err == nil {
+		r.invalidateProduct(code)
+	}
+	return variant, err
+}
+
+func (r *ProductRepository) DeleteVariant(ctx context.Context, code, sku string, version uint) error {
+	err := r.next.DeleteVariant(ctx, code, sku, version)
+	if err == nil {
+		r.invalidateProduct(code)
+	}
+	return err
+}
+
+func (r *ProductRepository) RestoreVariant(ctx context.Context, code, sku string) (*models.Variant, error) {
+	variant, err := r.next.RestoreVariant(ctx, code, sku)
+	if err == nil {
+		r.invalidateProduct(code)
+	}
+	return variant, err
+}
+
+// invalidateProduct drops the details of the product with code and every
+// list, since any of them may include the product.
+func (r *ProductRepository) invalidateProduct(code string) {
+	r.cache.invalidate(func(key string) bool {
+		return key == "code:"+code || strings.HasPrefix(key, "list:")
+	})
+}
+
+// InvalidateAll drops every cached product query, e.g. after a change to
+// category data that products embed.
+func (r *ProductRepository) InvalidateAll() {
+	r.cache.invalidate(func(string) bool { return true })
+}
+
+// CategoryRepository caches categories from the wrapped repository.
+type CategoryRepository struct {
+	next     models.CategoryRepository
+	cache    *readThrough
+	ttl      time.Duration
+	products *ProductRepository
+}
+
+// NewCategoryRepository creates the decorator. When products is not nil, its
+// cache is dropped whenever a category changes, since products embed their
+// category.
+func NewCategoryRepository(next models.CategoryRepository, cfg config.CacheConfig, products *ProductRepository) *CategoryRepository {
+	return &CategoryRepository{
+		next:     next,
+		cache:    newReadThrough(cfg.MaxEntries),
+		ttl:      cfg.ItemTTL,
+		products: products,
+	}
+}
+
+const allCategories = "all"
+
+// GetAll serves the category list from the cache unless deleted categories are asked for.
+func (r *CategoryRepository) GetAll(ctx context.Context, includeDeleted bool) ([]models.Category, error) {
+	if includeDeleted {
+		return r.next.GetAll(ctx, true)
+	}
+	return get(ctx, r.cache, allCategories, r.ttl, func(ctx context.Context) ([]models.Category, error) {
+		return r.next.GetAll(ctx, false)
+	})
+}
+
+func (r *CategoryRepository) GetByCode(ctx context.Context, code string, includeDeleted bool) (*models.Category, error) {
+	if includeDeleted {
+		return r.next.GetByCode(ctx, code, true)
+	}
+	return get(ctx, r.cache, "code:"+code, r.ttl, func(ctx context.Context) (*models.Category, error) {
+		return r.next.GetByCode(ctx, code, false)
+	})
+}
+
+// GetByIDs is not cached; it backs batched loads whose keys rarely repeat.
+func (r *CategoryRepository) GetByIDs(ctx context.Context, ids []uint) ([]models.Category, error) {
+	return r.next.GetByIDs(ctx, ids)
+}
+
+func (r *CategoryRepository) Update(ctx context.Context, code string, version uint, changes models.CategoryChanges) (*models.Category, error) {
+	category, err := r.next.Update(ctx, code, version, changes)
+	if err == nil {
+		r.invalidateCategory(code)
+	}
+	return category, err
+}
+
+func (r *CategoryRepository) Delete(ctx context.Context, code string, version uint) error {
+	err := r.next.Delete(ctx, code, version)
+	if err == nil {
+		r.invalidateCategory(code)
+	}
+	return err
+}
+
+func (r *CategoryRepository) Restore(ctx context.Context, code string) (*models.Category, error) {
+	category, err := r.next.Restore(ctx, code)
+	if err == nil {
+		r.invalidateCategory(code)
+	}
+	return category, err
+}
+
+func (r *CategoryRepository) invalidateCategory(code string) {
+	r.cache.invalidate(func(key string) bool { return key == allCategories || key == "code:"+code })
+	if r.products != nil {
+		r.products.InvalidateAll()
+	}
+}
+
+// Create adds a category and drops the cached list it now belongs to.
+func (r *CategoryRepository) Create(ctx context.Context, category *models.Category) error {
+	if err := r.next.Create(ctx, category); err != nil {
+		return err
+	}
+	r.cache.invalidate(func(key string) bool { return key == allCategories })
+	return nil
+}
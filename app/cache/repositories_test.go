@@ -0,0 +1,364 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mytheresa/go-hiring-challenge/app/config"
+	"github.com/mytheresa/go-hiring-challenge/models"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockProductRepository struct {
+	mock.Mock
+}
+
+func (m *MockProductRepository) GetAll(ctx context.Context, offset, limit int, categoryCode string, priceLessThan *decimal.Decimal, includeDeleted bool) ([]models.Product, int64, error) {
+	args := m.Called(ctx, offset, limit, categoryCode, priceLessThan, includeDeleted)
+	return args.Get(0).([]models.Product), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockProductRepository) GetByCode(ctx context.Context, code string, includeDeleted bool) (*models.Product, error) {
+	args := m.Called(ctx, code, includeDeleted)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Product), args.Error(1)
+}
+
+func (m *MockProductRepository) Find(ctx context.Context, filter models.ProductFilter, offset, limit int) ([]models.Product, int64, error) {
+	args := m.Called(ctx, filter, offset, limit)
+	return args.Get(0).([]models.Product), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockProductRepository) GetVariantsByProductIDs(ctx context.Context, productIDs []uint) ([]models.Variant, error) {
+	args := m.Called(ctx, productIDs)
+	return args.Get(0).([]models.Variant), args.Error(1)
+}
+
+func (m *MockProductRepository) Update(ctx context.Context, code string, version uint, changes models.ProductChanges) (*models.Product, error) {
+	args := m.Called(ctx, code, version, changes)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Product), args.Error(1)
+}
+
+func (m *MockProductRepository) Delete(ctx context.Context, code string, version uint) error {
+	args := m.Called(ctx, code, version)
+	return args.Error(0)
+}
+
+func (m *MockProductRepository) Restore(ctx context.Context, code string) (*models.Product, error) {
+	args := m.Called(ctx, code)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Product), args.Error(1)
+}
+
+func (m *MockProductRepository) GetVariant(ctx context.Context, code, sku string, includeDeleted bool) (*models.Variant, error) {
+	args := m.Called(ctx, code, sku, includeDeleted)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Variant), args.Error(1)
+}
+
+func (m *MockProductRepository) UpdateVariant(ctx context.Context, code, sku string, version uint, changes models.VariantChanges) (*models.Variant, error) {
+	args := m.Called(ctx, code, sku, version, changes)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Variant), args.Error(1)
+}
+
+func (m *MockProductRepository) DeleteVariant(ctx context.Context, code, sku string, version uint) error {
+	args := m.Called(ctx, code, sku, version)
+	return args.Error(0)
+}
+
+func (m *MockProductRepository) RestoreVariant(ctx context.Context, code, sku string) (*models.Variant, error) {
+	args := m.Called(ctx, code, sku)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Variant), args.Error(1)
+}
+
+type MockCategoryRepository struct {
+	mock.Mock
+}
+
+func (m *MockCategoryRepository) GetAll(ctx context.Context, includeDeleted bool) ([]models.Category, error) {
+	args := m.Called(ctx, includeDeleted)
+	return args.Get(0).([]models.Category), args.Error(1)
+}
+
+func (m *MockCategoryRepository) Create(ctx context.Context, category *models.Category) error {
+	args := m.Called(ctx, category)
+	return args.Error(0)
+}
+
+func (m *MockCategoryRepository) GetByCode(ctx context.Context, code string, includeDeleted bool) (*models.Category, error) {
+	args := m.Called(ctx, code, includeDeleted)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Category), args.Error(1)
+}
+
+func (m *MockCategoryRepository) GetByIDs(ctx context.Context, ids []uint) ([]models.Category, error) {
+	args := m.Called(ctx, ids)
+	return args.Get(0).([]models.Category), args.Error(1)
+}
+
+func (m *MockCategoryRepository) Update(ctx context.Context, code string, version uint, changes models.CategoryChanges) (*models.Category, error) {
+	args := m.Called(ctx, code, version, changes)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Category), args.Error(1)
+}
+
+func (m *MockCategoryRepository) Delete(ctx context.Context, code string, version uint) error {
+	args := m.Called(ctx, code, version)
+	return args.Error(0)
+}
+
+func (m *MockCategoryRepository) Restore(ctx context.Context, code string) (*models.Category, error) {
+	args := m.Called(ctx, code)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Category), args.Error(1)
+}
+
+var testConfig = config.CacheConfig{Enabled: true, MaxEntries: 100, ListTTL: time.Minute, ItemTTL: time.Minute}
+
+func TestProductRepository(t *testing.T) {
+	t.Run("serves repeated queries from the cache", func(t *testing.T) {
+		next := new(MockProductRepository)
+		price := decimal.NewFromInt(20)
+		next.On("GetAll", mock.Anything, 0, 10, "shoes", &price, false).
+			Return([]models.Product{{Code: "PROD002"}}, int64(1), nil).Once()
+		next.On("GetAll", mock.Anything, 10, 10, "shoes", &price, false).
+			Return([]models.Product{}, int64(1), nil).Once()
+		repo := NewProductRepository(next, testConfig)
+
+		for range 3 {
+			products, total, err := repo.GetAll(context.Background(), 0, 10, "shoes", &price, false)
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), total)
+			assert.Equal(t, "PROD002", products[0].Code)
+		}
+		_, _, err := repo.GetAll(context.Background(), 10, 10, "shoes", &price, false)
+		require.NoError(t, err)
+
+		next.AssertExpectations(t)
+	})
+
+	t.Run("does not cache errors", func(t *testing.T) {
+		next := new(MockProductRepository)
+		unavailable := &models.Error{Kind: models.ErrUnavailable, Message: "database unavailable"}
+		next.On("GetByCode", mock.Anything, "PROD001", false).Return(nil, unavailable).Once()
+		next.On("GetByCode", mock.Anything, "PROD001", false).Return(&models.Product{Code: "PROD001"}, nil).Once()
+		repo := NewProductRepository(next, testConfig)
+
+		_, err := repo.GetByCode(context.Background(), "PROD001", false)
+		assert.ErrorIs(t, err, models.ErrUnavailable)
+
+		product, err := repo.GetByCode(context.Background(), "PROD001", false)
+		require.NoError(t, err)
+		assert.Equal(t, "PROD001", product.Code)
+		next.AssertExpectations(t)
+	})
+
+	t.Run("de-duplicates identical concurrent loads", func(t *testing.T) {
+		next := new(MockProductRepository)
+		release := make(chan struct{})
+		next.On("GetByCode", mock.Anything, "PROD001", false).
+			Run(func(mock.Arguments) { <-release }).
+			Return(&models.Product{Code: "PROD001"}, nil).Once()
+		repo := NewProductRepository(next, testConfig)
+
+		var wg sync.WaitGroup
+		for range 10 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				product, err := repo.GetByCode(context.Background(), "PROD001", false)
+				assert.NoError(t, err)
+				assert.Equal(t, "PROD001", product.Code)
+			}()
+		}
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		next.AssertNumberOfCalls(t, "GetByCode", 1)
+	})
+
+	t.Run("never caches queries that include deleted products", func(t *testing.T) {
+		next := new(MockProductRepository)
+		next.On("GetByCode", mock.Anything, "PROD001", true).Return(&models.Product{Code: "PROD001"}, nil).Twice()
+		next.On("GetAll", mock.Anything, 0, 10, "", (*decimal.Decimal)(nil), true).Return([]models.Product{}, int64(0), nil).Twice()
+		repo := NewProductRepository(next, testConfig)
+
+		for range 2 {
+			_, err := repo.GetByCode(context.Background(), "PROD001", true)
+			require.NoError(t, err)
+			_, _, err = repo.GetAll(context.Background(), 0, 10, "", nil, true)
+			require.NoError(t, err)
+		}
+
+		next.AssertExpectations(t)
+	})
+
+	t.Run("stops waiting when the caller goes away", func(t *testing.T) {
+		next := new(MockProductRepository)
+		release := make(chan struct{})
+		defer close(release)
+		next.On("GetByCode", mock.Anything, "PROD001", false).
+			Run(func(mock.Arguments) { <-release }).
+			Return(&models.Product{Code: "PROD001"}, nil)
+		repo := NewProductRepository(next, testConfig)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := repo.GetByCode(ctx, "PROD001", false)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestCategoryRepository(t *testing.T) {
+	t.Run("invalidates the list when a category is created", func(t *testing.T) {
+		next := new(MockCategoryRepository)
+		next.On("GetAll", mock.Anything, false).Return([]models.Category{{Code: "clothing"}}, nil).Once()
+		next.On("GetAll", mock.Anything, false).Return([]models.Category{{Code: "clothing"}, {Code: "shoes"}}, nil).Once()
+		next.On("Create", mock.Anything, mock.Anything).Return(nil).Once()
+		repo := NewCategoryRepository(next, testConfig, nil)
+
+		before, err := repo.GetAll(context.Background(), false)
+		require.NoError(t, err)
+		cached, err := repo.GetAll(context.Background(), false)
+		require.NoError(t, err)
+		require.NoError(t, repo.Create(context.Background(), &models.Category{Code: "shoes"}))
+		after, err := repo.GetAll(context.Background(), false)
+		require.NoError(t, err)
+
+		assert.Len(t, before, 1)
+		assert.Len(t, cached, 1)
+		assert.Len(t, after, 2)
+		next.AssertExpectations(t)
+	})
+
+	t.Run("keeps the list when the create fails", func(t *testing.T) {
+		next := new(MockCategoryRepository)
+		conflict := &models.Error{Kind: models.ErrConflict, Message: "category already exists"}
+		next.On("GetAll", mock.Anything, false).Return([]models.Category{{Code: "clothing"}}, nil).Once()
+		next.On("Create", mock.Anything, mock.Anything).Return(conflict).Once()
+		repo := NewCategoryRepository(next, testConfig, nil)
+
+		repo.GetAll(context.Background(), false)
+		err := repo.Create(context.Background(), &models.Category{Code: "clothing"})
+		categories, _ := repo.GetAll(context.Background(), false)
+
+		assert.ErrorIs(t, err, models.ErrConflict)
+		assert.Len(t, categories, 1)
+		next.AssertExpectations(t)
+	})
+
+	t.Run("does not store loads that overlap an invalidation", func(t *testing.T) {
+		next := new(MockCategoryRepository)
+		release := make(chan struct{})
+		next.On("GetAll", mock.Anything, false).Run(func(mock.Arguments) { <-release }).Return([]models.Category{}, nil).Once()
+		next.On("GetAll", mock.Anything, false).Return([]models.Category{{Code: "shoes"}}, nil).Once()
+		next.On("Create", mock.Anything, mock.Anything).Return(nil).Once()
+		repo := NewCategoryRepository(next, testConfig, nil)
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			repo.GetAll(context.Background(), false)
+		}()
+		time.Sleep(20 * time.Millisecond)
+		require.NoError(t, repo.Create(context.Background(), &models.Category{Code: "shoes"}))
+		close(release)
+		<-done
+
+		categories, err := repo.GetAll(context.Background(), false)
+		require.NoError(t, err)
+		assert.Len(t, categories, 1)
+		next.AssertExpectations(t)
+	})
+}
+
+func TestInvalidation(t *testing.T) {
+	t.Run("product writes drop its details and every list", func(t *testing.T) {
+		next := new(MockProductRepository)
+		next.On("GetByCode", mock.Anything, "PROD001", false).Return(&models.Product{Code: "PROD001"}, nil).Twice()
+		next.On("GetByCode", mock.Anything, "PROD002", false).Return(&models.Product{Code: "PROD002"}, nil).Once()
+		next.On("GetAll", mock.Anything, 0, 10, "", (*decimal.Decimal)(nil), false).Return([]models.Product{}, int64(0), nil).Twice()
+		next.On("Update", mock.Anything, "PROD001", uint(1), mock.Anything).Return(&models.Product{Code: "PROD001"}, nil)
+		repo := NewProductRepository(next, testConfig)
+
+		load := func() {
+			repo.GetByCode(context.Background(), "PROD001", false)
+			repo.GetByCode(context.Background(), "PROD002", false)
+			repo.GetAll(context.Background(), 0, 10, "", nil, false)
+		}
+		load()
+		_, err := repo.Update(context.Background(), "PROD001", 1, models.ProductChanges{})
+		require.NoError(t, err)
+		load()
+
+		next.AssertExpectations(t)
+	})
+
+	t.Run("restores drop the product's details", func(t *testing.T) {
+		next := new(MockProductRepository)
+		next.On("GetByCode", mock.Anything, "PROD001", false).Return(&models.Product{Code: "PROD001", Version: 1}, nil).Once()
+		next.On("GetByCode", mock.Anything, "PROD001", false).Return(&models.Product{Code: "PROD001", Version: 3}, nil).Once()
+		next.On("RestoreVariant", mock.Anything, "PROD001", "SKU001A").Return(&models.Variant{SKU: "SKU001A"}, nil)
+		repo := NewProductRepository(next, testConfig)
+
+		_, err := repo.GetByCode(context.Background(), "PROD001", false)
+		require.NoError(t, err)
+		_, err = repo.RestoreVariant(context.Background(), "PROD001", "SKU001A")
+		require.NoError(t, err)
+		product, err := repo.GetByCode(context.Background(), "PROD001", false)
+
+		require.NoError(t, err)
+		assert.Equal(t, uint(3), product.Version)
+		next.AssertExpectations(t)
+	})
+
+	t.Run("category writes drop cached products that embed categories", func(t *testing.T) {
+		nextProducts := new(MockProductRepository)
+		nextProducts.On("GetByCode", mock.Anything, "PROD001", false).Return(&models.Product{Code: "PROD001"}, nil).Twice()
+		nextCategories := new(MockCategoryRepository)
+		nextCategories.On("GetByCode", mock.Anything, "shoes", false).Return(&models.Category{Code: "shoes"}, nil).Twice()
+		nextCategories.On("Update", mock.Anything, "shoes", uint(1), mock.Anything).Return(&models.Category{Code: "shoes"}, nil)
+		products := NewProductRepository(nextProducts, testConfig)
+		categories := NewCategoryRepository(nextCategories, testConfig, products)
+
+		load := func() {
+			products.GetByCode(context.Background(), "PROD001", false)
+			categories.GetByCode(context.Background(), "shoes", false)
+		}
+		load()
+		_, err := categories.Update(context.Background(), "shoes", 1, models.CategoryChanges{})
+		require.NoError(t, err)
+		load()
+
+		nextProducts.AssertExpectations(t)
+		nextCategories.AssertExpectations(t)
+	})
+}
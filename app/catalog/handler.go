@@ -1,12 +1,16 @@
 package catalog
 
 import (
+	"math"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/mytheresa/go-hiring-challenge/app/api"
+	"github.com/mytheresa/go-hiring-challenge/app/auth"
 	"github.com/mytheresa/go-hiring-challenge/models"
 	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
 )
 
 type CatalogResponse struct {
@@ -15,22 +19,25 @@ type CatalogResponse struct {
 }
 
 type ProductResponse struct {
-	Code     string           `json:"code"`
-	Price    float64          `json:"price"`
-	Category *CategorySummary `json:"category,omitempty"`
+	Code      string           `json:"code"`
+	Price     float64          `json:"price"`
+	Category  *CategorySummary `json:"category,omitempty"`
+	DeletedAt *time.Time       `json:"deleted_at,omitempty"`
 }
 
 type ProductDetailsResponse struct {
-	Code     string            `json:"code"`
-	Price    float64           `json:"price"`
-	Category *CategorySummary  `json:"category,omitempty"`
-	Variants []VariantResponse `json:"variants"`
+	Code      string            `json:"code"`
+	Price     float64           `json:"price"`
+	Category  *CategorySummary  `json:"category,omitempty"`
+	Variants  []VariantResponse `json:"variants"`
+	DeletedAt *time.Time        `json:"deleted_at,omitempty"`
 }
 
 type VariantResponse struct {
-	Name  string  `json:"name"`
-	SKU   string  `json:"sku"`
-	Price float64 `json:"price"`
+	Name      string     `json:"name"`
+	SKU       string     `json:"sku"`
+	Price     float64    `json:"price"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
 
 type CategorySummary struct {
@@ -38,111 +45,405 @@ type CategorySummary struct {
 	Name string `json:"name"`
 }
 
+type UpdateProductRequest struct {
+	Price    *decimal.Decimal `json:"price"`
+	Category *string          `json:"category"`
+}
+
+// Validate checks the request; a replacement must carry a price, and
+// omitting the category removes the product from its category.
+func (req UpdateProductRequest) Validate(replace bool) error {
+	var v api.Validator
+	if replace || req.Price != nil {
+		v.Field("price", decimalString(req.Price), api.Required(), api.DecimalRange(decimal.Zero, MaxPrice), api.DecimalPlaces(2))
+	}
+	if req.Category != nil {
+		v.Field("category", *req.Category, api.Length(0, 32), api.Pattern(api.SlugPattern, "a lowercase slug"))
+	}
+	return v.Err()
+}
+
+type UpdateVariantRequest struct {
+	Name  *string          `json:"name"`
+	Price *decimal.Decimal `json:"price"`
+}
+
+// Validate checks the request; a replacement must carry a name, and
+// omitting the price makes the variant inherit the product price.
+func (req UpdateVariantRequest) Validate(replace bool) error {
+	var v api.Validator
+	if replace || req.Name != nil {
+		v.Field("name", stringValue(req.Name), api.Required(), api.Length(1, 256))
+	}
+	if req.Price != nil {
+		v.Field("price", req.Price.String(), api.DecimalRange(decimal.Zero, MaxPrice), api.DecimalPlaces(2))
+	}
+	return v.Err()
+}
+
+func decimalString(d *decimal.Decimal) string {
+	if d == nil {
+		return ""
+	}
+	return d.String()
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
 type CatalogHandler struct {
-	repo models.ProductRepository
+	repo    models.ProductRepository
+	policy  *auth.Policy
+	present presenter
 }
 
-func NewCatalogHandler(r models.ProductRepository) *CatalogHandler {
+// NewCatalogHandler creates a handler serving the v1 response shapes. Routes
+// check catalog:write; the handler additionally checks prices:write against
+// policy when an update changes a price.
+func NewCatalogHandler(r models.ProductRepository, policy *auth.Policy) *CatalogHandler {
 	return &CatalogHandler{
-		repo: r,
+		repo:    r,
+		policy:  policy,
+		present: v1{},
 	}
 }
 
+// presenter shapes the responses of one API version.
+type presenter interface {
+	catalog(products []models.Product, total int64) any
+	product(product *models.Product) any
+	variant(variant *models.Variant, product *models.Product) any
+}
+
+// MaxPrice is the largest value a DECIMAL(10,2) price column can hold.
+var MaxPrice = decimal.RequireFromString("99999999.99")
+
 func (h *CatalogHandler) HandleGet(w http.ResponseWriter, r *http.Request) {
-	offset := 0
-	limit := 10
+	query := r.URL.Query()
 
-	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
-		if val, err := strconv.Atoi(offsetStr); err == nil && val >= 0 {
-			offset = val
-		}
+	var v api.Validator
+	v.Field("offset", query.Get("offset"), api.IntRange(0, math.MaxInt32))
+	v.Field("limit", query.Get("limit"), api.IntRange(1, 100))
+	v.Field("category", query.Get("category"), api.Length(1, 32), api.Pattern(api.SlugPattern, "a lowercase slug"))
+	v.Field("price_less_than", query.Get("price_less_than"), api.DecimalRange(decimal.Zero, MaxPrice))
+	v.Field("include_deleted", query.Get("include_deleted"), api.Bool())
+	if err := v.Err(); err != nil {
+		api.HandleError(w, r, err, "invalid query parameters")
+		return
 	}
 
-	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
-		if val, err := strconv.Atoi(limitStr); err == nil {
-			if val < 1 {
-				limit = 1
-			} else if val > 100 {
-				limit = 100
-			} else {
-				limit = val
-			}
-		}
+	offset := 0
+	if offsetStr := query.Get("offset"); offsetStr != "" {
+		offset, _ = strconv.Atoi(offsetStr)
 	}
 
-	categoryCode := r.URL.Query().Get("category")
+	limit := 10
+	if limitStr := query.Get("limit"); limitStr != "" {
+		limit, _ = strconv.Atoi(limitStr)
+	}
+
+	categoryCode := query.Get("category")
 
 	var priceLessThan *decimal.Decimal
-	if priceStr := r.URL.Query().Get("price_less_than"); priceStr != "" {
-		if price, err := decimal.NewFromString(priceStr); err == nil {
-			priceLessThan = &price
-		}
+	if priceStr := query.Get("price_less_than"); priceStr != "" {
+		price := decimal.RequireFromString(priceStr)
+		priceLessThan = &price
 	}
 
-	products, total, err := h.repo.GetAll(offset, limit, categoryCode, priceLessThan)
+	includeDeleted, _ := strconv.ParseBool(query.Get("include_deleted"))
+
+	products, total, err := h.repo.GetAll(r.Context(), offset, limit, categoryCode, priceLessThan, includeDeleted)
 	if err != nil {
-		api.ErrorResponse(w, http.StatusInternalServerError, "failed to fetch products")
+		api.HandleError(w, r, err, "failed to fetch products")
 		return
 	}
 
-	productResponses := make([]ProductResponse, len(products))
-	for i, p := range products {
-		productResponses[i] = ProductResponse{
-			Code:  p.Code,
-			Price: p.Price.InexactFloat64(),
-		}
-		if p.Category != nil {
-			productResponses[i].Category = &CategorySummary{
-				Code: p.Category.Code,
-				Name: p.Category.Name,
-			}
-		}
-	}
-
-	api.OKResponse(w, CatalogResponse{
-		Products: productResponses,
-		Total:    total,
-	})
+	// No Last-Modified: deleting a product or moving one to another page
+	// changes the list without raising the updated_at of what it returns,
+	// so only the body ETag tells whether the list is current
+	api.ConditionalResponse(w, r, h.present.catalog(products, total), time.Time{})
 }
 
 func (h *CatalogHandler) HandleGetByCode(w http.ResponseWriter, r *http.Request) {
 	code := r.PathValue("code")
 	if code == "" {
-		api.ErrorResponse(w, http.StatusBadRequest, "product code is required")
+		api.ErrorResponse(w, r, http.StatusBadRequest, api.CodeBadRequest, "product code is required")
 		return
 	}
 
-	product, err := h.repo.GetByCode(code)
+	includeDeleted, err := api.BoolQuery(r, "include_deleted")
 	if err != nil {
-		api.ErrorResponse(w, http.StatusNotFound, "product not found")
+		api.HandleError(w, r, err, "invalid query parameters")
 		return
 	}
 
-	variants := make([]VariantResponse, len(product.Variants))
-	for i, v := range product.Variants {
-		price := v.Price
-		if price.IsZero() {
-			price = product.Price
-		}
-		variants[i] = VariantResponse{
-			Name:  v.Name,
-			SKU:   v.SKU,
-			Price: price.InexactFloat64(),
-		}
+	product, err := h.repo.GetByCode(r.Context(), code, includeDeleted)
+	if err != nil {
+		api.HandleError(w, r, err, "failed to fetch product")
+		return
 	}
 
-	response := ProductDetailsResponse{
-		Code:     product.Code,
-		Price:    product.Price.InexactFloat64(),
-		Variants: variants,
+	api.VersionedResponse(w, r, h.present.product(product), productETag(product), product.LastModified())
+}
+
+// HandleUpdate replaces the price and category of a product.
+func (h *CatalogHandler) HandleUpdate(w http.ResponseWriter, r *http.Request) {
+	h.update(w, r, true)
+}
+
+// HandlePatch changes the price or category of a product.
+func (h *CatalogHandler) HandlePatch(w http.ResponseWriter, r *http.Request) {
+	h.update(w, r, false)
+}
+
+func (h *CatalogHandler) update(w http.ResponseWriter, r *http.Request, replace bool) {
+	version, err := api.IfMatchVersion(r)
+	if err != nil {
+		api.HandleError(w, r, err, "invalid If-Match header")
+		return
+	}
+
+	var req UpdateProductRequest
+	if err := api.DecodeJSON(r, &req); err != nil {
+		api.HandleError(w, r, err, "invalid request body")
+		return
+	}
+	if err := req.Validate(replace); err != nil {
+		api.HandleError(w, r, err, "invalid request body")
+		return
+	}
+
+	changes := models.ProductChanges{Price: req.Price, CategoryCode: req.Category}
+	if replace && changes.CategoryCode == nil {
+		changes.CategoryCode = new(string)
+	}
+	if changes.Price != nil && h.policy.Deny(w, r, auth.PermPricesWrite) {
+		return
 	}
 
+	product, err := h.repo.Update(r.Context(), r.PathValue("code"), version, changes)
+	if err != nil {
+		api.HandleError(w, r, err, "failed to update product")
+		return
+	}
+
+	w.Header().Set("ETag", productETag(product))
+	api.OKResponse(w, h.present.product(product))
+}
+
+// HandleDelete soft-deletes a product, hiding its variants with it.
+func (h *CatalogHandler) HandleDelete(w http.ResponseWriter, r *http.Request) {
+	version, err := api.IfMatchVersion(r)
+	if err != nil {
+		api.HandleError(w, r, err, "invalid If-Match header")
+		return
+	}
+
+	if err := h.repo.Delete(r.Context(), r.PathValue("code"), version); err != nil {
+		api.HandleError(w, r, err, "failed to delete product")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleRestore brings back a deleted product.
+func (h *CatalogHandler) HandleRestore(w http.ResponseWriter, r *http.Request) {
+	product, err := h.repo.Restore(r.Context(), r.PathValue("code"))
+	if err != nil {
+		api.HandleError(w, r, err, "failed to restore product")
+		return
+	}
+
+	w.Header().Set("ETag", productETag(product))
+	api.OKResponse(w, h.present.product(product))
+}
+
+func (h *CatalogHandler) HandleGetVariant(w http.ResponseWriter, r *http.Request) {
+	includeDeleted, err := api.BoolQuery(r, "include_deleted")
+	if err != nil {
+		api.HandleError(w, r, err, "invalid query parameters")
+		return
+	}
+
+	variant, err := h.repo.GetVariant(r.Context(), r.PathValue("code"), r.PathValue("sku"), includeDeleted)
+	if err != nil {
+		api.HandleError(w, r, err, "failed to fetch variant")
+		return
+	}
+
+	api.VersionedResponse(w, r, h.present.variant(variant, variant.Product), variantETag(variant), variant.LastModified())
+}
+
+// HandleUpdateVariant replaces the name and price of a variant.
+func (h *CatalogHandler) HandleUpdateVariant(w http.ResponseWriter, r *http.Request) {
+	h.updateVariant(w, r, true)
+}
+
+// HandlePatchVariant changes the name or price of a variant.
+func (h *CatalogHandler) HandlePatchVariant(w http.ResponseWriter, r *http.Request) {
+	h.updateVariant(w, r, false)
+}
+
+func (h *CatalogHandler) updateVariant(w http.ResponseWriter, r *http.Request, replace bool) {
+	version, err := api.IfMatchVersion(r)
+	if err != nil {
+		api.HandleError(w, r, err, "invalid If-Match header")
+		return
+	}
+
+	var req UpdateVariantRequest
+	if err := api.DecodeJSON(r, &req); err != nil {
+		api.HandleError(w, r, err, "invalid request body")
+		return
+	}
+	if err := req.Validate(replace); err != nil {
+		api.HandleError(w, r, err, "invalid request body")
+		return
+	}
+
+	changes := models.VariantChanges{Name: req.Name, Price: req.Price}
+	if replace && changes.Price == nil {
+		inherit := decimal.Zero
+		changes.Price = &inherit
+	}
+	// A replacement without a price resets it to the product price, which
+	// is a price change too
+	if changes.Price != nil && h.policy.Deny(w, r, auth.PermPricesWrite) {
+		return
+	}
+
+	variant, err := h.repo.UpdateVariant(r.Context(), r.PathValue("code"), r.PathValue("sku"), version, changes)
+	if err != nil {
+		api.HandleError(w, r, err, "failed to update variant")
+		return
+	}
+
+	w.Header().Set("ETag", variantETag(variant))
+	api.OKResponse(w, h.present.variant(variant, variant.Product))
+}
+
+// HandleDeleteVariant soft-deletes a single variant of a product.
+func (h *CatalogHandler) HandleDeleteVariant(w http.ResponseWriter, r *http.Request) {
+	version, err := api.IfMatchVersion(r)
+	if err != nil {
+		api.HandleError(w, r, err, "invalid If-Match header")
+		return
+	}
+
+	if err := h.repo.DeleteVariant(r.Context(), r.PathValue("code"), r.PathValue("sku"), version); err != nil {
+		api.HandleError(w, r, err, "failed to delete variant")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleRestoreVariant brings back a deleted variant of a product.
+func (h *CatalogHandler) HandleRestoreVariant(w http.ResponseWriter, r *http.Request) {
+	variant, err := h.repo.RestoreVariant(r.Context(), r.PathValue("code"), r.PathValue("sku"))
+	if err != nil {
+		api.HandleError(w, r, err, "failed to restore variant")
+		return
+	}
+
+	w.Header().Set("ETag", variantETag(variant))
+	api.OKResponse(w, h.present.variant(variant, variant.Product))
+}
+
+// productETag covers the product's own version and that of the category it embeds.
+func productETag(product *models.Product) string {
+	var categoryVersion uint
 	if product.Category != nil {
-		response.Category = &CategorySummary{
-			Code: product.Category.Code,
-			Name: product.Category.Name,
+		categoryVersion = product.Category.Version
+	}
+	return api.VersionETag(product.Version, categoryVersion)
+}
+
+// variantETag covers the variant's own version and that of its product, whose
+// price the variant shows when it has none of its own.
+func variantETag(variant *models.Variant) string {
+	var productVersion uint
+	if variant.Product != nil {
+		productVersion = variant.Product.Version
+	}
+	return api.VersionETag(variant.Version, productVersion)
+}
+
+// v1 reports prices as JSON numbers.
+type v1 struct{}
+
+func (v1) catalog(products []models.Product, total int64) any {
+	productResponses := make([]ProductResponse, len(products))
+	for i, p := range products {
+		productResponses[i] = ProductResponse{
+			Code:      p.Code,
+			Price:     p.Price.InexactFloat64(),
+			Category:  categorySummary(p.Category),
+			DeletedAt: deletedAt(p.DeletedAt),
 		}
 	}
+	return CatalogResponse{
+		Products: productResponses,
+		Total:    total,
+	}
+}
 
-	api.OKResponse(w, response)
+func (v1) product(product *models.Product) any {
+	return productDetails(product)
+}
+
+func (v1) variant(variant *models.Variant, product *models.Product) any {
+	return variantResponse(variant, product)
+}
+
+func productDetails(product *models.Product) ProductDetailsResponse {
+	variants := make([]VariantResponse, len(product.Variants))
+	for i := range product.Variants {
+		variants[i] = variantResponse(&product.Variants[i], product)
+	}
+
+	return ProductDetailsResponse{
+		Code:      product.Code,
+		Price:     product.Price.InexactFloat64(),
+		Category:  categorySummary(product.Category),
+		Variants:  variants,
+		DeletedAt: deletedAt(product.DeletedAt),
+	}
+}
+
+func variantResponse(v *models.Variant, product *models.Product) VariantResponse {
+	return VariantResponse{
+		Name:      v.Name,
+		SKU:       v.SKU,
+		Price:     effectivePrice(v, product).InexactFloat64(),
+		DeletedAt: deletedAt(v.DeletedAt),
+	}
+}
+
+// effectivePrice falls back to the product price for variants without their own.
+func effectivePrice(v *models.Variant, product *models.Product) decimal.Decimal {
+	if v.Price.IsZero() && product != nil {
+		return product.Price
+	}
+	return v.Price
+}
+
+func categorySummary(category *models.Category) *CategorySummary {
+	if category == nil {
+		return nil
+	}
+	return &CategorySummary{
+		Code: category.Code,
+		Name: category.Name,
+	}
+}
+
+func deletedAt(deleted gorm.DeletedAt) *time.Time {
+	if !deleted.Valid {
+		return nil
+	}
+	return &deleted.Time
 }
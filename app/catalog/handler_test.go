@@ -1,39 +1,133 @@
 package catalog
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/mytheresa/go-hiring-challenge/app/api"
+	"github.com/mytheresa/go-hiring-challenge/app/auth"
 	"github.com/mytheresa/go-hiring-challenge/models"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
 )
 
+// testPolicy adds an editor role that may change products but not prices.
+var testPolicy = auth.NewPolicy(map[string][]auth.Permission{
+	"merchandiser": auth.DefaultRoles["merchandiser"],
+	"editor":       {auth.PermCatalogRead, auth.PermCatalogWrite},
+}, auth.PermCatalogRead)
+
+// as attaches a principal with role to req, as the authenticator would.
+func as(req *http.Request, role string) *http.Request {
+	return req.WithContext(auth.WithPrincipal(req.Context(), &auth.Principal{Subject: role, Roles: []string{role}}))
+}
+
 type MockProductRepository struct {
 	mock.Mock
 }
 
-func (m *MockProductRepository) GetAll(offset, limit int, categoryCode string, priceLessThan *decimal.Decimal) ([]models.Product, int64, error) {
-	args := m.Called(offset, limit, categoryCode, priceLessThan)
+func (m *MockProductRepository) GetAll(ctx context.Context, offset, limit int, categoryCode string, priceLessThan *decimal.Decimal, includeDeleted bool) ([]models.Product, int64, error) {
+	args := m.Called(ctx, offset, limit, categoryCode, priceLessThan, includeDeleted)
 	return args.Get(0).([]models.Product), args.Get(1).(int64), args.Error(2)
 }
 
-func (m *MockProductRepository) GetByCode(code string) (*models.Product, error) {
-	args := m.Called(code)
+func (m *MockProductRepository) GetByCode(ctx context.Context, code string, includeDeleted bool) (*models.Product, error) {
+	args := m.Called(ctx, code, includeDeleted)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*models.Product), args.Error(1)
 }
 
+func (m *MockProductRepository) Find(ctx context.Context, filter models.ProductFilter, offset, limit int) ([]models.Product, int64, error) {
+	args := m.Called(ctx, filter, offset, limit)
+	return args.Get(0).([]models.Product), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockProductRepository) GetVariantsByProductIDs(ctx context.Context, productIDs []uint) ([]models.Variant, error) {
+	args := m.Called(ctx, productIDs)
+	return args.Get(0).([]models.Variant), args.Error(1)
+}
+
+func (m *MockProductRepository) Update(ctx context.Context, code string, version uint, changes models.ProductChanges) (*models.Product, error) {
+	args := m.Called(ctx, code, version, changes)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Product), args.Error(1)
+}
+
+func (m *MockProductRepository) Delete(ctx context.Context, code string, version uint) error {
+	args := m.Called(ctx, code, version)
+	return args.Error(0)
+}
+
+func (m *MockProductRepository) Restore(ctx context.Context, code string) (*models.Product, error) {
+	args := m.Called(ctx, code)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Product), args.Error(1)
+}
+
+func (m *MockProductRepository) GetVariant(ctx context.Context, code, sku string, includeDeleted bool) (*models.Variant, error) {
+	args := m.Called(ctx, code, sku, includeDeleted)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Variant), args.Error(1)
+}
+
+func (m *MockProductRepository) UpdateVariant(ctx context.Context, code, sku string, version uint, changes models.VariantChanges) (*models.Variant, error) {
+	args := m.Called(ctx, code, sku, version, changes)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Variant), args.Error(1)
+}
+
+func (m *MockProductRepository) DeleteVariant(ctx context.Context, code, sku string, version uint) error {
+	args := m.Called(ctx, code, sku, version)
+	return args.Error(0)
+}
+
+func (m *MockProductRepository) RestoreVariant(ctx context.Context, code, sku string) (*models.Variant, error) {
+	args := m.Called(ctx, code, sku)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Variant), args.Error(1)
+}
+
 func TestCatalogHandler_HandleGet(t *testing.T) {
+	t.Run("validates lists by ETag only", func(t *testing.T) {
+		mockRepo := new(MockProductRepository)
+		handler := NewCatalogHandler(mockRepo, testPolicy)
+		updated := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+		mockRepo.On("GetAll", mock.Anything, 0, 10, "", (*decimal.Decimal)(nil), false).
+			Return([]models.Product{{ID: 1, Code: "PROD001", UpdatedAt: updated}}, int64(1), nil)
+
+		req := httptest.NewRequest("GET", "/catalog", nil)
+		req.Header.Set("If-Modified-Since", updated.Add(time.Hour).Format(http.TimeFormat))
+		recorder := httptest.NewRecorder()
+		handler.HandleGet(recorder, req)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Empty(t, recorder.Header().Get("Last-Modified"))
+		assert.NotEmpty(t, recorder.Header().Get("ETag"))
+	})
+
 	t.Run("returns products with default pagination", func(t *testing.T) {
 		mockRepo := new(MockProductRepository)
-		handler := NewCatalogHandler(mockRepo)
+		handler := NewCatalogHandler(mockRepo, testPolicy)
 
 		category := &models.Category{ID: 1, Code: "clothing", Name: "Clothing"}
 		products := []models.Product{
@@ -46,7 +140,7 @@ func TestCatalogHandler_HandleGet(t *testing.T) {
 			},
 		}
 
-		mockRepo.On("GetAll", 0, 10, "", (*decimal.Decimal)(nil)).Return(products, int64(1), nil)
+		mockRepo.On("GetAll", mock.Anything, 0, 10, "", (*decimal.Decimal)(nil), false).Return(products, int64(1), nil)
 
 		req := httptest.NewRequest("GET", "/catalog", nil)
 		recorder := httptest.NewRecorder()
@@ -72,10 +166,10 @@ func TestCatalogHandler_HandleGet(t *testing.T) {
 
 	t.Run("returns products with custom pagination", func(t *testing.T) {
 		mockRepo := new(MockProductRepository)
-		handler := NewCatalogHandler(mockRepo)
+		handler := NewCatalogHandler(mockRepo, testPolicy)
 
 		products := []models.Product{}
-		mockRepo.On("GetAll", 5, 20, "", (*decimal.Decimal)(nil)).Return(products, int64(100), nil)
+		mockRepo.On("GetAll", mock.Anything, 5, 20, "", (*decimal.Decimal)(nil), false).Return(products, int64(100), nil)
 
 		req := httptest.NewRequest("GET", "/catalog?offset=5&limit=20", nil)
 		recorder := httptest.NewRecorder()
@@ -88,10 +182,10 @@ func TestCatalogHandler_HandleGet(t *testing.T) {
 
 	t.Run("filters by category", func(t *testing.T) {
 		mockRepo := new(MockProductRepository)
-		handler := NewCatalogHandler(mockRepo)
+		handler := NewCatalogHandler(mockRepo, testPolicy)
 
 		products := []models.Product{}
-		mockRepo.On("GetAll", 0, 10, "shoes", (*decimal.Decimal)(nil)).Return(products, int64(0), nil)
+		mockRepo.On("GetAll", mock.Anything, 0, 10, "shoes", (*decimal.Decimal)(nil), false).Return(products, int64(0), nil)
 
 		req := httptest.NewRequest("GET", "/catalog?category=shoes", nil)
 		recorder := httptest.NewRecorder()
@@ -104,12 +198,12 @@ func TestCatalogHandler_HandleGet(t *testing.T) {
 
 	t.Run("filters by price less than", func(t *testing.T) {
 		mockRepo := new(MockProductRepository)
-		handler := NewCatalogHandler(mockRepo)
+		handler := NewCatalogHandler(mockRepo, testPolicy)
 
 		products := []models.Product{}
-		mockRepo.On("GetAll", 0, 10, "", mock.MatchedBy(func(price *decimal.Decimal) bool {
+		mockRepo.On("GetAll", mock.Anything, 0, 10, "", mock.MatchedBy(func(price *decimal.Decimal) bool {
 			return price != nil && price.Equal(decimal.NewFromFloat(15.00))
-		})).Return(products, int64(0), nil)
+		}), false).Return(products, int64(0), nil)
 
 		req := httptest.NewRequest("GET", "/catalog?price_less_than=15.00", nil)
 		recorder := httptest.NewRecorder()
@@ -120,14 +214,38 @@ func TestCatalogHandler_HandleGet(t *testing.T) {
 		mockRepo.AssertExpectations(t)
 	})
 
-	t.Run("enforces limit constraints", func(t *testing.T) {
+	t.Run("rejects invalid query parameters", func(t *testing.T) {
 		mockRepo := new(MockProductRepository)
-		handler := NewCatalogHandler(mockRepo)
+		handler := NewCatalogHandler(mockRepo, testPolicy)
+
+		req := httptest.NewRequest("GET", "/catalog?limit=200&offset=-1&category=Shoes&price_less_than=abc", nil)
+		recorder := httptest.NewRecorder()
+
+		handler.HandleGet(recorder, req)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Equal(t, api.ProblemContentType, recorder.Header().Get("Content-Type"))
+
+		var problem api.Problem
+		err := json.NewDecoder(recorder.Body).Decode(&problem)
+		assert.NoError(t, err)
+		assert.Equal(t, api.CodeValidationFailed, problem.Code)
+		fields := make([]string, len(problem.Errors))
+		for i, fe := range problem.Errors {
+			fields[i] = fe.Field
+		}
+		assert.Equal(t, []string{"offset", "limit", "category", "price_less_than"}, fields)
+		mockRepo.AssertNotCalled(t, "GetAll", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("accepts the maximum limit", func(t *testing.T) {
+		mockRepo := new(MockProductRepository)
+		handler := NewCatalogHandler(mockRepo, testPolicy)
 
 		products := []models.Product{}
-		mockRepo.On("GetAll", 0, 100, "", (*decimal.Decimal)(nil)).Return(products, int64(0), nil)
+		mockRepo.On("GetAll", mock.Anything, 0, 100, "", (*decimal.Decimal)(nil), false).Return(products, int64(0), nil)
 
-		req := httptest.NewRequest("GET", "/catalog?limit=200", nil)
+		req := httptest.NewRequest("GET", "/catalog?limit=100", nil)
 		recorder := httptest.NewRecorder()
 
 		handler.HandleGet(recorder, req)
@@ -138,9 +256,9 @@ func TestCatalogHandler_HandleGet(t *testing.T) {
 
 	t.Run("handles repository errors", func(t *testing.T) {
 		mockRepo := new(MockProductRepository)
-		handler := NewCatalogHandler(mockRepo)
+		handler := NewCatalogHandler(mockRepo, testPolicy)
 
-		mockRepo.On("GetAll", 0, 10, "", (*decimal.Decimal)(nil)).
+		mockRepo.On("GetAll", mock.Anything, 0, 10, "", (*decimal.Decimal)(nil), false).
 			Return([]models.Product{}, int64(0), errors.New("database error"))
 
 		req := httptest.NewRequest("GET", "/catalog", nil)
@@ -156,7 +274,7 @@ func TestCatalogHandler_HandleGet(t *testing.T) {
 func TestCatalogHandler_HandleGetByCode(t *testing.T) {
 	t.Run("returns product with variants", func(t *testing.T) {
 		mockRepo := new(MockProductRepository)
-		handler := NewCatalogHandler(mockRepo)
+		handler := NewCatalogHandler(mockRepo, testPolicy)
 
 		category := &models.Category{ID: 1, Code: "clothing", Name: "Clothing"}
 		productID := uint(1)
@@ -172,7 +290,7 @@ func TestCatalogHandler_HandleGetByCode(t *testing.T) {
 			},
 		}
 
-		mockRepo.On("GetByCode", "PROD001").Return(product, nil)
+		mockRepo.On("GetByCode", mock.Anything, "PROD001", false).Return(product, nil)
 
 		req := httptest.NewRequest("GET", "/catalog/PROD001", nil)
 		req.SetPathValue("code", "PROD001")
@@ -199,11 +317,75 @@ func TestCatalogHandler_HandleGetByCode(t *testing.T) {
 		mockRepo.AssertExpectations(t)
 	})
 
+	t.Run("shows deleted products with their deletion time on request", func(t *testing.T) {
+		mockRepo := new(MockProductRepository)
+		handler := NewCatalogHandler(mockRepo, testPolicy)
+		deletedAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+		product := &models.Product{Code: "PROD001", Price: decimal.NewFromInt(10), DeletedAt: gorm.DeletedAt{Time: deletedAt, Valid: true}}
+		mockRepo.On("GetByCode", mock.Anything, "PROD001", true).Return(product, nil)
+
+		req := httptest.NewRequest("GET", "/catalog/PROD001?include_deleted=true", nil)
+		req.SetPathValue("code", "PROD001")
+		recorder := httptest.NewRecorder()
+
+		handler.HandleGetByCode(recorder, req)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.JSONEq(t, `{"code":"PROD001","price":10,"variants":[],"deleted_at":"2024-03-01T12:00:00Z"}`, recorder.Body.String())
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("rejects include_deleted values that are not booleans", func(t *testing.T) {
+		mockRepo := new(MockProductRepository)
+		handler := NewCatalogHandler(mockRepo, testPolicy)
+
+		req := httptest.NewRequest("GET", "/catalog/PROD001?include_deleted=maybe", nil)
+		req.SetPathValue("code", "PROD001")
+		recorder := httptest.NewRecorder()
+
+		handler.HandleGetByCode(recorder, req)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		mockRepo.AssertNotCalled(t, "GetByCode", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("answers conditional requests with 304", func(t *testing.T) {
+		mockRepo := new(MockProductRepository)
+		handler := NewCatalogHandler(mockRepo, testPolicy)
+
+		productUpdated := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		variantUpdated := productUpdated.Add(48 * time.Hour)
+		product := &models.Product{
+			Code:      "PROD001",
+			Price:     decimal.NewFromFloat(10.99),
+			UpdatedAt: productUpdated,
+			Variants:  []models.Variant{{Name: "Variant A", SKU: "SKU001A", UpdatedAt: variantUpdated}},
+		}
+		mockRepo.On("GetByCode", mock.Anything, "PROD001", false).Return(product, nil)
+
+		req := httptest.NewRequest("GET", "/catalog/PROD001", nil)
+		req.SetPathValue("code", "PROD001")
+		first := httptest.NewRecorder()
+		handler.HandleGetByCode(first, req)
+
+		assert.Equal(t, http.StatusOK, first.Code)
+		assert.Equal(t, variantUpdated.Format(http.TimeFormat), first.Header().Get("Last-Modified"))
+		assert.NotEmpty(t, first.Header().Get("ETag"))
+
+		req.Header.Set("If-None-Match", first.Header().Get("ETag"))
+		second := httptest.NewRecorder()
+		handler.HandleGetByCode(second, req)
+
+		assert.Equal(t, http.StatusNotModified, second.Code)
+		assert.Empty(t, second.Body.String())
+	})
+
 	t.Run("returns 404 when product not found", func(t *testing.T) {
 		mockRepo := new(MockProductRepository)
-		handler := NewCatalogHandler(mockRepo)
+		handler := NewCatalogHandler(mockRepo, testPolicy)
 
-		mockRepo.On("GetByCode", "INVALID").Return((*models.Product)(nil), errors.New("not found"))
+		notFound := &models.Error{Kind: models.ErrNotFound, Message: "product not found"}
+		mockRepo.On("GetByCode", mock.Anything, "INVALID", false).Return((*models.Product)(nil), notFound)
 
 		req := httptest.NewRequest("GET", "/catalog/INVALID", nil)
 		req.SetPathValue("code", "INVALID")
@@ -215,9 +397,26 @@ func TestCatalogHandler_HandleGetByCode(t *testing.T) {
 		mockRepo.AssertExpectations(t)
 	})
 
+	t.Run("returns 503 when the database is unavailable", func(t *testing.T) {
+		mockRepo := new(MockProductRepository)
+		handler := NewCatalogHandler(mockRepo, testPolicy)
+
+		unavailable := &models.Error{Kind: models.ErrUnavailable, Message: "database unavailable", Err: errors.New("connection refused")}
+		mockRepo.On("GetByCode", mock.Anything, "PROD001", false).Return((*models.Product)(nil), unavailable)
+
+		req := httptest.NewRequest("GET", "/catalog/PROD001", nil)
+		req.SetPathValue("code", "PROD001")
+		recorder := httptest.NewRecorder()
+
+		handler.HandleGetByCode(recorder, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+		mockRepo.AssertExpectations(t)
+	})
+
 	t.Run("returns 400 when code is empty", func(t *testing.T) {
 		mockRepo := new(MockProductRepository)
-		handler := NewCatalogHandler(mockRepo)
+		handler := NewCatalogHandler(mockRepo, testPolicy)
 
 		req := httptest.NewRequest("GET", "/catalog/", nil)
 		recorder := httptest.NewRecorder()
@@ -228,3 +427,317 @@ func TestCatalogHandler_HandleGetByCode(t *testing.T) {
 	})
 }
 
+func TestCatalogHandler_HandleUpdate(t *testing.T) {
+	newRequest := func(method, body, ifMatch string) *http.Request {
+		req := httptest.NewRequest(method, "/catalog/PROD001", strings.NewReader(body))
+		req.SetPathValue("code", "PROD001")
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		return as(req, "merchandiser")
+	}
+
+	t.Run("replaces price and category at the expected version", func(t *testing.T) {
+		mockRepo := new(MockProductRepository)
+		handler := NewCatalogHandler(mockRepo, testPolicy)
+
+		price := decimal.RequireFromString("12.50")
+		shoes := "shoes"
+		updated := &models.Product{Code: "PROD001", Price: price, Version: 4, Category: &models.Category{Code: "shoes", Name: "Shoes", Version: 2}}
+		mockRepo.On("Update", mock.Anything, "PROD001", uint(3), mock.MatchedBy(func(c models.ProductChanges) bool {
+			return c.Price.Equal(price) && *c.CategoryCode == shoes
+		})).Return(updated, nil)
+
+		recorder := httptest.NewRecorder()
+		handler.HandleUpdate(recorder, newRequest("PUT", `{"price": 12.50, "category": "shoes"}`, `"3.1"`))
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, `"4.2"`, recorder.Header().Get("ETag"))
+		assert.JSONEq(t, `{"code":"PROD001","price":12.5,"category":{"code":"shoes","name":"Shoes"},"variants":[]}`, recorder.Body.String())
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("removes the category when a replacement omits it", func(t *testing.T) {
+		mockRepo := new(MockProductRepository)
+		handler := NewCatalogHandler(mockRepo, testPolicy)
+
+		mockRepo.On("Update", mock.Anything, "PROD001", models.AnyVersion, mock.MatchedBy(func(c models.ProductChanges) bool {
+			return c.CategoryCode != nil && *c.CategoryCode == ""
+		})).Return(&models.Product{Code: "PROD001", Version: 2}, nil)
+
+		recorder := httptest.NewRecorder()
+		handler.HandleUpdate(recorder, newRequest("PUT", `{"price": 9}`, "*"))
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("patches only the fields sent", func(t *testing.T) {
+		mockRepo := new(MockProductRepository)
+		handler := NewCatalogHandler(mockRepo, testPolicy)
+
+		mockRepo.On("Update", mock.Anything, "PROD001", uint(1), mock.MatchedBy(func(c models.ProductChanges) bool {
+			return c.Price == nil && *c.CategoryCode == "bags"
+		})).Return(&models.Product{Code: "PROD001", Version: 2}, nil)
+
+		recorder := httptest.NewRecorder()
+		handler.HandlePatch(recorder, newRequest("PATCH", `{"category": "bags"}`, `"1.0"`))
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("requires If-Match", func(t *testing.T) {
+		mockRepo := new(MockProductRepository)
+		handler := NewCatalogHandler(mockRepo, testPolicy)
+
+		recorder := httptest.NewRecorder()
+		handler.HandleUpdate(recorder, newRequest("PUT", `{"price": 9}`, ""))
+
+		assert.Equal(t, http.StatusPreconditionRequired, recorder.Code)
+		mockRepo.AssertNotCalled(t, "Update")
+	})
+
+	t.Run("returns 412 when the version is stale", func(t *testing.T) {
+		mockRepo := new(MockProductRepository)
+		handler := NewCatalogHandler(mockRepo, testPolicy)
+
+		stale := &models.Error{Kind: models.ErrPreconditionFailed, Message: "product has been modified since it was read"}
+		mockRepo.On("Update", mock.Anything, "PROD001", uint(2), mock.Anything).Return(nil, stale)
+
+		recorder := httptest.NewRecorder()
+		handler.HandlePatch(recorder, newRequest("PATCH", `{"price": 9}`, `"2.1"`))
+
+		assert.Equal(t, http.StatusPreconditionFailed, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"code":"precondition_failed"`)
+	})
+
+	t.Run("rejects a replacement without price", func(t *testing.T) {
+		mockRepo := new(MockProductRepository)
+		handler := NewCatalogHandler(mockRepo, testPolicy)
+
+		recorder := httptest.NewRecorder()
+		handler.HandleUpdate(recorder, newRequest("PUT", `{"category": "shoes"}`, `"1"`))
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"field":"price"`)
+	})
+
+	t.Run("rejects prices with more than two decimal places", func(t *testing.T) {
+		mockRepo := new(MockProductRepository)
+		handler := NewCatalogHandler(mockRepo, testPolicy)
+
+		recorder := httptest.NewRecorder()
+		handler.HandlePatch(recorder, newRequest("PATCH", `{"price": 12.345}`, `"1.0"`))
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), "price must have at most 2 decimal places")
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("requires prices:write to change the price", func(t *testing.T) {
+		mockRepo := new(MockProductRepository)
+		handler := NewCatalogHandler(mockRepo, testPolicy)
+
+		patch := httptest.NewRecorder()
+		handler.HandlePatch(patch, as(newRequest("PATCH", `{"price": 9}`, `"1.0"`), "editor"))
+		replace := httptest.NewRecorder()
+		handler.HandleUpdate(replace, as(newRequest("PUT", `{"price": 9, "category": "shoes"}`, `"1.0"`), "editor"))
+
+		for _, recorder := range []*httptest.ResponseRecorder{patch, replace} {
+			assert.Equal(t, http.StatusForbidden, recorder.Code)
+			assert.Contains(t, recorder.Body.String(), `"missing_permission":"prices:write"`)
+		}
+		mockRepo.AssertNotCalled(t, "Update")
+	})
+
+	t.Run("lets catalog:write alone change the category", func(t *testing.T) {
+		mockRepo := new(MockProductRepository)
+		handler := NewCatalogHandler(mockRepo, testPolicy)
+		mockRepo.On("Update", mock.Anything, "PROD001", uint(1), mock.Anything).Return(&models.Product{Code: "PROD001", Version: 2}, nil)
+
+		recorder := httptest.NewRecorder()
+		handler.HandlePatch(recorder, as(newRequest("PATCH", `{"category": "bags"}`, `"1.0"`), "editor"))
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	})
+}
+
+func TestCatalogHandler_HandleDelete(t *testing.T) {
+	mockRepo := new(MockProductRepository)
+	handler := NewCatalogHandler(mockRepo, testPolicy)
+	mockRepo.On("Delete", mock.Anything, "PROD001", uint(5)).Return(nil)
+
+	req := httptest.NewRequest("DELETE", "/catalog/PROD001", nil)
+	req.SetPathValue("code", "PROD001")
+	req.Header.Set("If-Match", `"5.1"`)
+	recorder := httptest.NewRecorder()
+
+	handler.HandleDelete(recorder, req)
+
+	assert.Equal(t, http.StatusNoContent, recorder.Code)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCatalogHandler_HandleRestore(t *testing.T) {
+	newRequest := func() *http.Request {
+		req := httptest.NewRequest("POST", "/catalog/PROD001/restore", nil)
+		req.SetPathValue("code", "PROD001")
+		return req
+	}
+
+	t.Run("returns the restored product with its new version", func(t *testing.T) {
+		mockRepo := new(MockProductRepository)
+		handler := NewCatalogHandler(mockRepo, testPolicy)
+		mockRepo.On("Restore", mock.Anything, "PROD001").Return(&models.Product{Code: "PROD001", Price: decimal.NewFromInt(10), Version: 7}, nil)
+
+		recorder := httptest.NewRecorder()
+		handler.HandleRestore(recorder, newRequest())
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, `"7.0"`, recorder.Header().Get("ETag"))
+		assert.NotContains(t, recorder.Body.String(), "deleted_at")
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("returns 409 for products that are not deleted", func(t *testing.T) {
+		mockRepo := new(MockProductRepository)
+		handler := NewCatalogHandler(mockRepo, testPolicy)
+		mockRepo.On("Restore", mock.Anything, "PROD001").Return(nil, &models.Error{Kind: models.ErrConflict, Message: "product is not deleted"})
+
+		recorder := httptest.NewRecorder()
+		handler.HandleRestore(recorder, newRequest())
+
+		assert.Equal(t, http.StatusConflict, recorder.Code)
+	})
+}
+
+func TestCatalogHandler_Variants(t *testing.T) {
+	product := &models.Product{Code: "PROD001", Price: decimal.NewFromInt(20), Version: 2}
+	newRequest := func(method, body, ifMatch string) *http.Request {
+		req := httptest.NewRequest(method, "/catalog/PROD001/variants/SKU001A", strings.NewReader(body))
+		req.SetPathValue("code", "PROD001")
+		req.SetPathValue("sku", "SKU001A")
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		return as(req, "merchandiser")
+	}
+
+	t.Run("returns a variant with its and its product's version as ETag", func(t *testing.T) {
+		mockRepo := new(MockProductRepository)
+		handler := NewCatalogHandler(mockRepo, testPolicy)
+		mockRepo.On("GetVariant", mock.Anything, "PROD001", "SKU001A", false).
+			Return(&models.Variant{Name: "Variant A", SKU: "SKU001A", Version: 3, Product: product}, nil)
+
+		recorder := httptest.NewRecorder()
+		handler.HandleGetVariant(recorder, newRequest("GET", "", ""))
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, `"3.2"`, recorder.Header().Get("ETag"))
+		assert.JSONEq(t, `{"name":"Variant A","sku":"SKU001A","price":20}`, recorder.Body.String())
+	})
+
+	t.Run("revalidates an inherited price after the product's price changed", func(t *testing.T) {
+		variantUpdated := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+		repriced := &models.Product{Code: "PROD001", Price: decimal.NewFromInt(30), Version: 3, UpdatedAt: variantUpdated.Add(time.Hour)}
+		mockRepo := new(MockProductRepository)
+		handler := NewCatalogHandler(mockRepo, testPolicy)
+		mockRepo.On("GetVariant", mock.Anything, "PROD001", "SKU001A", false).
+			Return(&models.Variant{Name: "Variant A", SKU: "SKU001A", Version: 3, UpdatedAt: variantUpdated, Product: repriced}, nil)
+
+		req := newRequest("GET", "", "")
+		req.Header.Set("If-None-Match", `"3.2"`)
+		recorder := httptest.NewRecorder()
+		handler.HandleGetVariant(recorder, req)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, `"3.3"`, recorder.Header().Get("ETag"))
+		assert.Equal(t, "Fri, 01 Mar 2024 13:00:00 GMT", recorder.Header().Get("Last-Modified"))
+		assert.JSONEq(t, `{"name":"Variant A","sku":"SKU001A","price":30}`, recorder.Body.String())
+	})
+
+	t.Run("replacing without a price makes the variant inherit it", func(t *testing.T) {
+		mockRepo := new(MockProductRepository)
+		handler := NewCatalogHandler(mockRepo, testPolicy)
+		mockRepo.On("UpdateVariant", mock.Anything, "PROD001", "SKU001A", uint(3), mock.MatchedBy(func(c models.VariantChanges) bool {
+			return *c.Name == "Variant A2" && c.Price.IsZero()
+		})).Return(&models.Variant{Name: "Variant A2", SKU: "SKU001A", Version: 4, Product: product}, nil)
+
+		recorder := httptest.NewRecorder()
+		handler.HandleUpdateVariant(recorder, newRequest("PUT", `{"name": "Variant A2"}`, `"3"`))
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, `"4.2"`, recorder.Header().Get("ETag"))
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("patches the price only", func(t *testing.T) {
+		mockRepo := new(MockProductRepository)
+		handler := NewCatalogHandler(mockRepo, testPolicy)
+		mockRepo.On("UpdateVariant", mock.Anything, "PROD001", "SKU001A", uint(3), mock.MatchedBy(func(c models.VariantChanges) bool {
+			return c.Name == nil && c.Price.Equal(decimal.NewFromInt(25))
+		})).Return(&models.Variant{Name: "Variant A", SKU: "SKU001A", Price: decimal.NewFromInt(25), Version: 4, Product: product}, nil)
+
+		recorder := httptest.NewRecorder()
+		handler.HandlePatchVariant(recorder, newRequest("PATCH", `{"price": "25"}`, `"3"`))
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("requires prices:write to change or reset the price", func(t *testing.T) {
+		mockRepo := new(MockProductRepository)
+		handler := NewCatalogHandler(mockRepo, testPolicy)
+
+		patch := httptest.NewRecorder()
+		handler.HandlePatchVariant(patch, as(newRequest("PATCH", `{"price": "25"}`, `"3"`), "editor"))
+		replace := httptest.NewRecorder()
+		handler.HandleUpdateVariant(replace, as(newRequest("PUT", `{"name": "Variant A2"}`, `"3"`), "editor"))
+
+		for _, recorder := range []*httptest.ResponseRecorder{patch, replace} {
+			assert.Equal(t, http.StatusForbidden, recorder.Code)
+			assert.Contains(t, recorder.Body.String(), `"missing_permission":"prices:write"`)
+		}
+		mockRepo.AssertNotCalled(t, "UpdateVariant")
+	})
+
+	t.Run("lets catalog:write alone rename a variant", func(t *testing.T) {
+		mockRepo := new(MockProductRepository)
+		handler := NewCatalogHandler(mockRepo, testPolicy)
+		mockRepo.On("UpdateVariant", mock.Anything, "PROD001", "SKU001A", uint(3), mock.MatchedBy(func(c models.VariantChanges) bool {
+			return *c.Name == "Variant A2" && c.Price == nil
+		})).Return(&models.Variant{Name: "Variant A2", SKU: "SKU001A", Version: 4, Product: product}, nil)
+
+		recorder := httptest.NewRecorder()
+		handler.HandlePatchVariant(recorder, as(newRequest("PATCH", `{"name": "Variant A2"}`, `"3"`), "editor"))
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	})
+
+	t.Run("deletes at the expected version", func(t *testing.T) {
+		mockRepo := new(MockProductRepository)
+		handler := NewCatalogHandler(mockRepo, testPolicy)
+		mockRepo.On("DeleteVariant", mock.Anything, "PROD001", "SKU001A", uint(3)).Return(nil)
+
+		recorder := httptest.NewRecorder()
+		handler.HandleDeleteVariant(recorder, newRequest("DELETE", "", `"3"`))
+
+		assert.Equal(t, http.StatusNoContent, recorder.Code)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("restores a deleted variant", func(t *testing.T) {
+		mockRepo := new(MockProductRepository)
+		handler := NewCatalogHandler(mockRepo, testPolicy)
+		mockRepo.On("RestoreVariant", mock.Anything, "PROD001", "SKU001A").
+			Return(&models.Variant{Name: "Variant A", SKU: "SKU001A", Version: 5, Product: product}, nil)
+
+		recorder := httptest.NewRecorder()
+		handler.HandleRestoreVariant(recorder, newRequest("POST", "", ""))
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, `"5.2"`, recorder.Header().Get("ETag"))
+		mockRepo.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,93 @@
+package catalog
+
+import (
+	"time"
+
+	"github.com/mytheresa/go-hiring-challenge/app/auth"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+// The v2 shapes carry prices as exact decimal strings with two fraction
+// digits, e.g. "12.50", so clients never round through floating point.
+
+type CatalogResponseV2 struct {
+	Products []ProductResponseV2 `json:"products"`
+	Total    int64               `json:"total"`
+}
+
+type ProductResponseV2 struct {
+	Code      string           `json:"code"`
+	Price     string           `json:"price"`
+	Category  *CategorySummary `json:"category,omitempty"`
+	DeletedAt *time.Time       `json:"deleted_at,omitempty"`
+}
+
+type ProductDetailsResponseV2 struct {
+	Code      string              `json:"code"`
+	Price     string              `json:"price"`
+	Category  *CategorySummary    `json:"category,omitempty"`
+	Variants  []VariantResponseV2 `json:"variants"`
+	DeletedAt *time.Time          `json:"deleted_at,omitempty"`
+}
+
+type VariantResponseV2 struct {
+	Name      string     `json:"name"`
+	SKU       string     `json:"sku"`
+	Price     string     `json:"price"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// NewCatalogHandlerV2 creates a handler serving the v2 response shapes.
+func NewCatalogHandlerV2(r models.ProductRepository, policy *auth.Policy) *CatalogHandler {
+	return &CatalogHandler{
+		repo:    r,
+		policy:  policy,
+		present: v2{},
+	}
+}
+
+type v2 struct{}
+
+func (v2) catalog(products []models.Product, total int64) any {
+	productResponses := make([]ProductResponseV2, len(products))
+	for i, p := range products {
+		productResponses[i] = ProductResponseV2{
+			Code:      p.Code,
+			Price:     p.Price.StringFixed(2),
+			Category:  categorySummary(p.Category),
+			DeletedAt: deletedAt(p.DeletedAt),
+		}
+	}
+	return CatalogResponseV2{
+		Products: productResponses,
+		Total:    total,
+	}
+}
+
+func (v2) product(product *models.Product) any {
+	variants := make([]VariantResponseV2, len(product.Variants))
+	for i := range product.Variants {
+		variants[i] = variantResponseV2(&product.Variants[i], product)
+	}
+
+	return ProductDetailsResponseV2{
+		Code:      product.Code,
+		Price:     product.Price.StringFixed(2),
+		Category:  categorySummary(product.Category),
+		Variants:  variants,
+		DeletedAt: deletedAt(product.DeletedAt),
+	}
+}
+
+func (v2) variant(variant *models.Variant, product *models.Product) any {
+	return variantResponseV2(variant, product)
+}
+
+func variantResponseV2(v *models.Variant, product *models.Product) VariantResponseV2 {
+	return VariantResponseV2{
+		Name:      v.Name,
+		SKU:       v.SKU,
+		Price:     effectivePrice(v, product).StringFixed(2),
+		DeletedAt: deletedAt(v.DeletedAt),
+	}
+}
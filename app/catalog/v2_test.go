@@ -0,0 +1,80 @@
+package catalog
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mytheresa/go-hiring-challenge/models"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCatalogHandlerV2(t *testing.T) {
+	category := &models.Category{ID: 1, Code: "clothing", Name: "Clothing"}
+	product := &models.Product{
+		ID:       1,
+		Code:     "PROD001",
+		Price:    decimal.RequireFromString("10.5"),
+		Category: category,
+		Variants: []models.Variant{
+			{ID: 1, ProductID: 1, Name: "Variant A", SKU: "SKU001A", Price: decimal.RequireFromString("11.99")},
+			{ID: 2, ProductID: 1, Name: "Variant B", SKU: "SKU001B", Price: decimal.Zero},
+		},
+	}
+
+	t.Run("lists products with decimal prices", func(t *testing.T) {
+		mockRepo := new(MockProductRepository)
+		handler := NewCatalogHandlerV2(mockRepo, testPolicy)
+		mockRepo.On("GetAll", mock.Anything, 0, 10, "", (*decimal.Decimal)(nil), false).Return([]models.Product{*product}, int64(1), nil)
+
+		recorder := httptest.NewRecorder()
+		handler.HandleGet(recorder, httptest.NewRequest("GET", "/v2/catalog", nil))
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.JSONEq(t, `{"products":[{"code":"PROD001","price":"10.50","category":{"code":"clothing","name":"Clothing"}}],"total":1}`, recorder.Body.String())
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("returns a product with the effective prices of its variants", func(t *testing.T) {
+		mockRepo := new(MockProductRepository)
+		handler := NewCatalogHandlerV2(mockRepo, testPolicy)
+		mockRepo.On("GetByCode", mock.Anything, "PROD001", false).Return(product, nil)
+
+		req := httptest.NewRequest("GET", "/v2/catalog/PROD001", nil)
+		req.SetPathValue("code", "PROD001")
+		recorder := httptest.NewRecorder()
+		handler.HandleGetByCode(recorder, req)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.JSONEq(t, `{
+			"code": "PROD001",
+			"price": "10.50",
+			"category": {"code": "clothing", "name": "Clothing"},
+			"variants": [
+				{"name": "Variant A", "sku": "SKU001A", "price": "11.99"},
+				{"name": "Variant B", "sku": "SKU001B", "price": "10.50"}
+			]
+		}`, recorder.Body.String())
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("returns a variant with a decimal price", func(t *testing.T) {
+		mockRepo := new(MockProductRepository)
+		handler := NewCatalogHandlerV2(mockRepo, testPolicy)
+		variant := product.Variants[1]
+		variant.Product = product
+		mockRepo.On("GetVariant", mock.Anything, "PROD001", "SKU001B", false).Return(&variant, nil)
+
+		req := httptest.NewRequest("GET", "/v2/catalog/PROD001/variants/SKU001B", nil)
+		req.SetPathValue("code", "PROD001")
+		req.SetPathValue("sku", "SKU001B")
+		recorder := httptest.NewRecorder()
+		handler.HandleGetVariant(recorder, req)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.JSONEq(t, `{"name":"Variant B","sku":"SKU001B","price":"10.50"}`, recorder.Body.String())
+		mockRepo.AssertExpectations(t)
+	})
+}
@@ -1,16 +1,17 @@
 package categories
 
 import (
-	"encoding/json"
 	"net/http"
+	"time"
 
 	"github.com/mytheresa/go-hiring-challenge/app/api"
 	"github.com/mytheresa/go-hiring-challenge/models"
 )
 
 type CategoryResponse struct {
-	Code string `json:"code"`
-	Name string `json:"name"`
+	Code      string     `json:"code"`
+	Name      string     `json:"name"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
 
 type CreateCategoryRequest struct {
@@ -18,6 +19,31 @@ type CreateCategoryRequest struct {
 	Name string `json:"name"`
 }
 
+// Validate checks the request against the limits of the categories table.
+func (req CreateCategoryRequest) Validate() error {
+	var v api.Validator
+	v.Field("code", req.Code, api.Required(), api.Length(1, 32), api.Pattern(api.SlugPattern, "a lowercase slug"))
+	v.Field("name", req.Name, api.Required(), api.Length(1, 255))
+	return v.Err()
+}
+
+type UpdateCategoryRequest struct {
+	Name *string `json:"name"`
+}
+
+// Validate checks the request; a replacement must carry a name.
+func (req UpdateCategoryRequest) Validate(replace bool) error {
+	var v api.Validator
+	if replace || req.Name != nil {
+		name := ""
+		if req.Name != nil {
+			name = *req.Name
+		}
+		v.Field("name", name, api.Required(), api.Length(1, 255))
+	}
+	return v.Err()
+}
+
 type CategoriesHandler struct {
 	repo models.CategoryRepository
 }
@@ -29,32 +55,37 @@ func NewCategoriesHandler(r models.CategoryRepository) *CategoriesHandler {
 }
 
 func (h *CategoriesHandler) HandleGet(w http.ResponseWriter, r *http.Request) {
-	categories, err := h.repo.GetAll()
+	includeDeleted, err := api.BoolQuery(r, "include_deleted")
+	if err != nil {
+		api.HandleError(w, r, err, "invalid query parameters")
+		return
+	}
+
+	categories, err := h.repo.GetAll(r.Context(), includeDeleted)
 	if err != nil {
-		api.ErrorResponse(w, http.StatusInternalServerError, "failed to fetch categories")
+		api.HandleError(w, r, err, "failed to fetch categories")
 		return
 	}
 
 	responses := make([]CategoryResponse, len(categories))
 	for i, c := range categories {
-		responses[i] = CategoryResponse{
-			Code: c.Code,
-			Name: c.Name,
-		}
+		responses[i] = categoryResponse(&c)
 	}
 
-	api.OKResponse(w, responses)
+	// No Last-Modified: a deleted category leaves the updated_at of the
+	// others unchanged, so only the body ETag tells whether the list is current
+	api.ConditionalResponse(w, r, responses, time.Time{})
 }
 
 func (h *CategoriesHandler) HandleCreate(w http.ResponseWriter, r *http.Request) {
 	var req CreateCategoryRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		api.ErrorResponse(w, http.StatusBadRequest, "invalid request body")
+	if err := api.DecodeJSON(r, &req); err != nil {
+		api.HandleError(w, r, err, "invalid request body")
 		return
 	}
 
-	if req.Code == "" || req.Name == "" {
-		api.ErrorResponse(w, http.StatusBadRequest, "code and name are required")
+	if err := req.Validate(); err != nil {
+		api.HandleError(w, r, err, "invalid request body")
 		return
 	}
 
@@ -63,14 +94,103 @@ func (h *CategoriesHandler) HandleCreate(w http.ResponseWriter, r *http.Request)
 		Name: req.Name,
 	}
 
-	if err := h.repo.Create(category); err != nil {
-		api.ErrorResponse(w, http.StatusInternalServerError, "failed to create category")
+	if err := h.repo.Create(r.Context(), category); err != nil {
+		api.HandleError(w, r, err, "failed to create category")
+		return
+	}
+
+	w.Header().Set("ETag", api.VersionETag(category.Version))
+	api.OKResponse(w, categoryResponse(category))
+}
+
+func (h *CategoriesHandler) HandleGetByCode(w http.ResponseWriter, r *http.Request) {
+	includeDeleted, err := api.BoolQuery(r, "include_deleted")
+	if err != nil {
+		api.HandleError(w, r, err, "invalid query parameters")
+		return
+	}
+
+	category, err := h.repo.GetByCode(r.Context(), r.PathValue("code"), includeDeleted)
+	if err != nil {
+		api.HandleError(w, r, err, "failed to fetch category")
+		return
+	}
+
+	api.VersionedResponse(w, r, categoryResponse(category), api.VersionETag(category.Version), category.UpdatedAt)
+}
+
+// HandleUpdate replaces the name of a category.
+func (h *CategoriesHandler) HandleUpdate(w http.ResponseWriter, r *http.Request) {
+	h.update(w, r, true)
+}
+
+// HandlePatch changes the name of a category if given.
+func (h *CategoriesHandler) HandlePatch(w http.ResponseWriter, r *http.Request) {
+	h.update(w, r, false)
+}
+
+func (h *CategoriesHandler) update(w http.ResponseWriter, r *http.Request, replace bool) {
+	version, err := api.IfMatchVersion(r)
+	if err != nil {
+		api.HandleError(w, r, err, "invalid If-Match header")
+		return
+	}
+
+	var req UpdateCategoryRequest
+	if err := api.DecodeJSON(r, &req); err != nil {
+		api.HandleError(w, r, err, "invalid request body")
+		return
+	}
+	if err := req.Validate(replace); err != nil {
+		api.HandleError(w, r, err, "invalid request body")
+		return
+	}
+
+	category, err := h.repo.Update(r.Context(), r.PathValue("code"), version, models.CategoryChanges{Name: req.Name})
+	if err != nil {
+		api.HandleError(w, r, err, "failed to update category")
 		return
 	}
 
-	api.OKResponse(w, CategoryResponse{
+	w.Header().Set("ETag", api.VersionETag(category.Version))
+	api.OKResponse(w, categoryResponse(category))
+}
+
+// HandleDelete soft-deletes a category no product is assigned to.
+func (h *CategoriesHandler) HandleDelete(w http.ResponseWriter, r *http.Request) {
+	version, err := api.IfMatchVersion(r)
+	if err != nil {
+		api.HandleError(w, r, err, "invalid If-Match header")
+		return
+	}
+
+	if err := h.repo.Delete(r.Context(), r.PathValue("code"), version); err != nil {
+		api.HandleError(w, r, err, "failed to delete category")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleRestore brings back a deleted category.
+func (h *CategoriesHandler) HandleRestore(w http.ResponseWriter, r *http.Request) {
+	category, err := h.repo.Restore(r.Context(), r.PathValue("code"))
+	if err != nil {
+		api.HandleError(w, r, err, "failed to restore category")
+		return
+	}
+
+	w.Header().Set("ETag", api.VersionETag(category.Version))
+	api.OKResponse(w, categoryResponse(category))
+}
+
+func categoryResponse(category *models.Category) CategoryResponse {
+	response := CategoryResponse{
 		Code: category.Code,
 		Name: category.Name,
-	})
+	}
+	if category.DeletedAt.Valid {
+		response.DeletedAt = &category.DeletedAt.Time
+	}
+	return response
 }
 
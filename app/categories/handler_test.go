@@ -2,12 +2,15 @@ package categories
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/mytheresa/go-hiring-challenge/app/api"
 	"github.com/mytheresa/go-hiring-challenge/models"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -17,17 +20,67 @@ type MockCategoryRepository struct {
 	mock.Mock
 }
 
-func (m *MockCategoryRepository) GetAll() ([]models.Category, error) {
-	args := m.Called()
+func (m *MockCategoryRepository) GetAll(ctx context.Context, includeDeleted bool) ([]models.Category, error) {
+	args := m.Called(ctx, includeDeleted)
 	return args.Get(0).([]models.Category), args.Error(1)
 }
 
-func (m *MockCategoryRepository) Create(category *models.Category) error {
-	args := m.Called(category)
+func (m *MockCategoryRepository) Create(ctx context.Context, category *models.Category) error {
+	args := m.Called(ctx, category)
 	return args.Error(0)
 }
 
+func (m *MockCategoryRepository) GetByCode(ctx context.Context, code string, includeDeleted bool) (*models.Category, error) {
+	args := m.Called(ctx, code, includeDeleted)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Category), args.Error(1)
+}
+
+func (m *MockCategoryRepository) GetByIDs(ctx context.Context, ids []uint) ([]models.Category, error) {
+	args := m.Called(ctx, ids)
+	return args.Get(0).([]models.Category), args.Error(1)
+}
+
+func (m *MockCategoryRepository) Update(ctx context.Context, code string, version uint, changes models.CategoryChanges) (*models.Category, error) {
+	args := m.Called(ctx, code, version, changes)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Category), args.Error(1)
+}
+
+func (m *MockCategoryRepository) Delete(ctx context.Context, code string, version uint) error {
+	args := m.Called(ctx, code, version)
+	return args.Error(0)
+}
+
+func (m *MockCategoryRepository) Restore(ctx context.Context, code string) (*models.Category, error) {
+	args := m.Called(ctx, code)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Category), args.Error(1)
+}
+
 func TestCategoriesHandler_HandleGet(t *testing.T) {
+	t.Run("validates the list by ETag only", func(t *testing.T) {
+		mockRepo := new(MockCategoryRepository)
+		handler := NewCategoriesHandler(mockRepo)
+		updated := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+		mockRepo.On("GetAll", mock.Anything, false).Return([]models.Category{{ID: 1, Code: "shoes", Name: "Shoes", UpdatedAt: updated}}, nil)
+
+		req := httptest.NewRequest("GET", "/categories", nil)
+		req.Header.Set("If-Modified-Since", updated.Add(time.Hour).Format(http.TimeFormat))
+		recorder := httptest.NewRecorder()
+		handler.HandleGet(recorder, req)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Empty(t, recorder.Header().Get("Last-Modified"))
+		assert.NotEmpty(t, recorder.Header().Get("ETag"))
+	})
+
 	t.Run("returns all categories", func(t *testing.T) {
 		mockRepo := new(MockCategoryRepository)
 		handler := NewCategoriesHandler(mockRepo)
@@ -38,7 +91,7 @@ func TestCategoriesHandler_HandleGet(t *testing.T) {
 			{ID: 3, Code: "accessories", Name: "Accessories"},
 		}
 
-		mockRepo.On("GetAll").Return(categories, nil)
+		mockRepo.On("GetAll", mock.Anything, false).Return(categories, nil)
 
 		req := httptest.NewRequest("GET", "/categories", nil)
 		recorder := httptest.NewRecorder()
@@ -62,7 +115,7 @@ func TestCategoriesHandler_HandleGet(t *testing.T) {
 		mockRepo := new(MockCategoryRepository)
 		handler := NewCategoriesHandler(mockRepo)
 
-		mockRepo.On("GetAll").Return([]models.Category{}, errors.New("database error"))
+		mockRepo.On("GetAll", mock.Anything, false).Return([]models.Category{}, errors.New("database error"))
 
 		req := httptest.NewRequest("GET", "/categories", nil)
 		recorder := httptest.NewRecorder()
@@ -85,7 +138,7 @@ func TestCategoriesHandler_HandleCreate(t *testing.T) {
 		}
 		body, _ := json.Marshal(reqBody)
 
-		mockRepo.On("Create", mock.AnythingOfType("*models.Category")).Return(nil)
+		mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*models.Category")).Return(nil)
 
 		req := httptest.NewRequest("POST", "/categories", bytes.NewBuffer(body))
 		req.Header.Set("Content-Type", "application/json")
@@ -118,6 +171,63 @@ func TestCategoriesHandler_HandleCreate(t *testing.T) {
 		assert.Equal(t, http.StatusBadRequest, recorder.Code)
 	})
 
+	t.Run("returns 400 for unknown fields and trailing data", func(t *testing.T) {
+		for _, body := range []string{
+			`{"code":"electronics","name":"Electronics","id":7}`,
+			`{"code":"electronics","name":"Electronics"} {"code":"x"}`,
+		} {
+			mockRepo := new(MockCategoryRepository)
+			handler := NewCategoriesHandler(mockRepo)
+
+			req := httptest.NewRequest("POST", "/categories", bytes.NewBufferString(body))
+			req.Header.Set("Content-Type", "application/json")
+			recorder := httptest.NewRecorder()
+
+			handler.HandleCreate(recorder, req)
+
+			assert.Equal(t, http.StatusBadRequest, recorder.Code, body)
+			var problem api.Problem
+			assert.NoError(t, json.NewDecoder(recorder.Body).Decode(&problem))
+			assert.Equal(t, api.CodeInvalidBody, problem.Code)
+			mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+		}
+	})
+
+	t.Run("returns 400 for codes that are not short lowercase slugs", func(t *testing.T) {
+		mockRepo := new(MockCategoryRepository)
+		handler := NewCategoriesHandler(mockRepo)
+
+		body := `{"code":"Home Decor","name":""}`
+		req := httptest.NewRequest("POST", "/categories", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+
+		handler.HandleCreate(recorder, req)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		var problem api.Problem
+		assert.NoError(t, json.NewDecoder(recorder.Body).Decode(&problem))
+		assert.Equal(t, []api.FieldError{
+			{Field: "code", Code: "pattern", Message: "code must be a lowercase slug"},
+			{Field: "name", Code: "required", Message: "name is required"},
+		}, problem.Errors)
+	})
+
+	t.Run("returns 413 when body exceeds the limit", func(t *testing.T) {
+		mockRepo := new(MockCategoryRepository)
+		handler := NewCategoriesHandler(mockRepo)
+
+		body := `{"code":"electronics","name":"Electronics"}`
+		req := httptest.NewRequest("POST", "/categories", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		req.Body = http.MaxBytesReader(recorder, req.Body, 10)
+
+		handler.HandleCreate(recorder, req)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+	})
+
 	t.Run("returns 400 when code is missing", func(t *testing.T) {
 		mockRepo := new(MockCategoryRepository)
 		handler := NewCategoriesHandler(mockRepo)
@@ -134,6 +244,13 @@ func TestCategoriesHandler_HandleCreate(t *testing.T) {
 		handler.HandleCreate(recorder, req)
 
 		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Equal(t, api.ProblemContentType, recorder.Header().Get("Content-Type"))
+
+		var problem api.Problem
+		err := json.NewDecoder(recorder.Body).Decode(&problem)
+		assert.NoError(t, err)
+		assert.Equal(t, api.CodeValidationFailed, problem.Code)
+		assert.Equal(t, []api.FieldError{{Field: "code", Code: "required", Message: "code is required"}}, problem.Errors)
 	})
 
 	t.Run("returns 400 when name is missing", func(t *testing.T) {
@@ -154,6 +271,29 @@ func TestCategoriesHandler_HandleCreate(t *testing.T) {
 		assert.Equal(t, http.StatusBadRequest, recorder.Code)
 	})
 
+	t.Run("returns 409 for duplicate codes", func(t *testing.T) {
+		mockRepo := new(MockCategoryRepository)
+		handler := NewCategoriesHandler(mockRepo)
+
+		reqBody := CreateCategoryRequest{
+			Code: "clothing",
+			Name: "Clothing",
+		}
+		body, _ := json.Marshal(reqBody)
+
+		conflict := &models.Error{Kind: models.ErrConflict, Message: "category already exists"}
+		mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*models.Category")).Return(conflict)
+
+		req := httptest.NewRequest("POST", "/categories", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+
+		handler.HandleCreate(recorder, req)
+
+		assert.Equal(t, http.StatusConflict, recorder.Code)
+		mockRepo.AssertExpectations(t)
+	})
+
 	t.Run("handles repository errors", func(t *testing.T) {
 		mockRepo := new(MockCategoryRepository)
 		handler := NewCategoriesHandler(mockRepo)
@@ -164,7 +304,7 @@ func TestCategoriesHandler_HandleCreate(t *testing.T) {
 		}
 		body, _ := json.Marshal(reqBody)
 
-		mockRepo.On("Create", mock.AnythingOfType("*models.Category")).Return(errors.New("database error"))
+		mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*models.Category")).Return(errors.New("database error"))
 
 		req := httptest.NewRequest("POST", "/categories", bytes.NewBuffer(body))
 		req.Header.Set("Content-Type", "application/json")
@@ -177,3 +317,156 @@ func TestCategoriesHandler_HandleCreate(t *testing.T) {
 	})
 }
 
+func TestCategoriesHandler_HandleGetByCode(t *testing.T) {
+	t.Run("returns the category with its version as ETag", func(t *testing.T) {
+		mockRepo := new(MockCategoryRepository)
+		handler := NewCategoriesHandler(mockRepo)
+		mockRepo.On("GetByCode", mock.Anything, "shoes", false).Return(&models.Category{Code: "shoes", Name: "Shoes", Version: 2}, nil)
+
+		req := httptest.NewRequest("GET", "/categories/shoes", nil)
+		req.SetPathValue("code", "shoes")
+		recorder := httptest.NewRecorder()
+
+		handler.HandleGetByCode(recorder, req)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, `"2"`, recorder.Header().Get("ETag"))
+		assert.JSONEq(t, `{"code":"shoes","name":"Shoes"}`, recorder.Body.String())
+	})
+
+	t.Run("returns 404 for unknown categories", func(t *testing.T) {
+		mockRepo := new(MockCategoryRepository)
+		handler := NewCategoriesHandler(mockRepo)
+		mockRepo.On("GetByCode", mock.Anything, "hats", false).Return(nil, &models.Error{Kind: models.ErrNotFound, Message: "category not found"})
+
+		req := httptest.NewRequest("GET", "/categories/hats", nil)
+		req.SetPathValue("code", "hats")
+		recorder := httptest.NewRecorder()
+
+		handler.HandleGetByCode(recorder, req)
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+}
+
+func TestCategoriesHandler_HandleUpdate(t *testing.T) {
+	newRequest := func(method, body, ifMatch string) *http.Request {
+		req := httptest.NewRequest(method, "/categories/shoes", bytes.NewBufferString(body))
+		req.SetPathValue("code", "shoes")
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		return req
+	}
+
+	t.Run("renames the category at the expected version", func(t *testing.T) {
+		mockRepo := new(MockCategoryRepository)
+		handler := NewCategoriesHandler(mockRepo)
+		name := "Footwear"
+		mockRepo.On("Update", mock.Anything, "shoes", uint(2), models.CategoryChanges{Name: &name}).
+			Return(&models.Category{Code: "shoes", Name: name, Version: 3}, nil)
+
+		recorder := httptest.NewRecorder()
+		handler.HandleUpdate(recorder, newRequest("PUT", `{"name": "Footwear"}`, `"2"`))
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, `"3"`, recorder.Header().Get("ETag"))
+		assert.JSONEq(t, `{"code":"shoes","name":"Footwear"}`, recorder.Body.String())
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("requires a name on replacement but not on patch", func(t *testing.T) {
+		mockRepo := new(MockCategoryRepository)
+		handler := NewCategoriesHandler(mockRepo)
+		mockRepo.On("Update", mock.Anything, "shoes", uint(2), models.CategoryChanges{}).
+			Return(&models.Category{Code: "shoes", Name: "Shoes", Version: 3}, nil)
+
+		put := httptest.NewRecorder()
+		handler.HandleUpdate(put, newRequest("PUT", `{}`, `"2"`))
+		patch := httptest.NewRecorder()
+		handler.HandlePatch(patch, newRequest("PATCH", `{}`, `"2"`))
+
+		assert.Equal(t, http.StatusBadRequest, put.Code)
+		assert.Equal(t, http.StatusOK, patch.Code)
+	})
+
+	t.Run("returns 428 without If-Match and 412 for stale versions", func(t *testing.T) {
+		mockRepo := new(MockCategoryRepository)
+		handler := NewCategoriesHandler(mockRepo)
+		stale := &models.Error{Kind: models.ErrPreconditionFailed, Message: "category has been modified since it was read"}
+		mockRepo.On("Update", mock.Anything, "shoes", uint(1), mock.Anything).Return(nil, stale)
+
+		missing := httptest.NewRecorder()
+		handler.HandleUpdate(missing, newRequest("PUT", `{"name": "Footwear"}`, ""))
+		outdated := httptest.NewRecorder()
+		handler.HandleUpdate(outdated, newRequest("PUT", `{"name": "Footwear"}`, `"1"`))
+
+		assert.Equal(t, http.StatusPreconditionRequired, missing.Code)
+		assert.Equal(t, http.StatusPreconditionFailed, outdated.Code)
+	})
+}
+
+func TestCategoriesHandler_HandleDelete(t *testing.T) {
+	newRequest := func() *http.Request {
+		req := httptest.NewRequest("DELETE", "/categories/shoes", nil)
+		req.SetPathValue("code", "shoes")
+		req.Header.Set("If-Match", `"2"`)
+		return req
+	}
+
+	t.Run("deletes at the expected version", func(t *testing.T) {
+		mockRepo := new(MockCategoryRepository)
+		handler := NewCategoriesHandler(mockRepo)
+		mockRepo.On("Delete", mock.Anything, "shoes", uint(2)).Return(nil)
+
+		recorder := httptest.NewRecorder()
+		handler.HandleDelete(recorder, newRequest())
+
+		assert.Equal(t, http.StatusNoContent, recorder.Code)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("returns 409 while products are assigned", func(t *testing.T) {
+		mockRepo := new(MockCategoryRepository)
+		handler := NewCategoriesHandler(mockRepo)
+		inUse := &models.Error{Kind: models.ErrConflict, Message: "category is still assigned to products"}
+		mockRepo.On("Delete", mock.Anything, "shoes", uint(2)).Return(inUse)
+
+		recorder := httptest.NewRecorder()
+		handler.HandleDelete(recorder, newRequest())
+
+		assert.Equal(t, http.StatusConflict, recorder.Code)
+	})
+}
+
+func TestCategoriesHandler_HandleRestore(t *testing.T) {
+	newRequest := func() *http.Request {
+		req := httptest.NewRequest("POST", "/categories/shoes/restore", nil)
+		req.SetPathValue("code", "shoes")
+		return req
+	}
+
+	t.Run("returns the restored category with its new version", func(t *testing.T) {
+		mockRepo := new(MockCategoryRepository)
+		handler := NewCategoriesHandler(mockRepo)
+		mockRepo.On("Restore", mock.Anything, "shoes").Return(&models.Category{Code: "shoes", Name: "Shoes", Version: 4}, nil)
+
+		recorder := httptest.NewRecorder()
+		handler.HandleRestore(recorder, newRequest())
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, `"4"`, recorder.Header().Get("ETag"))
+		assert.JSONEq(t, `{"code":"shoes","name":"Shoes"}`, recorder.Body.String())
+	})
+
+	t.Run("returns 404 for unknown categories", func(t *testing.T) {
+		mockRepo := new(MockCategoryRepository)
+		handler := NewCategoriesHandler(mockRepo)
+		mockRepo.On("Restore", mock.Anything, "shoes").Return(nil, &models.Error{Kind: models.ErrNotFound, Message: "category not found"})
+
+		recorder := httptest.NewRecorder()
+		handler.HandleRestore(recorder, newRequest())
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+}
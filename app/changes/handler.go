@@ -0,0 +1,189 @@
+package changes
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mytheresa/go-hiring-challenge/app/api"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+// Response is a page of the change feed. Changed rows are listed by entity
+// and deleted ones as tombstones. Next is the token to send as since on the
+// following request; HasMore reports that it will return more right away.
+type Response struct {
+	Categories []CategoryChange `json:"categories"`
+	Products   []ProductChange  `json:"products"`
+	Variants   []VariantChange  `json:"variants"`
+	Deleted    []Tombstone      `json:"deleted"`
+	Next       string           `json:"next"`
+	HasMore    bool             `json:"has_more"`
+}
+
+type CategoryChange struct {
+	Code      string    `json:"code"`
+	Name      string    `json:"name"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type ProductChange struct {
+	Code      string    `json:"code"`
+	Price     float64   `json:"price"`
+	Category  *string   `json:"category"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type VariantChange struct {
+	SKU     string `json:"sku"`
+	Product string `json:"product"`
+	Name    string `json:"name"`
+	// Price is nil for variants that inherit the product price.
+	Price     *float64  `json:"price"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Tombstone reports a deleted row; Code is the SKU for variants.
+type Tombstone struct {
+	Entity    string    `json:"entity"`
+	Code      string    `json:"code"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+type Handler struct {
+	repo    models.ChangeRepository
+	present func(*models.Changes) any
+}
+
+// NewHandler creates a handler serving the v1 response shape.
+func NewHandler(r models.ChangeRepository) *Handler {
+	return &Handler{
+		repo:    r,
+		present: func(changes *models.Changes) any { return response(changes) },
+	}
+}
+
+// HandleGet returns the rows changed after the since token, or the whole
+// catalog without it.
+func (h *Handler) HandleGet(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var v api.Validator
+	v.Field("since", query.Get("since"), validToken())
+	v.Field("limit", query.Get("limit"), api.IntRange(1, 1000))
+	if err := v.Err(); err != nil {
+		api.HandleError(w, r, err, "invalid query parameters")
+		return
+	}
+
+	cursor, _ := decodeToken(query.Get("since"))
+
+	limit := 100
+	if limitStr := query.Get("limit"); limitStr != "" {
+		limit, _ = strconv.Atoi(limitStr)
+	}
+
+	changes, err := h.repo.GetChanges(r.Context(), cursor, limit)
+	if err != nil {
+		api.HandleError(w, r, err, "failed to fetch changes")
+		return
+	}
+
+	api.OKResponse(w, h.present(changes))
+}
+
+func response(changes *models.Changes) Response {
+	response := Response{
+		Categories: []CategoryChange{},
+		Products:   []ProductChange{},
+		Variants:   []VariantChange{},
+		Deleted:    []Tombstone{},
+		Next:       encodeToken(changes.Next),
+		HasMore:    changes.More,
+	}
+
+	for _, c := range changes.Categories {
+		if c.DeletedAt.Valid {
+			response.Deleted = append(response.Deleted, Tombstone{Entity: models.AuditEntityCategory, Code: c.Code, DeletedAt: c.DeletedAt.Time})
+			continue
+		}
+		response.Categories = append(response.Categories, CategoryChange{Code: c.Code, Name: c.Name, UpdatedAt: c.UpdatedAt})
+	}
+
+	for _, p := range changes.Products {
+		if p.DeletedAt.Valid {
+			response.Deleted = append(response.Deleted, Tombstone{Entity: models.AuditEntityProduct, Code: p.Code, DeletedAt: p.DeletedAt.Time})
+			continue
+		}
+		change := ProductChange{Code: p.Code, Price: p.Price.InexactFloat64(), UpdatedAt: p.UpdatedAt}
+		if p.Category != nil {
+			change.Category = &p.Category.Code
+		}
+		response.Products = append(response.Products, change)
+	}
+
+	for _, v := range changes.Variants {
+		if v.DeletedAt.Valid {
+			response.Deleted = append(response.Deleted, Tombstone{Entity: models.AuditEntityVariant, Code: v.SKU, DeletedAt: v.DeletedAt.Time})
+			continue
+		}
+		change := VariantChange{SKU: v.SKU, Name: v.Name, UpdatedAt: v.UpdatedAt}
+		if v.Product != nil {
+			change.Product = v.Product.Code
+		}
+		if !v.Price.IsZero() {
+			price := v.Price.InexactFloat64()
+			change.Price = &price
+		}
+		response.Variants = append(response.Variants, change)
+	}
+
+	return response
+}
+
+// token is the wire form of a cursor. It is opaque to clients.
+type token struct {
+	From     uint64 `json:"f"`
+	To       uint64 `json:"t,omitempty"`
+	Entity   string `json:"e,omitempty"`
+	AfterXID uint64 `json:"x,omitempty"`
+	AfterID  uint   `json:"i,omitempty"`
+}
+
+func encodeToken(c models.ChangeCursor) string {
+	b, _ := json.Marshal(token(c))
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeToken parses a token; the empty token is the start of the feed.
+func decodeToken(s string) (models.ChangeCursor, bool) {
+	if s == "" {
+		return models.ChangeCursor{}, true
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return models.ChangeCursor{}, false
+	}
+	var t token
+	if err := json.Unmarshal(b, &t); err != nil {
+		return models.ChangeCursor{}, false
+	}
+	switch {
+	case t.To == 0 && (t.Entity != "" || t.AfterXID != 0 || t.AfterID != 0):
+		return models.ChangeCursor{}, false
+	case t.To != 0 && (t.To < t.From || (t.Entity != models.AuditEntityCategory && t.Entity != models.AuditEntityProduct && t.Entity != models.AuditEntityVariant)):
+		return models.ChangeCursor{}, false
+	}
+	return models.ChangeCursor(t), true
+}
+
+func validToken() api.Rule {
+	return func(value string) *api.FieldError {
+		if _, ok := decodeToken(value); !ok {
+			return &api.FieldError{Code: "format", Message: "must be a token returned by this endpoint"}
+		}
+		return nil
+	}
+}
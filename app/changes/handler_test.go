@@ -0,0 +1,115 @@
+package changes
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mytheresa/go-hiring-challenge/models"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type MockChangeRepository struct {
+	mock.Mock
+}
+
+func (m *MockChangeRepository) GetChanges(ctx context.Context, cursor models.ChangeCursor, limit int) (*models.Changes, error) {
+	args := m.Called(ctx, cursor, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Changes), args.Error(1)
+}
+
+var updated = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func TestHandler_HandleGet(t *testing.T) {
+	t.Run("starts from the beginning without a token", func(t *testing.T) {
+		mockRepo := new(MockChangeRepository)
+		handler := NewHandler(mockRepo)
+		shoes := &models.Category{Code: "shoes", Name: "Shoes", UpdatedAt: updated}
+		product := &models.Product{Code: "PROD001", Price: decimal.RequireFromString("10.99"), Category: shoes, UpdatedAt: updated}
+		mockRepo.On("GetChanges", mock.Anything, models.ChangeCursor{}, 100).Return(&models.Changes{
+			Categories: []models.Category{*shoes},
+			Products:   []models.Product{*product},
+			Variants: []models.Variant{
+				{SKU: "SKU001A", Name: "Red", Product: product, UpdatedAt: updated},
+				{SKU: "SKU001B", Name: "Blue", Price: decimal.RequireFromString("12.50"), Product: product, UpdatedAt: updated},
+			},
+			Next: models.ChangeCursor{From: 734},
+		}, nil)
+
+		recorder := httptest.NewRecorder()
+		handler.HandleGet(recorder, httptest.NewRequest("GET", "/catalog/changes", nil))
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.JSONEq(t, `{
+			"categories": [{"code":"shoes","name":"Shoes","updated_at":"2024-03-01T12:00:00Z"}],
+			"products": [{"code":"PROD001","price":10.99,"category":"shoes","updated_at":"2024-03-01T12:00:00Z"}],
+			"variants": [
+				{"sku":"SKU001A","product":"PROD001","name":"Red","price":null,"updated_at":"2024-03-01T12:00:00Z"},
+				{"sku":"SKU001B","product":"PROD001","name":"Blue","price":12.5,"updated_at":"2024-03-01T12:00:00Z"}
+			],
+			"deleted": [],
+			"next": "`+encodeToken(models.ChangeCursor{From: 734})+`",
+			"has_more": false
+		}`, recorder.Body.String())
+	})
+
+	t.Run("continues from the token and reports deletions as tombstones", func(t *testing.T) {
+		mockRepo := new(MockChangeRepository)
+		handler := NewHandler(mockRepo)
+		since := models.ChangeCursor{From: 734}
+		next := models.ChangeCursor{From: 734, To: 810, Entity: models.AuditEntityVariant, AfterXID: 790, AfterID: 12}
+		deleted := gorm.DeletedAt{Time: updated, Valid: true}
+		mockRepo.On("GetChanges", mock.Anything, since, 2).Return(&models.Changes{
+			Products: []models.Product{{Code: "PROD002", DeletedAt: deleted}},
+			Variants: []models.Variant{{SKU: "SKU003A", DeletedAt: deleted}},
+			Next:     next,
+			More:     true,
+		}, nil)
+
+		recorder := httptest.NewRecorder()
+		handler.HandleGet(recorder, httptest.NewRequest("GET", "/catalog/changes?limit=2&since="+encodeToken(since), nil))
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.JSONEq(t, `{
+			"categories": [], "products": [], "variants": [],
+			"deleted": [
+				{"entity":"product","code":"PROD002","deleted_at":"2024-03-01T12:00:00Z"},
+				{"entity":"variant","code":"SKU003A","deleted_at":"2024-03-01T12:00:00Z"}
+			],
+			"next": "`+encodeToken(next)+`",
+			"has_more": true
+		}`, recorder.Body.String())
+	})
+
+	t.Run("rejects tokens it did not issue", func(t *testing.T) {
+		mockRepo := new(MockChangeRepository)
+		handler := NewHandler(mockRepo)
+
+		for _, since := range []string{"not-a-token", "eyJmIjoxfQ==", encodeToken(models.ChangeCursor{From: 5, To: 9, Entity: "order"})} {
+			recorder := httptest.NewRecorder()
+			handler.HandleGet(recorder, httptest.NewRequest("GET", "/catalog/changes?since="+since, nil))
+
+			assert.Equal(t, http.StatusBadRequest, recorder.Code, since)
+			assert.Contains(t, recorder.Body.String(), `"field":"since"`)
+		}
+		mockRepo.AssertNotCalled(t, "GetChanges", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestToken(t *testing.T) {
+	cursor := models.ChangeCursor{From: 734, To: 810, Entity: models.AuditEntityProduct, AfterXID: 790, AfterID: 12}
+
+	decoded, ok := decodeToken(encodeToken(cursor))
+
+	require.True(t, ok)
+	assert.Equal(t, cursor, decoded)
+}
@@ -0,0 +1,83 @@
+package changes
+
+import (
+	"time"
+
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+// ResponseV2 is Response with prices as exact decimal strings, e.g. "12.50".
+type ResponseV2 struct {
+	Categories []CategoryChange  `json:"categories"`
+	Products   []ProductChangeV2 `json:"products"`
+	Variants   []VariantChangeV2 `json:"variants"`
+	Deleted    []Tombstone       `json:"deleted"`
+	Next       string            `json:"next"`
+	HasMore    bool              `json:"has_more"`
+}
+
+type ProductChangeV2 struct {
+	Code      string    `json:"code"`
+	Price     string    `json:"price"`
+	Category  *string   `json:"category"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type VariantChangeV2 struct {
+	SKU     string `json:"sku"`
+	Product string `json:"product"`
+	Name    string `json:"name"`
+	// Price is nil for variants that inherit the product price.
+	Price     *string   `json:"price"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// NewHandlerV2 creates a handler serving the v2 response shape.
+func NewHandlerV2(r models.ChangeRepository) *Handler {
+	return &Handler{
+		repo:    r,
+		present: func(changes *models.Changes) any { return responseV2(changes) },
+	}
+}
+
+// responseV2 takes categories and tombstones from the v1 response, which
+// carry no prices.
+func responseV2(changes *models.Changes) ResponseV2 {
+	base := response(changes)
+	response := ResponseV2{
+		Categories: base.Categories,
+		Products:   []ProductChangeV2{},
+		Variants:   []VariantChangeV2{},
+		Deleted:    base.Deleted,
+		Next:       base.Next,
+		HasMore:    base.HasMore,
+	}
+
+	for _, p := range changes.Products {
+		if p.DeletedAt.Valid {
+			continue
+		}
+		change := ProductChangeV2{Code: p.Code, Price: p.Price.StringFixed(2), UpdatedAt: p.UpdatedAt}
+		if p.Category != nil {
+			change.Category = &p.Category.Code
+		}
+		response.Products = append(response.Products, change)
+	}
+
+	for _, v := range changes.Variants {
+		if v.DeletedAt.Valid {
+			continue
+		}
+		change := VariantChangeV2{SKU: v.SKU, Name: v.Name, UpdatedAt: v.UpdatedAt}
+		if v.Product != nil {
+			change.Product = v.Product.Code
+		}
+		if !v.Price.IsZero() {
+			price := v.Price.StringFixed(2)
+			change.Price = &price
+		}
+		response.Variants = append(response.Variants, change)
+	}
+
+	return response
+}
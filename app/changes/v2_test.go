@@ -0,0 +1,46 @@
+package changes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mytheresa/go-hiring-challenge/models"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+func TestHandlerV2_HandleGet(t *testing.T) {
+	mockRepo := new(MockChangeRepository)
+	handler := NewHandlerV2(mockRepo)
+	shoes := &models.Category{Code: "shoes", Name: "Shoes", UpdatedAt: updated}
+	product := &models.Product{Code: "PROD001", Price: decimal.RequireFromString("10.5"), Category: shoes, UpdatedAt: updated}
+	deleted := gorm.DeletedAt{Time: updated, Valid: true}
+	mockRepo.On("GetChanges", mock.Anything, models.ChangeCursor{}, 100).Return(&models.Changes{
+		Categories: []models.Category{*shoes},
+		Products:   []models.Product{*product, {Code: "PROD002", DeletedAt: deleted}},
+		Variants: []models.Variant{
+			{SKU: "SKU001A", Name: "Red", Product: product, UpdatedAt: updated},
+			{SKU: "SKU001B", Name: "Blue", Price: decimal.RequireFromString("12.5"), Product: product, UpdatedAt: updated},
+		},
+		Next: models.ChangeCursor{From: 734},
+	}, nil)
+
+	recorder := httptest.NewRecorder()
+	handler.HandleGet(recorder, httptest.NewRequest("GET", "/v2/catalog/changes", nil))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{
+		"categories": [{"code":"shoes","name":"Shoes","updated_at":"2024-03-01T12:00:00Z"}],
+		"products": [{"code":"PROD001","price":"10.50","category":"shoes","updated_at":"2024-03-01T12:00:00Z"}],
+		"variants": [
+			{"sku":"SKU001A","product":"PROD001","name":"Red","price":null,"updated_at":"2024-03-01T12:00:00Z"},
+			{"sku":"SKU001B","product":"PROD001","name":"Blue","price":"12.50","updated_at":"2024-03-01T12:00:00Z"}
+		],
+		"deleted": [{"entity":"product","code":"PROD002","deleted_at":"2024-03-01T12:00:00Z"}],
+		"next": "`+encodeToken(models.ChangeCursor{From: 734})+`",
+		"has_more": false
+	}`, recorder.Body.String())
+}
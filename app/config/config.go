@@ -0,0 +1,557 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds every setting the server and the seed command need.
+//
+// Values are resolved in increasing order of precedence:
+// built-in defaults, YAML file, .env file, process environment, command-line flags.
+type Config struct {
+	HTTP        HTTPConfig        `yaml:"http"`
+	Database    DatabaseConfig    `yaml:"database"`
+	Health      HealthConfig      `yaml:"health"`
+	Log         LogConfig         `yaml:"log"`
+	Tracing     TracingConfig     `yaml:"tracing"`
+	Auth        AuthConfig        `yaml:"auth"`
+	RateLimit   RateLimitConfig   `yaml:"rate_limit"`
+	Cache       CacheConfig       `yaml:"cache"`
+	Idempotency IdempotencyConfig `yaml:"idempotency"`
+	Outbox      OutboxConfig      `yaml:"outbox"`
+	Webhooks    WebhooksConfig    `yaml:"webhooks"`
+	API         APIConfig         `yaml:"api"`
+}
+
+type HTTPConfig struct {
+	Host              string        `yaml:"host"`
+	Port              int           `yaml:"port"`
+	ReadTimeout       time.Duration `yaml:"read_timeout"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`
+	ShutdownDelay     time.Duration `yaml:"shutdown_delay"`
+	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout"`
+	MaxHeaderBytes    int           `yaml:"max_header_bytes"`
+	MaxBodyBytes      int64         `yaml:"max_body_bytes"`
+	DocsUI            bool          `yaml:"docs_ui"`
+
+	// CacheControl maps route patterns to their Cache-Control header. YAML only.
+	CacheControl map[string]string `yaml:"cache_control"`
+}
+
+// Addr returns the address the HTTP server binds to.
+func (c HTTPConfig) Addr() string {
+	return net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+}
+
+type DatabaseConfig struct {
+	Host            string        `yaml:"host"`
+	Port            int           `yaml:"port"`
+	User            string        `yaml:"user"`
+	Password        string        `yaml:"password"`
+	Name            string        `yaml:"name"`
+	SSLMode         string        `yaml:"sslmode"`
+	SQLDir          string        `yaml:"sql_dir"`
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	QueryTimeout    time.Duration `yaml:"query_timeout"`
+}
+
+type HealthConfig struct {
+	CheckTimeout time.Duration `yaml:"check_timeout"`
+}
+
+type LogConfig struct {
+	Level string `yaml:"level"`
+}
+
+// SlogLevel returns the configured level for log/slog.
+func (c LogConfig) SlogLevel() slog.Level {
+	var level slog.Level
+	level.UnmarshalText([]byte(c.Level))
+	return level
+}
+
+type TracingConfig struct {
+	Exporter    string  `yaml:"exporter"`
+	Endpoint    string  `yaml:"endpoint"`
+	ServiceName string  `yaml:"service_name"`
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+type AuthConfig struct {
+	AnonymousReads bool   `yaml:"anonymous_reads"`
+	JWKSFile       string `yaml:"jwks_file"`
+	JWTIssuer      string `yaml:"jwt_issuer"`
+	JWTAudience    string `yaml:"jwt_audience"`
+
+	// Roles overrides the built-in role to permission grants. YAML only.
+	Roles map[string][]string `yaml:"roles"`
+}
+
+type RateLimitConfig struct {
+	Enabled           bool    `yaml:"enabled"`
+	Rate              float64 `yaml:"rate"`
+	Burst             int     `yaml:"burst"`
+	TrustForwardedFor bool    `yaml:"trust_forwarded_for"`
+
+	// Routes overrides the default limit per route pattern, e.g. "GET /catalog". YAML only.
+	Routes map[string]RouteLimit `yaml:"routes"`
+}
+
+type CacheConfig struct {
+	Enabled    bool          `yaml:"enabled"`
+	MaxEntries int           `yaml:"max_entries"`
+	ListTTL    time.Duration `yaml:"list_ttl"`
+	ItemTTL    time.Duration `yaml:"item_ttl"`
+}
+
+type IdempotencyConfig struct {
+	TTL time.Duration `yaml:"ttl"`
+}
+
+type OutboxConfig struct {
+	Publisher    string        `yaml:"publisher"`
+	File         string        `yaml:"file"`
+	PollInterval time.Duration `yaml:"poll_interval"`
+	BatchSize    int           `yaml:"batch_size"`
+	MaxAttempts  int           `yaml:"max_attempts"`
+	Retention    time.Duration `yaml:"retention"`
+}
+
+type WebhooksConfig struct {
+	Enabled      bool          `yaml:"enabled"`
+	Timeout      time.Duration `yaml:"timeout"`
+	PollInterval time.Duration `yaml:"poll_interval"`
+	BatchSize    int           `yaml:"batch_size"`
+	MaxAttempts  int           `yaml:"max_attempts"`
+	Retention    time.Duration `yaml:"retention"`
+}
+
+// APIConfig announces the deprecation of the unversioned routes, which
+// serve the same responses as /v1.
+type APIConfig struct {
+	UnversionedDeprecatedAt time.Time `yaml:"unversioned_deprecated_at"`
+	UnversionedSunset       time.Time `yaml:"unversioned_sunset"`
+}
+
+type RouteLimit struct {
+	Rate  float64 `yaml:"rate"`
+	Burst int     `yaml:"burst"`
+}
+
+// DSN returns the PostgreSQL connection URL for the configured database.
+func (c DatabaseConfig) DSN() string {
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.User, c.Password),
+		Host:     net.JoinHostPort(c.Host, strconv.Itoa(c.Port)),
+		Path:     c.Name,
+		RawQuery: url.Values{"sslmode": {c.SSLMode}}.Encode(),
+	}
+	return u.String()
+}
+
+// Default returns the configuration used when nothing else is provided.
+func Default() Config {
+	return Config{
+		HTTP: HTTPConfig{
+			Host:              "",
+			Port:              8484,
+			ReadTimeout:       10 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      15 * time.Second,
+			IdleTimeout:       60 * time.Second,
+			ShutdownDelay:     5 * time.Second,
+			ShutdownTimeout:   20 * time.Second,
+			MaxHeaderBytes:    1 << 20,
+			MaxBodyBytes:      1 << 20,
+			CacheControl: map[string]string{
+				"GET /catalog":        "max-age=60",
+				"GET /catalog/{code}": "max-age=300",
+				"GET /categories":     "max-age=300",
+			},
+		},
+		Database: DatabaseConfig{
+			Host:            "localhost",
+			Port:            5432,
+			SSLMode:         "disable",
+			SQLDir:          "./sql",
+			MaxOpenConns:    25,
+			MaxIdleConns:    25,
+			ConnMaxLifetime: 5 * time.Minute,
+			QueryTimeout:    5 * time.Second,
+		},
+		Health: HealthConfig{
+			CheckTimeout: 2 * time.Second,
+		},
+		Log: LogConfig{
+			Level: "info",
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			Endpoint:    "http://localhost:4318",
+			ServiceName: "catalog-api",
+			SampleRatio: 1,
+		},
+		Auth: AuthConfig{
+			AnonymousReads: true,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled: true,
+			Rate:    10,
+			Burst:   20,
+			Routes: map[string]RouteLimit{
+				"GET /catalog":  {Rate: 2, Burst: 10},
+				"POST /graphql": {Rate: 2, Burst: 10},
+			},
+		},
+		Cache: CacheConfig{
+			Enabled:    true,
+			MaxEntries: 1000,
+			ListTTL:    30 * time.Second,
+			ItemTTL:    5 * time.Minute,
+		},
+		Idempotency: IdempotencyConfig{
+			TTL: 24 * time.Hour,
+		},
+		Outbox: OutboxConfig{
+			Publisher:    "stdout",
+			PollInterval: time.Second,
+			BatchSize:    100,
+			MaxAttempts:  10,
+			Retention:    7 * 24 * time.Hour,
+		},
+		Webhooks: WebhooksConfig{
+			Enabled:      true,
+			Timeout:      10 * time.Second,
+			PollInterval: time.Second,
+			BatchSize:    20,
+			MaxAttempts:  10,
+			Retention:    7 * 24 * time.Hour,
+		},
+		API: APIConfig{
+			UnversionedDeprecatedAt: time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC),
+			UnversionedSunset:       time.Date(2027, 4, 30, 0, 0, 0, 0, time.UTC),
+		},
+	}
+}
+
+// setting binds a single configuration value to its environment key and flag name.
+type setting struct {
+	env    string
+	flag   string
+	usage  string
+	target any
+}
+
+func (c *Config) settings() []setting {
+	return []setting{
+		{"HTTP_HOST", "http-host", "interface the HTTP server binds to, empty for all", &c.HTTP.Host},
+		{"HTTP_PORT", "http-port", "port the HTTP server listens on", &c.HTTP.Port},
+		{"HTTP_READ_TIMEOUT", "http-read-timeout", "maximum duration for reading an entire request", &c.HTTP.ReadTimeout},
+		{"HTTP_READ_HEADER_TIMEOUT", "http-read-header-timeout", "maximum duration for reading request headers", &c.HTTP.ReadHeaderTimeout},
+		{"HTTP_WRITE_TIMEOUT", "http-write-timeout", "maximum duration before timing out writes of the response", &c.HTTP.WriteTimeout},
+		{"HTTP_IDLE_TIMEOUT", "http-idle-timeout", "maximum time to wait for the next request on keep-alive connections", &c.HTTP.IdleTimeout},
+		{"HTTP_SHUTDOWN_DELAY", "http-shutdown-delay", "how long readiness reports draining before the server stops accepting connections", &c.HTTP.ShutdownDelay},
+		{"HTTP_SHUTDOWN_TIMEOUT", "http-shutdown-timeout", "maximum time to drain in-flight requests on shutdown", &c.HTTP.ShutdownTimeout},
+		{"HTTP_MAX_HEADER_BYTES", "http-max-header-bytes", "maximum size of request headers", &c.HTTP.MaxHeaderBytes},
+		{"HTTP_MAX_BODY_BYTES", "http-max-body-bytes", "maximum size of request bodies", &c.HTTP.MaxBodyBytes},
+		{"HTTP_DOCS_UI", "http-docs-ui", "serve browsable API documentation at /docs", &c.HTTP.DocsUI},
+		{"POSTGRES_HOST", "db-host", "database host", &c.Database.Host},
+		{"POSTGRES_PORT", "db-port", "database port", &c.Database.Port},
+		{"POSTGRES_USER", "db-user", "database user", &c.Database.User},
+		{"POSTGRES_PASSWORD", "db-password", "database password", &c.Database.Password},
+		{"POSTGRES_DB", "db-name", "database name", &c.Database.Name},
+		{"POSTGRES_SSLMODE", "db-sslmode", "database SSL mode", &c.Database.SSLMode},
+		{"POSTGRES_SQL_DIR", "db-sql-dir", "directory holding the SQL migration files", &c.Database.SQLDir},
+		{"POSTGRES_MAX_OPEN_CONNS", "db-max-open-conns", "maximum number of open database connections", &c.Database.MaxOpenConns},
+		{"POSTGRES_MAX_IDLE_CONNS", "db-max-idle-conns", "maximum number of idle database connections", &c.Database.MaxIdleConns},
+		{"POSTGRES_CONN_MAX_LIFETIME", "db-conn-max-lifetime", "maximum lifetime of a database connection", &c.Database.ConnMaxLifetime},
+		{"POSTGRES_QUERY_TIMEOUT", "db-query-timeout", "deadline applied to the queries of a single request", &c.Database.QueryTimeout},
+		{"HEALTH_CHECK_TIMEOUT", "health-check-timeout", "maximum duration of the readiness checks", &c.Health.CheckTimeout},
+		{"LOG_LEVEL", "log-level", "minimum log level: debug, info, warn or error", &c.Log.Level},
+		{"TRACING_EXPORTER", "tracing-exporter", "trace exporter: none, stdout or otlp", &c.Tracing.Exporter},
+		{"TRACING_OTLP_ENDPOINT", "tracing-otlp-endpoint", "OTLP/HTTP collector URL", &c.Tracing.Endpoint},
+		{"TRACING_SERVICE_NAME", "tracing-service-name", "service name reported on spans", &c.Tracing.ServiceName},
+		{"TRACING_SAMPLE_RATIO", "tracing-sample-ratio", "fraction of new traces to sample, between 0 and 1", &c.Tracing.SampleRatio},
+		{"AUTH_ANONYMOUS_READS", "auth-anonymous-reads", "allow unauthenticated access to read-only routes", &c.Auth.AnonymousReads},
+		{"AUTH_JWKS_FILE", "auth-jwks-file", "JSON Web Key Set used to verify JWTs; empty disables JWT authentication", &c.Auth.JWKSFile},
+		{"AUTH_JWT_ISSUER", "auth-jwt-issuer", "required JWT issuer, if set", &c.Auth.JWTIssuer},
+		{"AUTH_JWT_AUDIENCE", "auth-jwt-audience", "required JWT audience, if set", &c.Auth.JWTAudience},
+		{"CACHE_ENABLED", "cache-enabled", "cache catalog queries in process memory", &c.Cache.Enabled},
+		{"CACHE_MAX_ENTRIES", "cache-max-entries", "maximum number of cached queries per repository", &c.Cache.MaxEntries},
+		{"CACHE_LIST_TTL", "cache-list-ttl", "how long product list queries stay cached", &c.Cache.ListTTL},
+		{"CACHE_ITEM_TTL", "cache-item-ttl", "how long single products and the category list stay cached", &c.Cache.ItemTTL},
+		{"IDEMPOTENCY_TTL", "idempotency-ttl", "how long responses to requests with an Idempotency-Key are replayed", &c.Idempotency.TTL},
+		{"OUTBOX_PUBLISHER", "outbox-publisher", "where catalog events are published: none, stdout or file", &c.Outbox.Publisher},
+		{"OUTBOX_FILE", "outbox-file", "file catalog events are appended to when OUTBOX_PUBLISHER is file", &c.Outbox.File},
+		{"OUTBOX_POLL_INTERVAL", "outbox-poll-interval", "how often the outbox is checked for new events", &c.Outbox.PollInterval},
+		{"OUTBOX_BATCH_SIZE", "outbox-batch-size", "maximum number of events published per poll", &c.Outbox.BatchSize},
+		{"OUTBOX_MAX_ATTEMPTS", "outbox-max-attempts", "attempts before an outbox event is given up as dead", &c.Outbox.MaxAttempts},
+		{"OUTBOX_RETENTION", "outbox-retention", "how long published and dead events are kept in the outbox", &c.Outbox.Retention},
+		{"WEBHOOKS_ENABLED", "webhooks-enabled", "deliver catalog events to webhook subscriptions", &c.Webhooks.Enabled},
+		{"WEBHOOKS_TIMEOUT", "webhooks-timeout", "maximum duration of a single webhook delivery", &c.Webhooks.Timeout},
+		{"WEBHOOKS_POLL_INTERVAL", "webhooks-poll-interval", "how often pending webhook deliveries are checked", &c.Webhooks.PollInterval},
+		{"WEBHOOKS_BATCH_SIZE", "webhooks-batch-size", "maximum number of webhook deliveries sent at once", &c.Webhooks.BatchSize},
+		{"WEBHOOKS_MAX_ATTEMPTS", "webhooks-max-attempts", "attempts before a webhook delivery is given up as dead", &c.Webhooks.MaxAttempts},
+		{"WEBHOOKS_RETENTION", "webhooks-retention", "how long finished webhook deliveries are kept in the delivery log", &c.Webhooks.Retention},
+		{"API_UNVERSIONED_DEPRECATED_AT", "api-unversioned-deprecated-at", "date the routes without a version prefix were deprecated", &c.API.UnversionedDeprecatedAt},
+		{"API_UNVERSIONED_SUNSET", "api-unversioned-sunset", "date the routes without a version prefix stop being served, empty if not planned", &c.API.UnversionedSunset},
+		{"RATELIMIT_ENABLED", "ratelimit-enabled", "limit request rates per client and route", &c.RateLimit.Enabled},
+		{"RATELIMIT_RATE", "ratelimit-rate", "default requests per second refilled per client and route", &c.RateLimit.Rate},
+		{"RATELIMIT_BURST", "ratelimit-burst", "default number of requests a client may send at once per route", &c.RateLimit.Burst},
+		{"RATELIMIT_TRUST_FORWARDED_FOR", "ratelimit-trust-forwarded-for", "identify anonymous clients by X-Forwarded-For; enable only behind a trusted proxy", &c.RateLimit.TrustForwardedFor},
+	}
+}
+
+// Load resolves the configuration from defaults, an optional YAML file,
+// an optional .env file, the environment and the given command-line arguments,
+// and validates the result.
+func Load(args []string) (*Config, error) {
+	cfg := Default()
+	settings := cfg.settings()
+
+	flags := flag.NewFlagSet("config", flag.ContinueOnError)
+	configFile := flags.String("config", os.Getenv("CONFIG_FILE"), "path to an optional YAML configuration file")
+	envFile := flags.String("env-file", envOr("ENV_FILE", ".env"), "path to an optional .env file")
+	flagValues := make(map[string]*string, len(settings))
+	for _, s := range settings {
+		flagValues[s.flag] = flags.String(s.flag, "", s.usage+" (env "+s.env+")")
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *configFile != "" {
+		content, err := os.ReadFile(*configFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading config file: %w", err)
+		}
+		if err := yaml.Unmarshal(content, &cfg); err != nil {
+			return nil, fmt.Errorf("parsing config file %s: %w", *configFile, err)
+		}
+	}
+
+	dotenv, err := godotenv.Read(*envFile)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("reading env file: %w", err)
+	}
+
+	setFlags := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) { setFlags[f.Name] = true })
+
+	var errs []error
+	for _, s := range settings {
+		value, ok := dotenv[s.env]
+		if v, found := os.LookupEnv(s.env); found {
+			value, ok = v, true
+		}
+		source := s.env
+		if setFlags[s.flag] {
+			value, ok, source = *flagValues[s.flag], true, "-"+s.flag
+		}
+		if !ok {
+			continue
+		}
+		if err := assign(s.target, value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", source, err))
+		}
+	}
+
+	// Validate even when values failed to parse, so every problem is
+	// reported at once
+	if err := errors.Join(append(errs, cfg.Validate())...); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// Validate reports every missing or out-of-range setting at once.
+func (c *Config) Validate() error {
+	var errs []error
+
+	required := []struct {
+		key   string
+		value string
+	}{
+		{"POSTGRES_HOST", c.Database.Host},
+		{"POSTGRES_USER", c.Database.User},
+		{"POSTGRES_DB", c.Database.Name},
+	}
+	for _, r := range required {
+		if r.value == "" {
+			errs = append(errs, fmt.Errorf("%s is required", r.key))
+		}
+	}
+
+	errs = append(errs, checkPort("HTTP_PORT", c.HTTP.Port), checkPort("POSTGRES_PORT", c.Database.Port))
+
+	positive := []struct {
+		key   string
+		value time.Duration
+	}{
+		{"HTTP_READ_TIMEOUT", c.HTTP.ReadTimeout},
+		{"HTTP_READ_HEADER_TIMEOUT", c.HTTP.ReadHeaderTimeout},
+		{"HTTP_WRITE_TIMEOUT", c.HTTP.WriteTimeout},
+		{"HTTP_IDLE_TIMEOUT", c.HTTP.IdleTimeout},
+		{"HTTP_SHUTDOWN_TIMEOUT", c.HTTP.ShutdownTimeout},
+		{"POSTGRES_QUERY_TIMEOUT", c.Database.QueryTimeout},
+		{"HEALTH_CHECK_TIMEOUT", c.Health.CheckTimeout},
+		{"IDEMPOTENCY_TTL", c.Idempotency.TTL},
+		{"OUTBOX_POLL_INTERVAL", c.Outbox.PollInterval},
+		{"OUTBOX_RETENTION", c.Outbox.Retention},
+	}
+	for _, p := range positive {
+		if p.value <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be positive, got %s", p.key, p.value))
+		}
+	}
+	if c.HTTP.ShutdownDelay < 0 {
+		errs = append(errs, fmt.Errorf("HTTP_SHUTDOWN_DELAY must not be negative, got %s", c.HTTP.ShutdownDelay))
+	}
+
+	if c.HTTP.MaxHeaderBytes <= 0 {
+		errs = append(errs, fmt.Errorf("HTTP_MAX_HEADER_BYTES must be positive, got %d", c.HTTP.MaxHeaderBytes))
+	}
+	if c.HTTP.MaxBodyBytes <= 0 {
+		errs = append(errs, fmt.Errorf("HTTP_MAX_BODY_BYTES must be positive, got %d", c.HTTP.MaxBodyBytes))
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.Log.Level)); err != nil {
+		errs = append(errs, fmt.Errorf("LOG_LEVEL must be one of debug, info, warn or error, got %q", c.Log.Level))
+	}
+	switch c.Tracing.Exporter {
+	case "none", "stdout":
+	case "otlp":
+		if c.Tracing.Endpoint == "" {
+			errs = append(errs, errors.New("TRACING_OTLP_ENDPOINT is required when TRACING_EXPORTER is otlp"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("TRACING_EXPORTER must be one of none, stdout or otlp, got %q", c.Tracing.Exporter))
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs = append(errs, fmt.Errorf("TRACING_SAMPLE_RATIO must be between 0 and 1, got %g", c.Tracing.SampleRatio))
+	}
+	if c.RateLimit.Rate <= 0 || c.RateLimit.Burst < 1 {
+		errs = append(errs, fmt.Errorf("RATELIMIT_RATE must be positive and RATELIMIT_BURST at least 1, got %g and %d", c.RateLimit.Rate, c.RateLimit.Burst))
+	}
+	for pattern, route := range c.RateLimit.Routes {
+		if route.Rate <= 0 || route.Burst < 1 {
+			errs = append(errs, fmt.Errorf("rate_limit.routes[%q] must have a positive rate and a burst of at least 1", pattern))
+		}
+	}
+	if c.Cache.Enabled && (c.Cache.MaxEntries < 1 || c.Cache.ListTTL <= 0 || c.Cache.ItemTTL <= 0) {
+		errs = append(errs, errors.New("CACHE_MAX_ENTRIES, CACHE_LIST_TTL and CACHE_ITEM_TTL must be positive when the cache is enabled"))
+	}
+	switch c.Outbox.Publisher {
+	case "none", "stdout":
+	case "file":
+		if c.Outbox.File == "" {
+			errs = append(errs, errors.New("OUTBOX_FILE is required when OUTBOX_PUBLISHER is file"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("OUTBOX_PUBLISHER must be one of none, stdout or file, got %q", c.Outbox.Publisher))
+	}
+	if c.Outbox.BatchSize < 1 {
+		errs = append(errs, fmt.Errorf("OUTBOX_BATCH_SIZE must be at least 1, got %d", c.Outbox.BatchSize))
+	}
+	if c.Outbox.MaxAttempts < 1 {
+		errs = append(errs, fmt.Errorf("OUTBOX_MAX_ATTEMPTS must be at least 1, got %d", c.Outbox.MaxAttempts))
+	}
+	if c.Webhooks.Enabled && (c.Webhooks.Timeout <= 0 || c.Webhooks.PollInterval <= 0 || c.Webhooks.BatchSize < 1 || c.Webhooks.MaxAttempts < 1 || c.Webhooks.Retention <= 0) {
+		errs = append(errs, errors.New("WEBHOOKS_TIMEOUT, WEBHOOKS_POLL_INTERVAL, WEBHOOKS_BATCH_SIZE, WEBHOOKS_MAX_ATTEMPTS and WEBHOOKS_RETENTION must be positive when webhooks are enabled"))
+	}
+	if c.API.UnversionedDeprecatedAt.IsZero() {
+		errs = append(errs, errors.New("API_UNVERSIONED_DEPRECATED_AT is required"))
+	}
+	if !c.API.UnversionedSunset.IsZero() && !c.API.UnversionedSunset.After(c.API.UnversionedDeprecatedAt) {
+		errs = append(errs, errors.New("API_UNVERSIONED_SUNSET must be after API_UNVERSIONED_DEPRECATED_AT"))
+	}
+	if c.Database.MaxOpenConns < 0 || c.Database.MaxIdleConns < 0 {
+		errs = append(errs, errors.New("POSTGRES_MAX_OPEN_CONNS and POSTGRES_MAX_IDLE_CONNS must not be negative"))
+	}
+
+	return errors.Join(errs...)
+}
+
+func checkPort(key string, port int) error {
+	if port < 1 || port > 65535 {
+		return fmt.Errorf("%s must be between 1 and 65535, got %d", key, port)
+	}
+	return nil
+}
+
+func assign(target any, value string) error {
+	switch t := target.(type) {
+	case *string:
+		*t = value
+	case *int:
+		v, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		*t = v
+	case *int64:
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		*t = v
+	case *float64:
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		*t = v
+	case *bool:
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		*t = v
+	case *time.Duration:
+		v, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", value)
+		}
+		*t = v
+	case *time.Time:
+		if value == "" {
+			*t = time.Time{}
+			break
+		}
+		v, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			v, err = time.Parse(time.RFC3339, value)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid date %q", value)
+		}
+		*t = v
+	default:
+		return fmt.Errorf("unsupported setting type %T", target)
+	}
+	return nil
+}
+
+func envOr(key, fallback string) string {
+	if v, ok := os.LookupEnv(key); ok {
+		return v
+	}
+	return fallback
+}
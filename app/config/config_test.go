@@ -0,0 +1,170 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+// unsetEnv removes keys from the environment for the duration of the test.
+func unsetEnv(t *testing.T, keys ...string) {
+	t.Helper()
+	for _, key := range keys {
+		t.Setenv(key, "")
+		require.NoError(t, os.Unsetenv(key))
+	}
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("missing .env file is not fatal", func(t *testing.T) {
+		t.Setenv("POSTGRES_USER", "postgres")
+		t.Setenv("POSTGRES_DB", "challenge")
+
+		cfg, err := Load([]string{"-env-file", filepath.Join(t.TempDir(), "missing.env")})
+
+		require.NoError(t, err)
+		assert.Equal(t, ":8484", cfg.HTTP.Addr())
+		assert.Equal(t, "postgres", cfg.Database.User)
+	})
+
+	t.Run("applies precedence yaml < .env < environment < flags", func(t *testing.T) {
+		yamlFile := writeFile(t, "config.yaml", `
+http:
+  port: 1000
+  read_timeout: 3s
+database:
+  user: from-yaml
+  name: from-yaml
+  host: yaml-host
+`)
+		envFile := writeFile(t, ".env", "HTTP_PORT=2000\nPOSTGRES_DB=from-dotenv\nPOSTGRES_HOST=dotenv-host\n")
+		unsetEnv(t, "HTTP_PORT", "POSTGRES_USER", "POSTGRES_DB")
+		t.Setenv("POSTGRES_HOST", "env-host")
+
+		cfg, err := Load([]string{"-config", yamlFile, "-env-file", envFile, "-db-host", "flag-host"})
+
+		require.NoError(t, err)
+		assert.Equal(t, 2000, cfg.HTTP.Port)
+		assert.Equal(t, 3*time.Second, cfg.HTTP.ReadTimeout)
+		assert.Equal(t, "from-yaml", cfg.Database.User)
+		assert.Equal(t, "from-dotenv", cfg.Database.Name)
+		assert.Equal(t, "flag-host", cfg.Database.Host)
+	})
+
+	t.Run("reports every missing key at once", func(t *testing.T) {
+		unsetEnv(t, "POSTGRES_USER", "POSTGRES_DB")
+
+		_, err := Load([]string{"-env-file", filepath.Join(t.TempDir(), "missing.env")})
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "POSTGRES_USER is required")
+		assert.Contains(t, err.Error(), "POSTGRES_DB is required")
+	})
+
+	t.Run("reports malformed values together with missing keys", func(t *testing.T) {
+		unsetEnv(t, "POSTGRES_USER")
+		t.Setenv("POSTGRES_DB", "challenge")
+		t.Setenv("HTTP_WRITE_TIMEOUT", "soon")
+
+		_, err := Load([]string{"-env-file", filepath.Join(t.TempDir(), "missing.env")})
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `HTTP_WRITE_TIMEOUT: invalid duration "soon"`)
+		assert.Contains(t, err.Error(), "POSTGRES_USER is required")
+	})
+
+	t.Run("parses dates and clears them when empty", func(t *testing.T) {
+		t.Setenv("POSTGRES_USER", "postgres")
+		t.Setenv("POSTGRES_DB", "challenge")
+		t.Setenv("API_UNVERSIONED_DEPRECATED_AT", "2026-11-01")
+		t.Setenv("API_UNVERSIONED_SUNSET", "")
+
+		cfg, err := Load([]string{"-env-file", filepath.Join(t.TempDir(), "missing.env")})
+
+		require.NoError(t, err)
+		assert.Equal(t, time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC), cfg.API.UnversionedDeprecatedAt)
+		assert.True(t, cfg.API.UnversionedSunset.IsZero())
+	})
+
+	t.Run("rejects out of range ports and malformed values", func(t *testing.T) {
+		t.Setenv("POSTGRES_USER", "postgres")
+		t.Setenv("POSTGRES_DB", "challenge")
+		t.Setenv("HTTP_WRITE_TIMEOUT", "soon")
+
+		_, err := Load([]string{"-env-file", filepath.Join(t.TempDir(), "missing.env"), "-http-port", "70000"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `HTTP_WRITE_TIMEOUT: invalid duration "soon"`)
+
+		t.Setenv("HTTP_WRITE_TIMEOUT", "5s")
+		_, err = Load([]string{"-env-file", filepath.Join(t.TempDir(), "missing.env"), "-http-port", "70000"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "HTTP_PORT must be between 1 and 65535, got 70000")
+	})
+}
+
+func TestConfig_Validate(t *testing.T) {
+	t.Run("rejects non-positive rate limits per route", func(t *testing.T) {
+		cfg := Default()
+		cfg.Database.User, cfg.Database.Name = "postgres", "challenge"
+		cfg.RateLimit.Routes["GET /categories"] = RouteLimit{Rate: 0, Burst: 5}
+
+		err := cfg.Validate()
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `rate_limit.routes["GET /categories"] must have a positive rate`)
+	})
+
+	t.Run("requires a file for the file outbox publisher", func(t *testing.T) {
+		cfg := Default()
+		cfg.Database.User, cfg.Database.Name = "postgres", "challenge"
+		cfg.Outbox.Publisher = "file"
+
+		err := cfg.Validate()
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "OUTBOX_FILE is required when OUTBOX_PUBLISHER is file")
+	})
+
+	t.Run("checks webhook settings only when webhooks are enabled", func(t *testing.T) {
+		cfg := Default()
+		cfg.Database.User, cfg.Database.Name = "postgres", "challenge"
+		cfg.Webhooks.MaxAttempts = 0
+
+		err := cfg.Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "WEBHOOKS_MAX_ATTEMPTS")
+
+		cfg.Webhooks.Enabled = false
+		assert.NoError(t, cfg.Validate())
+	})
+
+	t.Run("requires the sunset of unversioned routes to follow their deprecation", func(t *testing.T) {
+		cfg := Default()
+		cfg.Database.User, cfg.Database.Name = "postgres", "challenge"
+		cfg.API.UnversionedSunset = cfg.API.UnversionedDeprecatedAt
+
+		err := cfg.Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "API_UNVERSIONED_SUNSET must be after API_UNVERSIONED_DEPRECATED_AT")
+
+		cfg.API.UnversionedSunset = time.Time{}
+		assert.NoError(t, cfg.Validate())
+	})
+}
+
+func TestDatabaseConfig_DSN(t *testing.T) {
+	cfg := DatabaseConfig{Host: "db", Port: 5432, User: "user", Password: "p@ss", Name: "challenge", SSLMode: "disable"}
+
+	assert.Equal(t, "postgres://user:p%40ss@db:5432/challenge?sslmode=disable", cfg.DSN())
+}
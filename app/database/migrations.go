@@ -0,0 +1,69 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Migrations returns the names of the .sql files in dir in the order they must be applied.
+func Migrations(dir string) ([]string, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, file := range files {
+		if !file.IsDir() && strings.HasSuffix(file.Name(), ".sql") {
+			names = append(names, file.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// LatestMigration returns the version of the last migration found in dir.
+func LatestMigration(dir string) (string, error) {
+	names, err := Migrations(dir)
+	if err != nil {
+		return "", err
+	}
+	if len(names) == 0 {
+		return "", errors.New("no migrations found in " + dir)
+	}
+	return Version(names[len(names)-1]), nil
+}
+
+// Version derives a migration version from its file name, e.g. "004-categories.sql" -> "004-categories".
+func Version(name string) string {
+	return strings.TrimSuffix(name, ".sql")
+}
+
+// RecordMigration stores version as applied. The bookkeeping table is created
+// on demand because the first migration drops every table in the schema.
+func RecordMigration(db *gorm.DB, version string) error {
+	if err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version VARCHAR(255) PRIMARY KEY,
+		applied_at TIMESTAMP NOT NULL DEFAULT NOW()
+	)`).Error; err != nil {
+		return err
+	}
+	return db.Exec("INSERT INTO schema_migrations (version) VALUES (?) ON CONFLICT (version) DO NOTHING", version).Error
+}
+
+// CurrentVersion returns the most recent migration applied to the database.
+func CurrentVersion(ctx context.Context, db *gorm.DB) (string, error) {
+	var version *string
+	if err := db.WithContext(ctx).Raw("SELECT MAX(version) FROM schema_migrations").Scan(&version).Error; err != nil {
+		return "", err
+	}
+	if version == nil {
+		return "", nil
+	}
+	return *version, nil
+}
@@ -0,0 +1,33 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatestMigration(t *testing.T) {
+	t.Run("returns the highest sql file version", func(t *testing.T) {
+		dir := t.TempDir()
+		for _, name := range []string{"002-variants.sql", "000-truncate.sql", "010-later.sql", "README.md"} {
+			require.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0o600))
+		}
+		require.NoError(t, os.Mkdir(filepath.Join(dir, "999-dir.sql"), 0o700))
+
+		names, err := Migrations(dir)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"000-truncate.sql", "002-variants.sql", "010-later.sql"}, names)
+
+		version, err := LatestMigration(dir)
+		require.NoError(t, err)
+		assert.Equal(t, "010-later", version)
+	})
+
+	t.Run("fails when there are no migrations", func(t *testing.T) {
+		_, err := LatestMigration(t.TempDir())
+		assert.Error(t, err)
+	})
+}
@@ -1,26 +1,31 @@
 package database
 
 import (
-	"fmt"
-	"log"
+	"log/slog"
+	"os"
 
 	_ "github.com/lib/pq"
+	"github.com/mytheresa/go-hiring-challenge/app/config"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
-func New(user, password, dbname, port string) (db *gorm.DB, close func() error) {
-	dsn := fmt.Sprintf("postgres://%s:%s@localhost:%s/%s?sslmode=disable", user, password, port, dbname)
-
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+func New(cfg config.DatabaseConfig) (db *gorm.DB, close func() error) {
+	db, err := gorm.Open(postgres.Open(cfg.DSN()), &gorm.Config{})
 	if err != nil {
-		log.Fatalf("failed to connect database: %s", err)
+		slog.Error("failed to connect database", "error", err)
+		os.Exit(1)
 	}
 
 	sqlDB, err := db.DB()
 	if err != nil {
-		log.Fatalf("Failed to get database connection: %s", err)
+		slog.Error("failed to get database connection", "error", err)
+		os.Exit(1)
 	}
 
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+
 	return db, sqlDB.Close
 }
@@ -0,0 +1,67 @@
+package graphql
+
+import (
+	"errors"
+
+	"github.com/mytheresa/go-hiring-challenge/app/api"
+	"github.com/mytheresa/go-hiring-challenge/app/auth"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+// queryError is an error returned by a resolver. Its message is safe to show
+// to clients, and its extensions carry the code the REST API would put in
+// the problem response, so clients can branch on the same codes.
+type queryError struct {
+	message    string
+	extensions map[string]any
+	// err is the cause, logged for internal errors whose message is hidden.
+	err error
+}
+
+func (e *queryError) Error() string {
+	return e.message
+}
+
+func (e *queryError) Extensions() map[string]any {
+	return e.extensions
+}
+
+func (e *queryError) Unwrap() error {
+	return e.err
+}
+
+func (e *queryError) code() string {
+	code, _ := e.extensions["code"].(string)
+	return code
+}
+
+// resolverError wraps err for the response. Validation, authorization and
+// domain errors keep their message; anything else is reported with fallback.
+func resolverError(err error, fallback string) error {
+	var validationErr *api.ValidationError
+	var forbidden *auth.ForbiddenError
+	switch {
+	case errors.As(err, &validationErr):
+		return &queryError{
+			message:    "input has invalid fields",
+			extensions: map[string]any{"code": api.CodeValidationFailed, "errors": validationErr.Errors},
+			err:        err,
+		}
+	case errors.Is(err, auth.ErrUnauthenticated):
+		return &queryError{message: err.Error(), extensions: map[string]any{"code": api.CodeUnauthenticated}, err: err}
+	case errors.As(err, &forbidden):
+		return &queryError{
+			message:    forbidden.Error(),
+			extensions: map[string]any{"code": api.CodeForbidden, "missing_permission": forbidden.Missing},
+			err:        err,
+		}
+	}
+
+	code := api.CodeFromError(err)
+	message := fallback
+	var domainErr *models.Error
+	if errors.As(err, &domainErr) && code != api.CodeInternal {
+		message = domainErr.Message
+	}
+	return &queryError{message: message, extensions: map[string]any{"code": code}, err: err}
+}
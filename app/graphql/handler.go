@@ -0,0 +1,126 @@
+package graphql
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	gql "github.com/graph-gophers/graphql-go"
+	"github.com/mytheresa/go-hiring-challenge/app/api"
+	"github.com/mytheresa/go-hiring-challenge/app/auth"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+//go:embed schema.graphql
+var schema string
+
+// maxDepth bounds how deeply queries nest. The schema itself is shallow; the
+// bound leaves room for the introspection query tools send.
+const maxDepth = 15
+
+// Request is a GraphQL query sent as JSON in a POST body.
+type Request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+	Extensions    map[string]any `json:"extensions,omitempty"`
+}
+
+type Handler struct {
+	schema     *gql.Schema
+	products   models.ProductRepository
+	categories models.CategoryRepository
+	logger     *slog.Logger
+}
+
+// NewHandler creates a handler resolving queries against the repositories.
+// Queries need no more than the route's permission; each mutation checks the
+// permission its REST counterpart requires against policy.
+func NewHandler(products models.ProductRepository, categories models.CategoryRepository, policy *auth.Policy, logger *slog.Logger) (*Handler, error) {
+	root := &resolver{products: products, categories: categories, policy: policy}
+	s, err := gql.ParseSchema(schema, root, gql.UseStringDescriptions(), gql.MaxDepth(maxDepth))
+	if err != nil {
+		return nil, err
+	}
+	return &Handler{schema: s, products: products, categories: categories, logger: logger}, nil
+}
+
+// HandleQuery executes a query or mutation. Errors while resolving fields
+// are reported in the response's errors with a 200, as GraphQL clients
+// expect; only requests that are not GraphQL at all are rejected with a
+// problem response.
+func (h *Handler) HandleQuery(w http.ResponseWriter, r *http.Request) {
+	var req Request
+	if err := api.DecodeJSON(r, &req); err != nil {
+		api.HandleError(w, r, err, "invalid request body")
+		return
+	}
+
+	var v api.Validator
+	v.Field("query", req.Query, api.Required())
+	if err := v.Err(); err != nil {
+		api.HandleError(w, r, err, "invalid request body")
+		return
+	}
+
+	ctx := withLoaders(r.Context(), newLoaders(h.products, h.categories))
+	response := h.schema.Exec(ctx, req.Query, req.OperationName, req.Variables)
+
+	for _, queryErr := range response.Errors {
+		var resolverErr *queryError
+		if errors.As(queryErr, &resolverErr) && resolverErr.code() == api.CodeInternal {
+			h.logger.ErrorContext(ctx, "graphql field failed",
+				"path", queryErr.Path,
+				"request_id", api.RequestIDFromRequest(r),
+				"error", resolverErr.err,
+			)
+		}
+	}
+
+	api.OKResponse(w, response)
+}
+
+// loaders batch the lookups of one request.
+type loaders struct {
+	categories *loader[uint, *models.Category]
+	variants   *loader[uint, []models.Variant]
+}
+
+func newLoaders(products models.ProductRepository, categories models.CategoryRepository) *loaders {
+	return &loaders{
+		categories: newLoader(func(ctx context.Context, ids []uint) (map[uint]*models.Category, error) {
+			list, err := categories.GetByIDs(ctx, ids)
+			if err != nil {
+				return nil, err
+			}
+			byID := make(map[uint]*models.Category, len(list))
+			for i := range list {
+				byID[list[i].ID] = &list[i]
+			}
+			return byID, nil
+		}),
+		variants: newLoader(func(ctx context.Context, productIDs []uint) (map[uint][]models.Variant, error) {
+			list, err := products.GetVariantsByProductIDs(ctx, productIDs)
+			if err != nil {
+				return nil, err
+			}
+			byProduct := make(map[uint][]models.Variant, len(productIDs))
+			for _, v := range list {
+				byProduct[v.ProductID] = append(byProduct[v.ProductID], v)
+			}
+			return byProduct, nil
+		}),
+	}
+}
+
+type loadersKey struct{}
+
+func withLoaders(ctx context.Context, l *loaders) context.Context {
+	return context.WithValue(ctx, loadersKey{}, l)
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
@@ -0,0 +1,433 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mytheresa/go-hiring-challenge/app/auth"
+	"github.com/mytheresa/go-hiring-challenge/models"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockProductRepository struct {
+	mock.Mock
+}
+
+func (m *MockProductRepository) GetAll(ctx context.Context, offset, limit int, categoryCode string, priceLessThan *decimal.Decimal, includeDeleted bool) ([]models.Product, int64, error) {
+	args := m.Called(ctx, offset, limit, categoryCode, priceLessThan, includeDeleted)
+	return args.Get(0).([]models.Product), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockProductRepository) GetByCode(ctx context.Context, code string, includeDeleted bool) (*models.Product, error) {
+	args := m.Called(ctx, code, includeDeleted)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Product), args.Error(1)
+}
+
+func (m *MockProductRepository) Find(ctx context.Context, filter models.ProductFilter, offset, limit int) ([]models.Product, int64, error) {
+	args := m.Called(ctx, filter, offset, limit)
+	return args.Get(0).([]models.Product), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockProductRepository) GetVariantsByProductIDs(ctx context.Context, productIDs []uint) ([]models.Variant, error) {
+	args := m.Called(ctx, productIDs)
+	return args.Get(0).([]models.Variant), args.Error(1)
+}
+
+func (m *MockProductRepository) Update(ctx context.Context, code string, version uint, changes models.ProductChanges) (*models.Product, error) {
+	args := m.Called(ctx, code, version, changes)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Product), args.Error(1)
+}
+
+func (m *MockProductRepository) Delete(ctx context.Context, code string, version uint) error {
+	args := m.Called(ctx, code, version)
+	return args.Error(0)
+}
+
+func (m *MockProductRepository) Restore(ctx context.Context, code string) (*models.Product, error) {
+	args := m.Called(ctx, code)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Product), args.Error(1)
+}
+
+func (m *MockProductRepository) GetVariant(ctx context.Context, code, sku string, includeDeleted bool) (*models.Variant, error) {
+	args := m.Called(ctx, code, sku, includeDeleted)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Variant), args.Error(1)
+}
+
+func (m *MockProductRepository) UpdateVariant(ctx context.Context, code, sku string, version uint, changes models.VariantChanges) (*models.Variant, error) {
+	args := m.Called(ctx, code, sku, version, changes)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Variant), args.Error(1)
+}
+
+func (m *MockProductRepository) DeleteVariant(ctx context.Context, code, sku string, version uint) error {
+	args := m.Called(ctx, code, sku, version)
+	return args.Error(0)
+}
+
+func (m *MockProductRepository) RestoreVariant(ctx context.Context, code, sku string) (*models.Variant, error) {
+	args := m.Called(ctx, code, sku)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Variant), args.Error(1)
+}
+
+type MockCategoryRepository struct {
+	mock.Mock
+}
+
+func (m *MockCategoryRepository) GetAll(ctx context.Context, includeDeleted bool) ([]models.Category, error) {
+	args := m.Called(ctx, includeDeleted)
+	return args.Get(0).([]models.Category), args.Error(1)
+}
+
+func (m *MockCategoryRepository) Create(ctx context.Context, category *models.Category) error {
+	args := m.Called(ctx, category)
+	return args.Error(0)
+}
+
+func (m *MockCategoryRepository) GetByCode(ctx context.Context, code string, includeDeleted bool) (*models.Category, error) {
+	args := m.Called(ctx, code, includeDeleted)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Category), args.Error(1)
+}
+
+func (m *MockCategoryRepository) GetByIDs(ctx context.Context, ids []uint) ([]models.Category, error) {
+	args := m.Called(ctx, ids)
+	return args.Get(0).([]models.Category), args.Error(1)
+}
+
+func (m *MockCategoryRepository) Update(ctx context.Context, code string, version uint, changes models.CategoryChanges) (*models.Category, error) {
+	args := m.Called(ctx, code, version, changes)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Category), args.Error(1)
+}
+
+func (m *MockCategoryRepository) Delete(ctx context.Context, code string, version uint) error {
+	args := m.Called(ctx, code, version)
+	return args.Error(0)
+}
+
+func (m *MockCategoryRepository) Restore(ctx context.Context, code string) (*models.Category, error) {
+	args := m.Called(ctx, code)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Category), args.Error(1)
+}
+
+var (
+	shoesID = uint(7)
+	shoes   = models.Category{ID: shoesID, Code: "shoes", Name: "Shoes", Version: 2}
+)
+
+func testProducts() []models.Product {
+	return []models.Product{
+		{ID: 1, Code: "PROD001", Price: decimal.RequireFromString("10.99"), CategoryID: &shoesID},
+		{ID: 2, Code: "PROD002", Price: decimal.RequireFromString("12.5"), CategoryID: &shoesID},
+		{ID: 3, Code: "PROD003", Price: decimal.RequireFromString("5")},
+	}
+}
+
+func newTestHandler(t *testing.T, products models.ProductRepository, categories models.CategoryRepository) *Handler {
+	t.Helper()
+	handler, err := NewHandler(products, categories, auth.NewPolicy(auth.DefaultRoles, auth.PermCatalogRead), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+	return handler
+}
+
+func execute(t *testing.T, handler *Handler, principal *auth.Principal, query string, variables map[string]any) *httptest.ResponseRecorder {
+	t.Helper()
+	body, err := json.Marshal(Request{Query: query, Variables: variables})
+	require.NoError(t, err)
+	req := httptest.NewRequest("POST", "/graphql", bytes.NewReader(body))
+	if principal != nil {
+		req = req.WithContext(auth.WithPrincipal(req.Context(), principal))
+	}
+	recorder := httptest.NewRecorder()
+	handler.HandleQuery(recorder, req)
+	return recorder
+}
+
+func TestHandler_Products(t *testing.T) {
+	t.Run("loads the categories and variants of a page in one query each", func(t *testing.T) {
+		mockProducts := new(MockProductRepository)
+		mockCategories := new(MockCategoryRepository)
+		handler := newTestHandler(t, mockProducts, mockCategories)
+		mockProducts.On("Find", mock.Anything, models.ProductFilter{CategoryCode: "shoes"}, 0, 3).Return(testProducts(), int64(5), nil)
+		mockProducts.On("GetVariantsByProductIDs", mock.Anything, []uint{1, 2, 3}).Return([]models.Variant{
+			{ProductID: 1, SKU: "SKU001A", Name: "Red"},
+			{ProductID: 1, SKU: "SKU001B", Name: "Blue", Price: decimal.RequireFromString("11.5")},
+			{ProductID: 2, SKU: "SKU002A", Name: "Green"},
+		}, nil).Once()
+		mockCategories.On("GetByIDs", mock.Anything, []uint{shoesID}).Return([]models.Category{shoes}, nil).Once()
+
+		recorder := execute(t, handler, nil, `{
+			products(filter: {category: "shoes"}, first: 3) {
+				totalCount
+				pageInfo { hasNextPage endCursor }
+				edges { cursor node { code price category { code name } variants { sku name price } } }
+			}
+		}`, nil)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.JSONEq(t, `{"data": {"products": {
+			"totalCount": 5,
+			"pageInfo": {"hasNextPage": true, "endCursor": "`+encodeCursor(2)+`"},
+			"edges": [
+				{"cursor": "`+encodeCursor(0)+`", "node": {"code": "PROD001", "price": "10.99", "category": {"code": "shoes", "name": "Shoes"}, "variants": [
+					{"sku": "SKU001A", "name": "Red", "price": "10.99"},
+					{"sku": "SKU001B", "name": "Blue", "price": "11.50"}
+				]}},
+				{"cursor": "`+encodeCursor(1)+`", "node": {"code": "PROD002", "price": "12.50", "category": {"code": "shoes", "name": "Shoes"}, "variants": [
+					{"sku": "SKU002A", "name": "Green", "price": "12.50"}
+				]}},
+				{"cursor": "`+encodeCursor(2)+`", "node": {"code": "PROD003", "price": "5.00", "category": null, "variants": []}}
+			]
+		}}}`, recorder.Body.String())
+		mockProducts.AssertExpectations(t)
+		mockCategories.AssertExpectations(t)
+	})
+
+	t.Run("skips the lookups of fields that are not selected", func(t *testing.T) {
+		mockProducts := new(MockProductRepository)
+		mockCategories := new(MockCategoryRepository)
+		handler := newTestHandler(t, mockProducts, mockCategories)
+		mockProducts.On("Find", mock.Anything, models.ProductFilter{}, 0, 10).Return(testProducts(), int64(3), nil)
+
+		recorder := execute(t, handler, nil, `{ products { edges { node { code } } } }`, nil)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.JSONEq(t, `{"data": {"products": {"edges": [
+			{"node": {"code": "PROD001"}}, {"node": {"code": "PROD002"}}, {"node": {"code": "PROD003"}}
+		]}}}`, recorder.Body.String())
+		mockProducts.AssertNotCalled(t, "GetVariantsByProductIDs", mock.Anything, mock.Anything)
+		mockCategories.AssertNotCalled(t, "GetByIDs", mock.Anything, mock.Anything)
+	})
+
+	t.Run("continues after the cursor with the price filter", func(t *testing.T) {
+		mockProducts := new(MockProductRepository)
+		handler := newTestHandler(t, mockProducts, new(MockCategoryRepository))
+		price := decimal.RequireFromString("20")
+		mockProducts.On("Find", mock.Anything, models.ProductFilter{PriceLessThan: &price}, 3, 2).Return([]models.Product{}, int64(3), nil)
+
+		recorder := execute(t, handler, nil, `query($after: String) {
+			products(filter: {priceLessThan: "20"}, first: 2, after: $after) { totalCount pageInfo { hasNextPage endCursor } edges { cursor } }
+		}`, map[string]any{"after": encodeCursor(2)})
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.JSONEq(t, `{"data": {"products": {"totalCount": 3, "pageInfo": {"hasNextPage": false, "endCursor": null}, "edges": []}}}`, recorder.Body.String())
+	})
+
+	t.Run("rejects invalid arguments with the validation errors", func(t *testing.T) {
+		mockProducts := new(MockProductRepository)
+		handler := newTestHandler(t, mockProducts, new(MockCategoryRepository))
+
+		recorder := execute(t, handler, nil, `{ products(first: 500, after: "bogus", filter: {priceLessThan: -1}) { totalCount } }`, nil)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		var response struct {
+			Data   map[string]any
+			Errors []struct {
+				Message    string
+				Extensions struct {
+					Code   string
+					Errors []struct{ Field string }
+				}
+			}
+		}
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		require.Len(t, response.Errors, 1)
+		assert.Nil(t, response.Data)
+		assert.Equal(t, "input has invalid fields", response.Errors[0].Message)
+		assert.Equal(t, "validation_failed", response.Errors[0].Extensions.Code)
+		var fields []string
+		for _, fe := range response.Errors[0].Extensions.Errors {
+			fields = append(fields, fe.Field)
+		}
+		assert.Equal(t, []string{"first", "after", "filter.priceLessThan"}, fields)
+		mockProducts.AssertNotCalled(t, "Find", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("hides the cause of internal errors", func(t *testing.T) {
+		mockProducts := new(MockProductRepository)
+		handler := newTestHandler(t, mockProducts, new(MockCategoryRepository))
+		mockProducts.On("Find", mock.Anything, models.ProductFilter{}, 0, 10).Return([]models.Product(nil), int64(0), errors.New("pq: connection reset"))
+
+		recorder := execute(t, handler, nil, `{ products { totalCount } }`, nil)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"message":"failed to fetch products"`)
+		assert.Contains(t, recorder.Body.String(), `"code":"internal_error"`)
+		assert.NotContains(t, recorder.Body.String(), "pq:")
+	})
+}
+
+func TestHandler_Product(t *testing.T) {
+	t.Run("serves the category and variants loaded with the product", func(t *testing.T) {
+		mockProducts := new(MockProductRepository)
+		mockCategories := new(MockCategoryRepository)
+		handler := newTestHandler(t, mockProducts, mockCategories)
+		product := testProducts()[0]
+		product.Category = &shoes
+		product.Variants = []models.Variant{{ProductID: 1, SKU: "SKU001A", Name: "Red"}}
+		mockProducts.On("GetByCode", mock.Anything, "PROD001", false).Return(&product, nil)
+
+		recorder := execute(t, handler, nil, `{ product(code: "PROD001") { code category { code version } variants { sku price } } }`, nil)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.JSONEq(t, `{"data": {"product": {
+			"code": "PROD001",
+			"category": {"code": "shoes", "version": 2},
+			"variants": [{"sku": "SKU001A", "price": "10.99"}]
+		}}}`, recorder.Body.String())
+		mockProducts.AssertNotCalled(t, "GetVariantsByProductIDs", mock.Anything, mock.Anything)
+		mockCategories.AssertNotCalled(t, "GetByIDs", mock.Anything, mock.Anything)
+	})
+
+	t.Run("returns null for unknown products", func(t *testing.T) {
+		mockProducts := new(MockProductRepository)
+		handler := newTestHandler(t, mockProducts, new(MockCategoryRepository))
+		mockProducts.On("GetByCode", mock.Anything, "NOPE", false).Return(nil, &models.Error{Kind: models.ErrNotFound, Message: "product not found"})
+
+		recorder := execute(t, handler, nil, `{ product(code: "NOPE") { code } }`, nil)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.JSONEq(t, `{"data": {"product": null}}`, recorder.Body.String())
+	})
+}
+
+func TestHandler_Categories(t *testing.T) {
+	mockCategories := new(MockCategoryRepository)
+	handler := newTestHandler(t, new(MockProductRepository), mockCategories)
+	mockCategories.On("GetAll", mock.Anything, false).Return([]models.Category{shoes, {Code: "bags", Name: "Bags", Version: 1}}, nil)
+
+	recorder := execute(t, handler, nil, `{ categories { code name } }`, nil)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"data": {"categories": [{"code": "shoes", "name": "Shoes"}, {"code": "bags", "name": "Bags"}]}}`, recorder.Body.String())
+}
+
+func TestHandler_CategoryMutations(t *testing.T) {
+	merchandiser := &auth.Principal{Subject: "alice", Roles: []string{"merchandiser"}}
+	viewer := &auth.Principal{Subject: "bob", Roles: []string{"viewer"}}
+
+	t.Run("creates a category", func(t *testing.T) {
+		mockCategories := new(MockCategoryRepository)
+		handler := newTestHandler(t, new(MockProductRepository), mockCategories)
+		mockCategories.On("Create", mock.Anything, &models.Category{Code: "hats", Name: "Hats"}).
+			Run(func(args mock.Arguments) { args.Get(1).(*models.Category).Version = 1 }).
+			Return(nil)
+
+		recorder := execute(t, handler, merchandiser, `mutation { createCategory(input: {code: "hats", name: "Hats"}) { code name version } }`, nil)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.JSONEq(t, `{"data": {"createCategory": {"code": "hats", "name": "Hats", "version": 1}}}`, recorder.Body.String())
+	})
+
+	t.Run("validates the input like the REST API", func(t *testing.T) {
+		mockCategories := new(MockCategoryRepository)
+		handler := newTestHandler(t, new(MockProductRepository), mockCategories)
+
+		recorder := execute(t, handler, merchandiser, `mutation { createCategory(input: {code: "Not A Slug", name: ""}) { code } }`, nil)
+
+		assert.Contains(t, recorder.Body.String(), `"code":"validation_failed"`)
+		assert.Contains(t, recorder.Body.String(), `"field":"code"`)
+		assert.Contains(t, recorder.Body.String(), `"field":"name"`)
+		mockCategories.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("updates and deletes at a version", func(t *testing.T) {
+		mockCategories := new(MockCategoryRepository)
+		handler := newTestHandler(t, new(MockProductRepository), mockCategories)
+		name := "Footwear"
+		mockCategories.On("Update", mock.Anything, "shoes", uint(2), models.CategoryChanges{Name: &name}).
+			Return(&models.Category{Code: "shoes", Name: name, Version: 3}, nil)
+		mockCategories.On("Delete", mock.Anything, "shoes", uint(3)).Return(nil)
+
+		recorder := execute(t, handler, merchandiser, `mutation {
+			updateCategory(code: "shoes", version: 2, input: {name: "Footwear"}) { name version }
+			deleteCategory(code: "shoes", version: 3)
+		}`, nil)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.JSONEq(t, `{"data": {"updateCategory": {"name": "Footwear", "version": 3}, "deleteCategory": true}}`, recorder.Body.String())
+	})
+
+	t.Run("reports stale versions with their code", func(t *testing.T) {
+		mockCategories := new(MockCategoryRepository)
+		handler := newTestHandler(t, new(MockProductRepository), mockCategories)
+		mockCategories.On("Delete", mock.Anything, "shoes", uint(1)).
+			Return(&models.Error{Kind: models.ErrPreconditionFailed, Message: "category has been modified since it was read"})
+
+		recorder := execute(t, handler, merchandiser, `mutation { deleteCategory(code: "shoes", version: 1) }`, nil)
+
+		assert.Contains(t, recorder.Body.String(), `"message":"category has been modified since it was read"`)
+		assert.Contains(t, recorder.Body.String(), `"code":"precondition_failed"`)
+	})
+
+	t.Run("requires the permissions of the REST routes", func(t *testing.T) {
+		tests := []struct {
+			name      string
+			principal *auth.Principal
+			mutation  string
+			expected  string
+		}{
+			{"anonymous", nil, `mutation { createCategory(input: {code: "hats", name: "Hats"}) { code } }`, `"code":"unauthenticated"`},
+			{"viewer", viewer, `mutation { deleteCategory(code: "shoes", version: 1) }`, `"missing_permission":"categories:write"`},
+			{"restore", merchandiser, `mutation { restoreCategory(code: "shoes") { code } }`, `"missing_permission":"catalog:admin"`},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				mockCategories := new(MockCategoryRepository)
+				handler := newTestHandler(t, new(MockProductRepository), mockCategories)
+
+				recorder := execute(t, handler, tt.principal, tt.mutation, nil)
+
+				assert.Equal(t, http.StatusOK, recorder.Code)
+				assert.Contains(t, recorder.Body.String(), tt.expected)
+				assert.Empty(t, mockCategories.Calls)
+			})
+		}
+	})
+}
+
+func TestHandler_HandleQuery(t *testing.T) {
+	handler := newTestHandler(t, new(MockProductRepository), new(MockCategoryRepository))
+
+	for _, body := range []string{`not json`, `{}`, `{"query": "{ categories { code } }", "unknown": 1}`} {
+		recorder := httptest.NewRecorder()
+		handler.HandleQuery(recorder, httptest.NewRequest("POST", "/graphql", strings.NewReader(body)))
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code, body)
+	}
+}
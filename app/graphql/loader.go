@@ -0,0 +1,102 @@
+package graphql
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// loader batches lookups by key within one request. Resolvers queue the keys
+// they are about to need, e.g. every product on a page, and the first Load
+// of any queued key fetches them all in one call; later loads are served
+// from the results. A loader is not shared between requests, so its results
+// never go stale.
+type loader[K comparable, V any] struct {
+	fetch func(ctx context.Context, keys []K) (map[K]V, error)
+
+	mu      sync.Mutex
+	pending []K
+	results map[K]*result[V]
+}
+
+type result[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+// newLoader creates a loader that fetches batches with fetch. Keys fetch
+// leaves out of its map load as the zero value.
+func newLoader[K comparable, V any](fetch func(ctx context.Context, keys []K) (map[K]V, error)) *loader[K, V] {
+	return &loader[K, V]{fetch: fetch, results: make(map[K]*result[V])}
+}
+
+// Queue adds keys to the next batch, skipping those already known.
+func (l *loader[K, V]) Queue(keys ...K) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, key := range keys {
+		l.queue(key)
+	}
+}
+
+func (l *loader[K, V]) queue(key K) *result[V] {
+	if res, ok := l.results[key]; ok {
+		return res
+	}
+	res := &result[V]{done: make(chan struct{})}
+	l.results[key] = res
+	l.pending = append(l.pending, key)
+	return res
+}
+
+// Prime records value for key, e.g. from a query that already returned it.
+// A key that is already known keeps its value.
+func (l *loader[K, V]) Prime(key K, value V) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.results[key]; ok {
+		return
+	}
+	res := &result[V]{done: make(chan struct{}), value: value}
+	close(res.done)
+	l.results[key] = res
+}
+
+// Load returns the value for key, fetching it together with every queued
+// key if it has not been fetched yet. Concurrent loads of keys in the same
+// batch wait for the one fetch.
+func (l *loader[K, V]) Load(ctx context.Context, key K) (V, error) {
+	l.mu.Lock()
+	res := l.queue(key)
+	var batch []K
+	if slices.Contains(l.pending, key) {
+		batch, l.pending = l.pending, nil
+	}
+	l.mu.Unlock()
+
+	if batch != nil {
+		l.dispatch(ctx, batch)
+	}
+
+	select {
+	case <-res.done:
+		return res.value, res.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// dispatch fetches batch and completes the results waiting on it.
+func (l *loader[K, V]) dispatch(ctx context.Context, batch []K) {
+	values, err := l.fetch(ctx, batch)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, key := range batch {
+		res := l.results[key]
+		res.value, res.err = values[key], err
+		close(res.done)
+	}
+}
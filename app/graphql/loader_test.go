@@ -0,0 +1,104 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingFetch returns each key doubled and records the batches it was called with.
+type recordingFetch struct {
+	mu      sync.Mutex
+	batches [][]int
+	err     error
+}
+
+func (f *recordingFetch) fetch(_ context.Context, keys []int) (map[int]int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.batches = append(f.batches, keys)
+	if f.err != nil {
+		return nil, f.err
+	}
+	values := make(map[int]int, len(keys))
+	for _, k := range keys {
+		if k != 0 {
+			values[k] = k * 2
+		}
+	}
+	return values, nil
+}
+
+func TestLoader(t *testing.T) {
+	t.Run("fetches every queued key on the first load", func(t *testing.T) {
+		f := &recordingFetch{}
+		l := newLoader(f.fetch)
+		l.Queue(1, 2, 3)
+
+		var wg sync.WaitGroup
+		values := make([]int, 3)
+		for i := range values {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				values[i], _ = l.Load(context.Background(), i+1)
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, []int{2, 4, 6}, values)
+		assert.Equal(t, [][]int{{1, 2, 3}}, f.batches)
+	})
+
+	t.Run("serves later loads from the results", func(t *testing.T) {
+		f := &recordingFetch{}
+		l := newLoader(f.fetch)
+
+		first, err := l.Load(context.Background(), 4)
+		require.NoError(t, err)
+		again, err := l.Load(context.Background(), 4)
+		require.NoError(t, err)
+
+		assert.Equal(t, 8, first)
+		assert.Equal(t, 8, again)
+		assert.Equal(t, [][]int{{4}}, f.batches)
+	})
+
+	t.Run("loads keys the fetch left out as the zero value", func(t *testing.T) {
+		l := newLoader((&recordingFetch{}).fetch)
+
+		value, err := l.Load(context.Background(), 0)
+
+		require.NoError(t, err)
+		assert.Zero(t, value)
+	})
+
+	t.Run("does not fetch primed keys", func(t *testing.T) {
+		f := &recordingFetch{}
+		l := newLoader(f.fetch)
+		l.Prime(5, 50)
+		l.Queue(5, 6)
+
+		primed, err := l.Load(context.Background(), 5)
+		require.NoError(t, err)
+
+		assert.Equal(t, 50, primed)
+		assert.Empty(t, f.batches)
+	})
+
+	t.Run("fails every key of a failed batch", func(t *testing.T) {
+		f := &recordingFetch{err: errors.New("connection reset")}
+		l := newLoader(f.fetch)
+		l.Queue(1, 2)
+
+		_, err := l.Load(context.Background(), 1)
+		assert.EqualError(t, err, "connection reset")
+		_, err = l.Load(context.Background(), 2)
+		assert.EqualError(t, err, "connection reset")
+		assert.Len(t, f.batches, 1)
+	})
+}
@@ -0,0 +1,418 @@
+package graphql
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mytheresa/go-hiring-challenge/app/api"
+	"github.com/mytheresa/go-hiring-challenge/app/auth"
+	"github.com/mytheresa/go-hiring-challenge/app/catalog"
+	"github.com/mytheresa/go-hiring-challenge/app/categories"
+	"github.com/mytheresa/go-hiring-challenge/models"
+	"github.com/shopspring/decimal"
+)
+
+// resolver is the root of the schema. It reads through the same repositories
+// as the REST handlers; the loaders batching lookups within a request travel
+// in the context.
+type resolver struct {
+	products   models.ProductRepository
+	categories models.CategoryRepository
+	policy     *auth.Policy
+}
+
+type productsArgs struct {
+	Filter *productFilter
+	First  int32
+	After  *string
+}
+
+type productFilter struct {
+	Category      *string
+	PriceLessThan *decimalScalar
+}
+
+func (r *resolver) Products(ctx context.Context, args productsArgs) (*connectionResolver, error) {
+	first := int(args.First)
+	after := ""
+	if args.After != nil {
+		after = *args.After
+	}
+
+	var filter models.ProductFilter
+	var v api.Validator
+	v.Field("first", strconv.Itoa(first), api.IntRange(1, 100))
+	v.Field("after", after, validCursor())
+	if f := args.Filter; f != nil {
+		if f.Category != nil {
+			filter.CategoryCode = *f.Category
+			v.Field("filter.category", filter.CategoryCode, api.Length(1, 32), api.Pattern(api.SlugPattern, "a lowercase slug"))
+		}
+		if f.PriceLessThan != nil {
+			filter.PriceLessThan = &f.PriceLessThan.Decimal
+			v.Field("filter.priceLessThan", filter.PriceLessThan.String(), api.DecimalRange(decimal.Zero, catalog.MaxPrice))
+		}
+	}
+	if err := v.Err(); err != nil {
+		return nil, resolverError(err, "invalid arguments")
+	}
+
+	offset := 0
+	if after != "" {
+		last, _ := decodeCursor(after)
+		offset = last + 1
+	}
+
+	products, total, err := r.products.Find(ctx, filter, offset, first)
+	if err != nil {
+		return nil, resolverError(err, "failed to fetch products")
+	}
+
+	// Queue the page so the first category or variants resolved fetches
+	// them for every product at once
+	l := loadersFrom(ctx)
+	for _, p := range products {
+		l.variants.Queue(p.ID)
+		if p.CategoryID != nil {
+			l.categories.Queue(*p.CategoryID)
+		}
+	}
+
+	return &connectionResolver{products: products, offset: offset, total: total}, nil
+}
+
+func (r *resolver) Product(ctx context.Context, args struct{ Code string }) (*productResolver, error) {
+	product, err := r.products.GetByCode(ctx, args.Code, false)
+	if errors.Is(err, models.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, resolverError(err, "failed to fetch product")
+	}
+
+	// The product comes with its category and variants
+	l := loadersFrom(ctx)
+	l.variants.Prime(product.ID, product.Variants)
+	if product.Category != nil {
+		l.categories.Prime(product.Category.ID, product.Category)
+	}
+
+	return &productResolver{product: product}, nil
+}
+
+func (r *resolver) Categories(ctx context.Context) ([]*categoryResolver, error) {
+	list, err := r.categories.GetAll(ctx, false)
+	if err != nil {
+		return nil, resolverError(err, "failed to fetch categories")
+	}
+	resolvers := make([]*categoryResolver, len(list))
+	for i := range list {
+		resolvers[i] = &categoryResolver{category: &list[i]}
+	}
+	return resolvers, nil
+}
+
+type createCategoryArgs struct {
+	Input struct {
+		Code string
+		Name string
+	}
+}
+
+func (r *resolver) CreateCategory(ctx context.Context, args createCategoryArgs) (*categoryResolver, error) {
+	if err := r.authorize(ctx, auth.PermCategoriesWrite); err != nil {
+		return nil, err
+	}
+
+	req := categories.CreateCategoryRequest{Code: args.Input.Code, Name: args.Input.Name}
+	if err := req.Validate(); err != nil {
+		return nil, resolverError(err, "invalid input")
+	}
+
+	category := &models.Category{Code: req.Code, Name: req.Name}
+	if err := r.categories.Create(ctx, category); err != nil {
+		return nil, resolverError(err, "failed to create category")
+	}
+	return &categoryResolver{category: category}, nil
+}
+
+type updateCategoryArgs struct {
+	Code    string
+	Version int32
+	Input   struct {
+		Name string
+	}
+}
+
+func (r *resolver) UpdateCategory(ctx context.Context, args updateCategoryArgs) (*categoryResolver, error) {
+	if err := r.authorize(ctx, auth.PermCategoriesWrite); err != nil {
+		return nil, err
+	}
+
+	version, err := validVersion(args.Version)
+	if err != nil {
+		return nil, err
+	}
+	req := categories.UpdateCategoryRequest{Name: &args.Input.Name}
+	if err := req.Validate(true); err != nil {
+		return nil, resolverError(err, "invalid input")
+	}
+
+	category, err := r.categories.Update(ctx, args.Code, version, models.CategoryChanges{Name: req.Name})
+	if err != nil {
+		return nil, resolverError(err, "failed to update category")
+	}
+	return &categoryResolver{category: category}, nil
+}
+
+type deleteCategoryArgs struct {
+	Code    string
+	Version int32
+}
+
+func (r *resolver) DeleteCategory(ctx context.Context, args deleteCategoryArgs) (bool, error) {
+	if err := r.authorize(ctx, auth.PermCategoriesWrite); err != nil {
+		return false, err
+	}
+
+	version, err := validVersion(args.Version)
+	if err != nil {
+		return false, err
+	}
+
+	if err := r.categories.Delete(ctx, args.Code, version); err != nil {
+		return false, resolverError(err, "failed to delete category")
+	}
+	return true, nil
+}
+
+func (r *resolver) RestoreCategory(ctx context.Context, args struct{ Code string }) (*categoryResolver, error) {
+	if err := r.authorize(ctx, auth.PermCatalogAdmin); err != nil {
+		return nil, err
+	}
+
+	category, err := r.categories.Restore(ctx, args.Code)
+	if err != nil {
+		return nil, resolverError(err, "failed to restore category")
+	}
+	return &categoryResolver{category: category}, nil
+}
+
+// authorize checks a mutation's permission. The route only demands
+// catalog:read, which covers queries, so mutations check their own.
+func (r *resolver) authorize(ctx context.Context, perm auth.Permission) error {
+	if err := r.policy.Check(auth.PrincipalFromContext(ctx), perm); err != nil {
+		return resolverError(err, "")
+	}
+	return nil
+}
+
+// validVersion converts a version argument. Unlike If-Match, there is no
+// wildcard: mutations always name the version they were based on.
+func validVersion(version int32) (uint, error) {
+	var v api.Validator
+	v.Field("version", strconv.Itoa(int(version)), api.IntRange(1, 1<<31-1))
+	if err := v.Err(); err != nil {
+		return 0, resolverError(err, "invalid arguments")
+	}
+	return uint(version), nil
+}
+
+type connectionResolver struct {
+	products []models.Product
+	offset   int
+	total    int64
+}
+
+func (c *connectionResolver) Edges() []*edgeResolver {
+	edges := make([]*edgeResolver, len(c.products))
+	for i := range c.products {
+		edges[i] = &edgeResolver{cursor: encodeCursor(c.offset + i), product: &c.products[i]}
+	}
+	return edges
+}
+
+func (c *connectionResolver) PageInfo() *pageInfoResolver {
+	info := &pageInfoResolver{hasNextPage: int64(c.offset+len(c.products)) < c.total}
+	if len(c.products) > 0 {
+		cursor := encodeCursor(c.offset + len(c.products) - 1)
+		info.endCursor = &cursor
+	}
+	return info
+}
+
+func (c *connectionResolver) TotalCount() int32 {
+	return int32(c.total)
+}
+
+type edgeResolver struct {
+	cursor  string
+	product *models.Product
+}
+
+func (e *edgeResolver) Cursor() string {
+	return e.cursor
+}
+
+func (e *edgeResolver) Node() *productResolver {
+	return &productResolver{product: e.product}
+}
+
+type pageInfoResolver struct {
+	hasNextPage bool
+	endCursor   *string
+}
+
+func (p *pageInfoResolver) HasNextPage() bool {
+	return p.hasNextPage
+}
+
+func (p *pageInfoResolver) EndCursor() *string {
+	return p.endCursor
+}
+
+type productResolver struct {
+	product *models.Product
+}
+
+func (p *productResolver) Code() string {
+	return p.product.Code
+}
+
+func (p *productResolver) Price() decimalScalar {
+	return decimalScalar{p.product.Price}
+}
+
+func (p *productResolver) Category(ctx context.Context) (*categoryResolver, error) {
+	if p.product.CategoryID == nil {
+		return nil, nil
+	}
+	category, err := loadersFrom(ctx).categories.Load(ctx, *p.product.CategoryID)
+	if err != nil {
+		return nil, resolverError(err, "failed to fetch category")
+	}
+	if category == nil {
+		return nil, nil
+	}
+	return &categoryResolver{category: category}, nil
+}
+
+func (p *productResolver) Variants(ctx context.Context) ([]*variantResolver, error) {
+	variants, err := loadersFrom(ctx).variants.Load(ctx, p.product.ID)
+	if err != nil {
+		return nil, resolverError(err, "failed to fetch variants")
+	}
+	resolvers := make([]*variantResolver, len(variants))
+	for i := range variants {
+		resolvers[i] = &variantResolver{variant: &variants[i], product: p.product}
+	}
+	return resolvers, nil
+}
+
+type variantResolver struct {
+	variant *models.Variant
+	product *models.Product
+}
+
+func (v *variantResolver) SKU() string {
+	return v.variant.SKU
+}
+
+func (v *variantResolver) Name() string {
+	return v.variant.Name
+}
+
+func (v *variantResolver) Price() decimalScalar {
+	if v.variant.Price.IsZero() {
+		return decimalScalar{v.product.Price}
+	}
+	return decimalScalar{v.variant.Price}
+}
+
+type categoryResolver struct {
+	category *models.Category
+}
+
+func (c *categoryResolver) Code() string {
+	return c.category.Code
+}
+
+func (c *categoryResolver) Name() string {
+	return c.category.Name
+}
+
+func (c *categoryResolver) Version() int32 {
+	return int32(c.category.Version)
+}
+
+// decimalScalar is the Decimal scalar. Prices go out as strings with two
+// decimals, so clients never see float rounding.
+type decimalScalar struct {
+	decimal.Decimal
+}
+
+func (decimalScalar) ImplementsGraphQLType(name string) bool {
+	return name == "Decimal"
+}
+
+func (d *decimalScalar) UnmarshalGraphQL(input any) error {
+	switch input := input.(type) {
+	case string:
+		parsed, err := decimal.NewFromString(input)
+		if err != nil {
+			return fmt.Errorf("invalid Decimal %q", input)
+		}
+		d.Decimal = parsed
+	case int32:
+		d.Decimal = decimal.NewFromInt32(input)
+	case float64:
+		d.Decimal = decimal.NewFromFloat(input)
+	default:
+		return fmt.Errorf("wrong type for Decimal: %T", input)
+	}
+	return nil
+}
+
+func (d decimalScalar) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(d.StringFixed(2))), nil
+}
+
+// cursorPrefix marks cursors issued by products; the offset after it stays
+// opaque to clients.
+const cursorPrefix = "offset:"
+
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(cursorPrefix + strconv.Itoa(offset)))
+}
+
+func decodeCursor(s string) (int, bool) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return 0, false
+	}
+	digits, ok := strings.CutPrefix(string(b), cursorPrefix)
+	if !ok {
+		return 0, false
+	}
+	offset, err := strconv.Atoi(digits)
+	if err != nil || offset < 0 || offset >= 1<<31-1 {
+		return 0, false
+	}
+	return offset, true
+}
+
+func validCursor() api.Rule {
+	return func(value string) *api.FieldError {
+		if value == "" {
+			return nil
+		}
+		if _, ok := decodeCursor(value); !ok {
+			return &api.FieldError{Code: "format", Message: "must be a cursor returned by products"}
+		}
+		return nil
+	}
+}
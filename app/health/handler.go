@@ -0,0 +1,140 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/mytheresa/go-hiring-challenge/app/api"
+	"github.com/mytheresa/go-hiring-challenge/app/database"
+	"gorm.io/gorm"
+)
+
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+	StatusDraining    = "draining"
+)
+
+// Check probes a single dependency and returns an error when it is not usable.
+type Check func(ctx context.Context) error
+
+type CheckResult struct {
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type Response struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+type Handler struct {
+	draining func() bool
+	timeout  time.Duration
+	checks   []namedCheck
+}
+
+// NewHandler creates a health handler. Readiness fails whenever draining reports true.
+func NewHandler(draining func() bool, timeout time.Duration) *Handler {
+	return &Handler{
+		draining: draining,
+		timeout:  timeout,
+	}
+}
+
+// Register adds a dependency check to the readiness probe.
+func (h *Handler) Register(name string, check Check) {
+	h.checks = append(h.checks, namedCheck{name: name, check: check})
+}
+
+// HandleLiveness reports that the process is up and serving HTTP.
+func (h *Handler) HandleLiveness(w http.ResponseWriter, r *http.Request) {
+	api.OKResponse(w, Response{Status: StatusOK})
+}
+
+// HandleReadiness runs every registered check concurrently and reports
+// per-dependency status and latency.
+func (h *Handler) HandleReadiness(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+
+	if h.draining != nil && h.draining() {
+		api.JSONResponse(w, http.StatusServiceUnavailable, Response{Status: StatusDraining})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.timeout)
+	defer cancel()
+
+	response := Response{Status: StatusOK, Checks: make(map[string]CheckResult, len(h.checks))}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, c := range h.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := run(ctx, c.check)
+
+			mu.Lock()
+			defer mu.Unlock()
+			response.Checks[c.name] = result
+			if result.Status != StatusOK {
+				response.Status = StatusUnavailable
+			}
+		}()
+	}
+	wg.Wait()
+
+	status := http.StatusOK
+	if response.Status != StatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	api.JSONResponse(w, status, response)
+}
+
+func run(ctx context.Context, check Check) CheckResult {
+	start := time.Now()
+	err := check(ctx)
+	result := CheckResult{
+		Status:    StatusOK,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = StatusUnavailable
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// DatabasePing checks that a connection can be obtained from the pool.
+func DatabasePing(db *gorm.DB) Check {
+	return func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	}
+}
+
+// MigrationVersion checks that the database schema is at least at the expected version.
+func MigrationVersion(db *gorm.DB, expected string) Check {
+	return func(ctx context.Context) error {
+		current, err := database.CurrentVersion(ctx, db)
+		if err != nil {
+			return err
+		}
+		if current < expected {
+			return fmt.Errorf("schema at version %q, expected %q", current, expected)
+		}
+		return nil
+	}
+}
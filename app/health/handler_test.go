@@ -0,0 +1,82 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func notDraining() bool { return false }
+
+func TestHandler_HandleLiveness(t *testing.T) {
+	handler := NewHandler(notDraining, time.Second)
+
+	recorder := httptest.NewRecorder()
+	handler.HandleLiveness(recorder, httptest.NewRequest("GET", "/healthz", nil))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, recorder.Body.String())
+}
+
+func TestHandler_HandleReadiness(t *testing.T) {
+	t.Run("reports ok when every check passes", func(t *testing.T) {
+		handler := NewHandler(notDraining, time.Second)
+		handler.Register("database", func(ctx context.Context) error { return nil })
+
+		recorder := httptest.NewRecorder()
+		handler.HandleReadiness(recorder, httptest.NewRequest("GET", "/readyz", nil))
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		var response Response
+		require.NoError(t, json.NewDecoder(recorder.Body).Decode(&response))
+		assert.Equal(t, StatusOK, response.Status)
+		assert.Equal(t, StatusOK, response.Checks["database"].Status)
+	})
+
+	t.Run("reports each failing dependency", func(t *testing.T) {
+		handler := NewHandler(notDraining, time.Second)
+		handler.Register("database", func(ctx context.Context) error { return nil })
+		handler.Register("migrations", func(ctx context.Context) error { return errors.New("schema outdated") })
+
+		recorder := httptest.NewRecorder()
+		handler.HandleReadiness(recorder, httptest.NewRequest("GET", "/readyz", nil))
+
+		assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+		var response Response
+		require.NoError(t, json.NewDecoder(recorder.Body).Decode(&response))
+		assert.Equal(t, StatusUnavailable, response.Status)
+		assert.Equal(t, StatusOK, response.Checks["database"].Status)
+		assert.Equal(t, "schema outdated", response.Checks["migrations"].Error)
+	})
+
+	t.Run("bounds slow checks by the timeout", func(t *testing.T) {
+		handler := NewHandler(notDraining, 20*time.Millisecond)
+		handler.Register("database", func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+
+		recorder := httptest.NewRecorder()
+		handler.HandleReadiness(recorder, httptest.NewRequest("GET", "/readyz", nil))
+
+		assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	})
+
+	t.Run("fails while the server is draining", func(t *testing.T) {
+		handler := NewHandler(func() bool { return true }, time.Second)
+		handler.Register("database", func(ctx context.Context) error { return nil })
+
+		recorder := httptest.NewRecorder()
+		handler.HandleReadiness(recorder, httptest.NewRequest("GET", "/readyz", nil))
+
+		assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+		assert.JSONEq(t, `{"status":"draining"}`, recorder.Body.String())
+	})
+}
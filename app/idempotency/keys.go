@@ -0,0 +1,196 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/mytheresa/go-hiring-challenge/app/api"
+	"github.com/mytheresa/go-hiring-challenge/app/auth"
+	"github.com/mytheresa/go-hiring-challenge/app/config"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+const (
+	// Header carries the client-chosen key that identifies retries of one request.
+	Header = "Idempotency-Key"
+	// ReplayedHeader marks responses that were replayed from a previous request.
+	ReplayedHeader = "Idempotent-Replayed"
+
+	maxKeyLength = 255
+)
+
+// replayedHeaders are the response headers stored and replayed with the body.
+var replayedHeaders = []string{"Content-Type", "ETag", "Last-Modified", "Location"}
+
+// Keys makes POST handlers safe to retry: the first request with an
+// Idempotency-Key runs the handler and records its response for the
+// configured window, identical retries get the recorded response, and reusing
+// a key for a different request is rejected.
+type Keys struct {
+	repo   models.IdempotencyKeyRepository
+	ttl    time.Duration
+	logger *slog.Logger
+}
+
+func New(repo models.IdempotencyKeyRepository, cfg config.IdempotencyConfig, logger *slog.Logger) *Keys {
+	return &Keys{repo: repo, ttl: cfg.TTL, logger: logger}
+}
+
+// Wrap applies idempotency keys to h. Requests without the header pass
+// through unchanged. Keys are scoped to the authenticated principal, so it
+// must run after authentication.
+func (k *Keys) Wrap(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(Header)
+		if key == "" {
+			h(w, r)
+			return
+		}
+		if len(key) > maxKeyLength {
+			api.ErrorResponse(w, r, http.StatusBadRequest, api.CodeBadRequest, "Idempotency-Key must not exceed 255 characters")
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			api.HandleError(w, r, err, "failed to read request body")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		reservation := &models.IdempotencyKey{
+			Scope:       scope(r),
+			Key:         key,
+			RequestHash: requestHash(r, body),
+			ExpiresAt:   time.Now().Add(k.ttl),
+		}
+		existing, err := k.repo.Reserve(r.Context(), reservation)
+		if err != nil {
+			api.HandleError(w, r, err, "failed to record idempotency key")
+			return
+		}
+		if existing != nil {
+			k.replay(w, r, reservation, existing)
+			return
+		}
+
+		k.record(w, r, h, reservation)
+	}
+}
+
+// replay answers a retry from the record of the first request.
+func (k *Keys) replay(w http.ResponseWriter, r *http.Request, request, existing *models.IdempotencyKey) {
+	switch {
+	case existing.RequestHash != request.RequestHash:
+		api.ErrorResponse(w, r, http.StatusUnprocessableEntity, api.CodeIdempotencyKeyReused,
+			"Idempotency-Key was already used for a different request")
+	case existing.Status == nil:
+		w.Header().Set("Retry-After", "1")
+		api.ErrorResponse(w, r, http.StatusConflict, api.CodeConflict,
+			"a request with this Idempotency-Key is still being processed")
+	default:
+		var headers map[string]string
+		if len(existing.Headers) > 0 {
+			if err := json.Unmarshal(existing.Headers, &headers); err != nil {
+				api.HandleError(w, r, err, "failed to replay response")
+				return
+			}
+		}
+		for name, value := range headers {
+			w.Header().Set(name, value)
+		}
+		w.Header().Set(ReplayedHeader, "true")
+		w.WriteHeader(*existing.Status)
+		w.Write(existing.Body)
+	}
+}
+
+// record runs h and stores its response. Server errors and panics release
+// the key instead, so that the client can retry the request.
+func (k *Keys) record(w http.ResponseWriter, r *http.Request, h http.HandlerFunc, reservation *models.IdempotencyKey) {
+	rec := &recorder{ResponseRecorder: api.NewResponseRecorder(w)}
+	stored := false
+	ctx := context.WithoutCancel(r.Context())
+	defer func() {
+		if stored {
+			return
+		}
+		if err := k.repo.Release(ctx, reservation.ID); err != nil {
+			k.logger.ErrorContext(ctx, "failed to release idempotency key", "key", reservation.Key, "error", err)
+		}
+	}()
+
+	h(rec, r)
+
+	status := rec.Status()
+	if status >= http.StatusInternalServerError {
+		return
+	}
+
+	headers := make(map[string]string)
+	for _, name := range replayedHeaders {
+		if value := w.Header().Get(name); value != "" {
+			headers[name] = value
+		}
+	}
+	reservation.Headers, _ = json.Marshal(headers)
+	reservation.Status = &status
+	reservation.Body = rec.body.Bytes()
+	if err := k.repo.Complete(ctx, reservation); err != nil {
+		k.logger.ErrorContext(ctx, "failed to store idempotent response", "key", reservation.Key, "error", err)
+		return
+	}
+	stored = true
+}
+
+// Run deletes expired keys every interval until ctx is cancelled.
+func (k *Keys) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if n, err := k.repo.DeleteExpired(ctx); err != nil {
+				k.logger.ErrorContext(ctx, "failed to delete expired idempotency keys", "error", err)
+			} else if n > 0 {
+				k.logger.DebugContext(ctx, "deleted expired idempotency keys", "count", n)
+			}
+		}
+	}
+}
+
+// recorder passes the response through while keeping a copy of the body.
+type recorder struct {
+	*api.ResponseRecorder
+	body bytes.Buffer
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	n, err := r.ResponseRecorder.Write(b)
+	r.body.Write(b[:n])
+	return n, err
+}
+
+func scope(r *http.Request) string {
+	if p := auth.PrincipalFromContext(r.Context()); p != nil {
+		return p.Subject
+	}
+	return ""
+}
+
+// requestHash fingerprints what makes two requests the same: method, path and body.
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.Path+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,194 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mytheresa/go-hiring-challenge/app/api"
+	"github.com/mytheresa/go-hiring-challenge/app/auth"
+	"github.com/mytheresa/go-hiring-challenge/app/config"
+	"github.com/mytheresa/go-hiring-challenge/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryRepository implements models.IdempotencyKeyRepository with the same
+// reservation semantics as the database table.
+type memoryRepository struct {
+	mu     sync.Mutex
+	nextID uint
+	keys   map[string]*models.IdempotencyKey
+	err    error
+}
+
+func newMemoryRepository() *memoryRepository {
+	return &memoryRepository{keys: make(map[string]*models.IdempotencyKey)}
+}
+
+func (m *memoryRepository) Reserve(_ context.Context, key *models.IdempotencyKey) (*models.IdempotencyKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return nil, m.err
+	}
+	id := key.Scope + "/" + key.Key
+	if existing, ok := m.keys[id]; ok && existing.ExpiresAt.After(time.Now()) {
+		copied := *existing
+		return &copied, nil
+	}
+	m.nextID++
+	key.ID = m.nextID
+	stored := *key
+	m.keys[id] = &stored
+	return nil, nil
+}
+
+func (m *memoryRepository) Complete(_ context.Context, key *models.IdempotencyKey) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored := *key
+	m.keys[key.Scope+"/"+key.Key] = &stored
+	return nil
+}
+
+func (m *memoryRepository) Release(_ context.Context, id uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for k, v := range m.keys {
+		if v.ID == id {
+			delete(m.keys, k)
+		}
+	}
+	return nil
+}
+
+func (m *memoryRepository) DeleteExpired(context.Context) (int64, error) { return 0, nil }
+
+func TestKeys_Wrap(t *testing.T) {
+	var calls int
+	create := func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if strings.Contains(r.URL.Path, "fail") {
+			api.ErrorResponse(w, r, http.StatusServiceUnavailable, api.CodeUnavailable, "database unavailable")
+			return
+		}
+		w.Header().Set("ETag", `"1"`)
+		w.Header().Set("X-Not-Replayed", "1")
+		api.OKResponse(w, map[string]int{"call": calls})
+	}
+
+	post := func(keys *Keys, path, key, body, subject string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(Header, key)
+		}
+		req = req.WithContext(auth.WithPrincipal(req.Context(), &auth.Principal{Subject: subject}))
+		recorder := httptest.NewRecorder()
+		keys.Wrap(create)(recorder, req)
+		return recorder
+	}
+
+	newKeys := func(repo models.IdempotencyKeyRepository) *Keys {
+		return New(repo, config.IdempotencyConfig{TTL: time.Hour}, slog.Default())
+	}
+
+	t.Run("replays the recorded response for identical retries", func(t *testing.T) {
+		calls = 0
+		keys := newKeys(newMemoryRepository())
+
+		first := post(keys, "/categories", "abc", `{"code":"shoes"}`, "alice")
+		retry := post(keys, "/categories", "abc", `{"code":"shoes"}`, "alice")
+
+		assert.Equal(t, 1, calls)
+		assert.Equal(t, http.StatusOK, retry.Code)
+		assert.Equal(t, first.Body.String(), retry.Body.String())
+		assert.Equal(t, `"1"`, retry.Header().Get("ETag"))
+		assert.Equal(t, "application/json", retry.Header().Get("Content-Type"))
+		assert.Empty(t, retry.Header().Get("X-Not-Replayed"))
+		assert.Equal(t, "true", retry.Header().Get(ReplayedHeader))
+		assert.Empty(t, first.Header().Get(ReplayedHeader))
+	})
+
+	t.Run("rejects a reused key with a different payload", func(t *testing.T) {
+		calls = 0
+		keys := newKeys(newMemoryRepository())
+
+		post(keys, "/categories", "abc", `{"code":"shoes"}`, "alice")
+		reused := post(keys, "/categories", "abc", `{"code":"bags"}`, "alice")
+
+		assert.Equal(t, 1, calls)
+		assert.Equal(t, http.StatusUnprocessableEntity, reused.Code)
+		assert.Contains(t, reused.Body.String(), `"code":"idempotency_key_reused"`)
+	})
+
+	t.Run("scopes keys to the principal", func(t *testing.T) {
+		calls = 0
+		keys := newKeys(newMemoryRepository())
+
+		post(keys, "/categories", "abc", `{"code":"shoes"}`, "alice")
+		other := post(keys, "/categories", "abc", `{"code":"bags"}`, "bob")
+
+		assert.Equal(t, 2, calls)
+		assert.Equal(t, http.StatusOK, other.Code)
+	})
+
+	t.Run("reports requests still in progress", func(t *testing.T) {
+		repo := newMemoryRepository()
+		repo.keys["alice/abc"] = &models.IdempotencyKey{Scope: "alice", Key: "abc", RequestHash: requestHash(httptest.NewRequest("POST", "/categories", nil), []byte("{}")), ExpiresAt: time.Now().Add(time.Hour)}
+		keys := newKeys(repo)
+
+		recorder := post(keys, "/categories", "abc", `{}`, "alice")
+
+		assert.Equal(t, http.StatusConflict, recorder.Code)
+		assert.Equal(t, "1", recorder.Header().Get("Retry-After"))
+	})
+
+	t.Run("releases the key after a server error so the retry runs", func(t *testing.T) {
+		calls = 0
+		keys := newKeys(newMemoryRepository())
+
+		first := post(keys, "/fail", "abc", `{}`, "alice")
+		retry := post(keys, "/fail", "abc", `{}`, "alice")
+
+		assert.Equal(t, http.StatusServiceUnavailable, first.Code)
+		assert.Equal(t, http.StatusServiceUnavailable, retry.Code)
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("passes requests without a key through", func(t *testing.T) {
+		calls = 0
+		keys := newKeys(newMemoryRepository())
+
+		post(keys, "/categories", "", `{}`, "alice")
+		post(keys, "/categories", "", `{}`, "alice")
+
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("rejects overlong keys", func(t *testing.T) {
+		keys := newKeys(newMemoryRepository())
+
+		recorder := post(keys, "/categories", strings.Repeat("k", 256), `{}`, "alice")
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+
+	t.Run("returns 503 when keys cannot be stored", func(t *testing.T) {
+		calls = 0
+		repo := newMemoryRepository()
+		repo.err = &models.Error{Kind: models.ErrUnavailable, Message: "database unavailable", Err: errors.New("connection refused")}
+		keys := newKeys(repo)
+
+		recorder := post(keys, "/categories", "abc", `{}`, "alice")
+
+		require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+		assert.Equal(t, 0, calls)
+	})
+}
@@ -0,0 +1,80 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mytheresa/go-hiring-challenge/app/api"
+	"github.com/mytheresa/go-hiring-challenge/models"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestMetrics_Middleware(t *testing.T) {
+	m := New()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /catalog/{code}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.Handle("GET /metrics", m.Handler())
+	h := api.Chain(mux, m.Middleware())
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/catalog/PROD001", nil))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/catalog/PROD002", nil))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/does/not/exist", nil))
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.requests.WithLabelValues("GET /catalog/{code}", "404")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.requests.WithLabelValues(unmatchedRoute, "404")))
+
+	recorder := httptest.NewRecorder()
+	h.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(recorder.Body)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, string(body), `http_requests_total{route="GET /catalog/{code}",status="404"} 2`)
+	assert.Contains(t, string(body), `http_request_duration_seconds_bucket{route="GET /catalog/{code}",status="404",le="+Inf"} 2`)
+}
+
+func TestMetrics_InstrumentGORM(t *testing.T) {
+	m := New()
+
+	// DryRun builds statements without a live database, which is enough to fire callbacks.
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+	})
+	require.NoError(t, err)
+	require.NoError(t, m.InstrumentGORM(db))
+
+	var products []models.Product
+	db.Find(&products)
+	db.Create(&models.Category{Code: "shoes", Name: "Shoes"})
+
+	count, err := testutil.GatherAndCount(m.registry, "db_query_duration_seconds")
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	families, err := m.registry.Gather()
+	require.NoError(t, err)
+	var labels []string
+	for _, mf := range families {
+		if mf.GetName() != "db_query_duration_seconds" {
+			continue
+		}
+		for _, metric := range mf.GetMetric() {
+			var pairs []string
+			for _, l := range metric.GetLabel() {
+				pairs = append(pairs, l.GetName()+"="+l.GetValue())
+			}
+			labels = append(labels, strings.Join(pairs, ","))
+		}
+	}
+	assert.ElementsMatch(t, []string{"operation=create,table=categories", "operation=query,table=products"}, labels)
+}
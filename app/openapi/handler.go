@@ -0,0 +1,94 @@
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/mytheresa/go-hiring-challenge/app/api"
+	"gopkg.in/yaml.v3"
+)
+
+// The v1 document is maintained as YAML for readability and served as JSON.
+// Later versions are described by overlays replacing what changed.
+//
+//go:embed openapi.yaml
+var specYAML []byte
+
+//go:embed openapi.v2.yaml
+var v2YAML []byte
+
+var overlays = map[string][]byte{
+	"v1": nil,
+	"v2": v2YAML,
+}
+
+//go:embed docs.html
+var docsHTML []byte
+
+// Spec returns the OpenAPI document describing every route of an API
+// version, e.g. "v1".
+func Spec(version string) (json.RawMessage, error) {
+	overlay, ok := overlays[version]
+	if !ok {
+		return nil, fmt.Errorf("unknown API version %q", version)
+	}
+
+	var doc, changes map[string]any
+	if err := yaml.Unmarshal(specYAML, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI document: %w", err)
+	}
+	if err := yaml.Unmarshal(overlay, &changes); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI overlay for %s: %w", version, err)
+	}
+	merge(doc, changes)
+
+	spec, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode OpenAPI document: %w", err)
+	}
+	return spec, nil
+}
+
+// merge copies overlay into doc, descending into objects present in both and
+// replacing everything else.
+func merge(doc, overlay map[string]any) {
+	for key, value := range overlay {
+		if nested, ok := value.(map[string]any); ok {
+			if existing, ok := doc[key].(map[string]any); ok {
+				merge(existing, nested)
+				continue
+			}
+		}
+		doc[key] = value
+	}
+}
+
+type Handler struct {
+	spec json.RawMessage
+}
+
+// NewHandler creates a handler serving the OpenAPI document of an API
+// version, which is converted once up front.
+func NewHandler(version string) (*Handler, error) {
+	spec, err := Spec(version)
+	if err != nil {
+		return nil, err
+	}
+	return &Handler{
+		spec: spec,
+	}, nil
+}
+
+// HandleSpec serves the OpenAPI document.
+func (h *Handler) HandleSpec(w http.ResponseWriter, r *http.Request) {
+	api.ConditionalResponse(w, r, h.spec, time.Time{})
+}
+
+// HandleDocs serves a page that renders the OpenAPI document for browsing.
+func (h *Handler) HandleDocs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(docsHTML)
+}
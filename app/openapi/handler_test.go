@@ -0,0 +1,342 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mytheresa/go-hiring-challenge/app/api"
+	"github.com/mytheresa/go-hiring-challenge/app/audit"
+	"github.com/mytheresa/go-hiring-challenge/app/catalog"
+	"github.com/mytheresa/go-hiring-challenge/app/categories"
+	"github.com/mytheresa/go-hiring-challenge/app/changes"
+	"github.com/mytheresa/go-hiring-challenge/app/graphql"
+	"github.com/mytheresa/go-hiring-challenge/app/health"
+	"github.com/mytheresa/go-hiring-challenge/app/webhooks"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type schema struct {
+	name    string
+	typ     reflect.Type
+	request bool
+}
+
+// shared are the schemas that are the same in every API version.
+var shared = []schema{
+	{"CategorySummary", reflect.TypeFor[catalog.CategorySummary](), false},
+	{"UpdateProductRequest", reflect.TypeFor[catalog.UpdateProductRequest](), true},
+	{"UpdateVariantRequest", reflect.TypeFor[catalog.UpdateVariantRequest](), true},
+	{"CategoryResponse", reflect.TypeFor[categories.CategoryResponse](), false},
+	{"CreateCategoryRequest", reflect.TypeFor[categories.CreateCategoryRequest](), true},
+	{"UpdateCategoryRequest", reflect.TypeFor[categories.UpdateCategoryRequest](), true},
+	{"AuditResponse", reflect.TypeFor[audit.Response](), false},
+	{"EntryResponse", reflect.TypeFor[audit.EntryResponse](), false},
+	{"CategoryChange", reflect.TypeFor[changes.CategoryChange](), false},
+	{"Tombstone", reflect.TypeFor[changes.Tombstone](), false},
+	{"SubscriptionResponse", reflect.TypeFor[webhooks.SubscriptionResponse](), false},
+	{"CreateSubscriptionRequest", reflect.TypeFor[webhooks.CreateSubscriptionRequest](), true},
+	{"UpdateSubscriptionRequest", reflect.TypeFor[webhooks.UpdateSubscriptionRequest](), true},
+	{"DeliveriesResponse", reflect.TypeFor[webhooks.DeliveriesResponse](), false},
+	{"DeliveryResponse", reflect.TypeFor[webhooks.DeliveryResponse](), false},
+	{"HealthResponse", reflect.TypeFor[health.Response](), false},
+	{"CheckResult", reflect.TypeFor[health.CheckResult](), false},
+	{"Problem", reflect.TypeFor[api.Problem](), false},
+	{"FieldError", reflect.TypeFor[api.FieldError](), false},
+	{"GraphQLRequest", reflect.TypeFor[graphql.Request](), true},
+}
+
+// versions maps each API version to the types its handlers encode or decode
+// and the schemas that document them.
+var versions = map[string][]schema{
+	"v1": append([]schema{
+		{"CatalogResponse", reflect.TypeFor[catalog.CatalogResponse](), false},
+		{"ProductResponse", reflect.TypeFor[catalog.ProductResponse](), false},
+		{"ProductDetailsResponse", reflect.TypeFor[catalog.ProductDetailsResponse](), false},
+		{"VariantResponse", reflect.TypeFor[catalog.VariantResponse](), false},
+		{"ChangesResponse", reflect.TypeFor[changes.Response](), false},
+		{"ProductChange", reflect.TypeFor[changes.ProductChange](), false},
+		{"VariantChange", reflect.TypeFor[changes.VariantChange](), false},
+	}, shared...),
+	"v2": append([]schema{
+		{"CatalogResponse", reflect.TypeFor[catalog.CatalogResponseV2](), false},
+		{"ProductResponse", reflect.TypeFor[catalog.ProductResponseV2](), false},
+		{"ProductDetailsResponse", reflect.TypeFor[catalog.ProductDetailsResponseV2](), false},
+		{"VariantResponse", reflect.TypeFor[catalog.VariantResponseV2](), false},
+		{"ChangesResponse", reflect.TypeFor[changes.ResponseV2](), false},
+		{"ProductChange", reflect.TypeFor[changes.ProductChangeV2](), false},
+		{"VariantChange", reflect.TypeFor[changes.VariantChangeV2](), false},
+	}, shared...),
+}
+
+// extensions are documented properties that are not struct fields.
+var extensions = map[string][]string{
+	"Problem": {"missing_permission"},
+}
+
+func loadSpec(t *testing.T, version string) map[string]any {
+	t.Helper()
+	spec, err := Spec(version)
+	require.NoError(t, err)
+	var doc map[string]any
+	require.NoError(t, json.Unmarshal(spec, &doc))
+	return doc
+}
+
+func TestSpec_MatchesTypes(t *testing.T) {
+	for version, schemas := range versions {
+		doc := loadSpec(t, version)
+		components := doc["components"].(map[string]any)["schemas"].(map[string]any)
+
+		names := make(map[reflect.Type]string, len(schemas))
+		for _, s := range schemas {
+			names[s.typ] = s.name
+		}
+
+		for _, s := range schemas {
+			t.Run(version+"/"+s.name, func(t *testing.T) {
+				schema, ok := components[s.name].(map[string]any)
+				require.True(t, ok, "schema %s is not documented", s.name)
+				properties, _ := schema["properties"].(map[string]any)
+				required := stringList(schema["required"])
+
+				documented := make(map[string]bool, len(properties))
+				for name := range properties {
+					documented[name] = true
+				}
+				for _, name := range extensions[s.name] {
+					delete(documented, name)
+				}
+
+				for i := 0; i < s.typ.NumField(); i++ {
+					field := s.typ.Field(i)
+					name, omitempty := jsonName(field)
+					if name == "" {
+						continue
+					}
+					delete(documented, name)
+
+					property, ok := properties[name].(map[string]any)
+					if !assert.True(t, ok, "field %s is not documented", name) {
+						continue
+					}
+					checkType(t, components, names, name, field.Type, property, s.request)
+
+					if s.request {
+						continue
+					}
+					assert.Equal(t, !omitempty, slices.Contains(required, name), "field %s: required must match the absence of omitempty", name)
+					if field.Type.Kind() == reflect.Pointer && !omitempty {
+						assert.Contains(t, types(resolve(components, property)), "null", "field %s can be null", name)
+					}
+				}
+
+				for name := range documented {
+					assert.Fail(t, "property is not a struct field", "property %s of %s", name, s.name)
+				}
+			})
+		}
+	}
+}
+
+// checkType asserts that the schema of a property fits the Go type of its field.
+func checkType(t *testing.T, components map[string]any, names map[reflect.Type]string, name string, typ reflect.Type, property map[string]any, request bool) {
+	t.Helper()
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	switch typ {
+	case reflect.TypeFor[json.RawMessage]():
+		return
+	case reflect.TypeFor[time.Time]():
+		assert.Equal(t, []string{"string"}, nonNull(resolve(components, property)), "field %s", name)
+		assert.Equal(t, "date-time", resolve(components, property)["format"], "field %s", name)
+		return
+	case reflect.TypeFor[decimal.Decimal]():
+		assert.ElementsMatch(t, []string{"number", "string"}, nonNull(resolve(components, property)), "field %s", name)
+		return
+	}
+
+	if typ.Kind() == reflect.Interface {
+		return
+	}
+
+	if typ.Kind() == reflect.Struct {
+		want, ok := names[typ]
+		if assert.True(t, ok, "field %s: %s has no schema", name, typ) {
+			assert.Equal(t, "#/components/schemas/"+want, property["$ref"], "field %s", name)
+		}
+		return
+	}
+
+	schema := resolve(components, property)
+	var want string
+	switch typ.Kind() {
+	case reflect.String:
+		want = "string"
+	case reflect.Bool:
+		want = "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		want = "integer"
+	case reflect.Float32, reflect.Float64:
+		want = "number"
+	case reflect.Slice:
+		want = "array"
+		if items, ok := schema["items"].(map[string]any); assert.True(t, ok, "field %s has no items", name) {
+			checkType(t, components, names, name+"[]", typ.Elem(), items, request)
+		}
+	case reflect.Map:
+		want = "object"
+		if values, ok := schema["additionalProperties"].(map[string]any); assert.True(t, ok, "field %s has no additionalProperties", name) {
+			checkType(t, components, names, name+"{}", typ.Elem(), values, request)
+		}
+	default:
+		assert.Fail(t, "unsupported field type", "field %s: %s", name, typ)
+		return
+	}
+	assert.Equal(t, []string{want}, nonNull(schema), "field %s", name)
+}
+
+// resolve follows a reference to a component schema.
+func resolve(components map[string]any, schema map[string]any) map[string]any {
+	ref, ok := schema["$ref"].(string)
+	if !ok {
+		return schema
+	}
+	resolved, _ := components[strings.TrimPrefix(ref, "#/components/schemas/")].(map[string]any)
+	return resolved
+}
+
+func types(schema map[string]any) []string {
+	if typ, ok := schema["type"].(string); ok {
+		return []string{typ}
+	}
+	return stringList(schema["type"])
+}
+
+func nonNull(schema map[string]any) []string {
+	return slices.DeleteFunc(types(schema), func(typ string) bool { return typ == "null" })
+}
+
+func stringList(v any) []string {
+	list, _ := v.([]any)
+	out := make([]string, 0, len(list))
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func jsonName(field reflect.StructField) (name string, omitempty bool) {
+	if !field.IsExported() {
+		return "", false
+	}
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	name, options, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = field.Name
+	}
+	return name, slices.Contains(strings.Split(options, ","), "omitempty")
+}
+
+// routePattern matches registrations on the mux, a route group or a router.
+var routePattern = regexp.MustCompile(`\.Handle(?:Func)?\("([A-Z]+) ([^"]+)"`)
+
+func TestSpec_CoversRoutes(t *testing.T) {
+	source, err := os.ReadFile("../../cmd/server/main.go")
+	require.NoError(t, err)
+
+	registered := make(map[string]bool)
+	for _, match := range routePattern.FindAllStringSubmatch(string(source), -1) {
+		registered[strings.ToLower(match[1])+" "+match[2]] = true
+	}
+	require.NotEmpty(t, registered)
+
+	for version := range versions {
+		documented := make(map[string]bool)
+		for path, item := range loadSpec(t, version)["paths"].(map[string]any) {
+			for method := range item.(map[string]any) {
+				if method != "parameters" && method != "servers" {
+					documented[method+" "+path] = true
+				}
+			}
+		}
+
+		for route := range registered {
+			assert.True(t, documented[route], "route %s is not documented in %s", route, version)
+		}
+		for route := range documented {
+			assert.True(t, registered[route], "route %s documented in %s is not registered", route, version)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	h, err := NewHandler("v1")
+	require.NoError(t, err)
+
+	t.Run("serves the document as JSON", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		h.HandleSpec(res, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+		require.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, "application/json", res.Header().Get("Content-Type"))
+		assert.NotEmpty(t, res.Header().Get("ETag"))
+
+		var doc map[string]any
+		require.NoError(t, json.Unmarshal(res.Body.Bytes(), &doc))
+		assert.Equal(t, "3.1.0", doc["openapi"])
+	})
+
+	t.Run("answers 304 when the client is current", func(t *testing.T) {
+		first := httptest.NewRecorder()
+		h.HandleSpec(first, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+		req := httptest.NewRequest(http.MethodGet, "/openapi.json", nil)
+		req.Header.Set("If-None-Match", first.Header().Get("ETag"))
+		res := httptest.NewRecorder()
+		h.HandleSpec(res, req)
+
+		assert.Equal(t, http.StatusNotModified, res.Code)
+	})
+
+	t.Run("serves the docs page", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		h.HandleDocs(res, httptest.NewRequest(http.MethodGet, "/docs", nil))
+
+		require.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, "text/html; charset=utf-8", res.Header().Get("Content-Type"))
+		assert.Contains(t, res.Body.String(), `spec-url="openapi.json"`)
+	})
+
+	t.Run("describes v2 by its overlay", func(t *testing.T) {
+		doc := loadSpec(t, "v2")
+
+		assert.Equal(t, "2.0.0", doc["info"].(map[string]any)["version"])
+		assert.Equal(t, []any{map[string]any{"url": "/v2"}}, doc["servers"])
+		price := doc["components"].(map[string]any)["schemas"].(map[string]any)["VariantResponse"].(map[string]any)["properties"].(map[string]any)["price"]
+		assert.Equal(t, "string", price.(map[string]any)["type"])
+		assert.Contains(t, price.(map[string]any), "description")
+	})
+
+	t.Run("rejects unknown versions", func(t *testing.T) {
+		_, err := NewHandler("v3")
+		assert.EqualError(t, err, `unknown API version "v3"`)
+	})
+}
@@ -0,0 +1,133 @@
+package outbox
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/mytheresa/go-hiring-challenge/app/config"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+const (
+	// claimLease is how long a claimed event is reserved for this dispatcher
+	// before another one may take it over.
+	claimLease = time.Minute
+
+	minBackoff = time.Second
+	maxBackoff = 5 * time.Minute
+
+	pruneInterval = time.Hour
+)
+
+// Dispatcher publishes the events written to the outbox. Failed events are
+// retried with exponential backoff and hold back the later events of their
+// aggregate meanwhile, so each aggregate's events are published in order.
+// An event that runs out of attempts is left dead and logged as an error,
+// and the later events of its aggregate are published without it.
+type Dispatcher struct {
+	repo         models.OutboxEventRepository
+	publisher    Publisher
+	batchSize    int
+	maxAttempts  int
+	pollInterval time.Duration
+	retention    time.Duration
+	logger       *slog.Logger
+}
+
+func NewDispatcher(repo models.OutboxEventRepository, publisher Publisher, cfg config.OutboxConfig, logger *slog.Logger) *Dispatcher {
+	return &Dispatcher{
+		repo:         repo,
+		publisher:    publisher,
+		batchSize:    cfg.BatchSize,
+		maxAttempts:  cfg.MaxAttempts,
+		pollInterval: cfg.PollInterval,
+		retention:    cfg.Retention,
+		logger:       logger,
+	}
+}
+
+// Run polls the outbox until ctx is done, publishing due events and
+// removing published and dead ones once they are older than the retention
+// period.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.pollInterval)
+	defer ticker.Stop()
+	var pruned time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.drain(ctx)
+			if time.Since(pruned) >= pruneInterval {
+				d.prune(ctx)
+				pruned = time.Now()
+			}
+		}
+	}
+}
+
+// drain dispatches batches until no event is due.
+func (d *Dispatcher) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		n, err := d.dispatch(ctx)
+		if err != nil {
+			d.logger.ErrorContext(ctx, "failed to claim outbox events", "error", err)
+			return
+		}
+		if n == 0 {
+			return
+		}
+	}
+}
+
+// dispatch publishes one batch of due events and returns how many it claimed.
+func (d *Dispatcher) dispatch(ctx context.Context) (int, error) {
+	events, err := d.repo.Claim(ctx, d.batchSize, claimLease)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, e := range events {
+		if err := d.publisher.Publish(ctx, newEvent(e)); err != nil {
+			var retryAt *time.Time
+			if e.Attempts < d.maxAttempts {
+				at := time.Now().Add(backoff(e.Attempts))
+				retryAt = &at
+				d.logger.WarnContext(ctx, "failed to publish outbox event",
+					"id", e.ID, "type", e.Type, "attempts", e.Attempts, "retry_at", at, "error", err)
+			} else {
+				d.logger.ErrorContext(ctx, "giving up on outbox event",
+					"id", e.ID, "type", e.Type, "aggregate_type", e.AggregateType, "aggregate_id", e.AggregateID,
+					"attempts", e.Attempts, "error", err)
+			}
+			if err := d.repo.MarkFailed(ctx, e.ID, retryAt, err.Error()); err != nil {
+				d.logger.ErrorContext(ctx, "failed to reschedule outbox event", "id", e.ID, "error", err)
+			}
+			continue
+		}
+		if err := d.repo.MarkPublished(ctx, e.ID); err != nil {
+			d.logger.ErrorContext(ctx, "failed to mark outbox event published", "id", e.ID, "error", err)
+		}
+	}
+	return len(events), nil
+}
+
+func (d *Dispatcher) prune(ctx context.Context) {
+	if n, err := d.repo.DeleteFinishedBefore(ctx, time.Now().Add(-d.retention)); err != nil {
+		d.logger.ErrorContext(ctx, "failed to delete finished outbox events", "error", err)
+	} else if n > 0 {
+		d.logger.DebugContext(ctx, "deleted finished outbox events", "count", n)
+	}
+}
+
+// backoff returns the delay before retrying an event that failed attempts
+// times, doubling from minBackoff up to maxBackoff.
+func backoff(attempts int) time.Duration {
+	delay := minBackoff
+	for i := 1; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, maxBackoff)
+}
@@ -0,0 +1,163 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/mytheresa/go-hiring-challenge/app/config"
+	"github.com/mytheresa/go-hiring-challenge/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockOutboxEventRepository struct {
+	mock.Mock
+}
+
+func (m *MockOutboxEventRepository) Claim(ctx context.Context, limit int, lease time.Duration) ([]models.OutboxEvent, error) {
+	args := m.Called(ctx, limit, lease)
+	return args.Get(0).([]models.OutboxEvent), args.Error(1)
+}
+
+func (m *MockOutboxEventRepository) MarkPublished(ctx context.Context, id uint) error {
+	return m.Called(ctx, id).Error(0)
+}
+
+func (m *MockOutboxEventRepository) MarkFailed(ctx context.Context, id uint, retryAt *time.Time, reason string) error {
+	return m.Called(ctx, id, retryAt, reason).Error(0)
+}
+
+func (m *MockOutboxEventRepository) DeleteFinishedBefore(ctx context.Context, t time.Time) (int64, error) {
+	args := m.Called(ctx, t)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// recordingPublisher keeps the published events and fails for the event IDs in failing.
+type recordingPublisher struct {
+	published []Event
+	failing   map[uint]bool
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, event Event) error {
+	if p.failing[event.ID] {
+		return errors.New("broker unavailable")
+	}
+	p.published = append(p.published, event)
+	return nil
+}
+
+var testConfig = config.OutboxConfig{PollInterval: time.Second, BatchSize: 2, MaxAttempts: 5, Retention: time.Hour}
+
+func newTestDispatcher(repo models.OutboxEventRepository, publisher Publisher) *Dispatcher {
+	return NewDispatcher(repo, publisher, testConfig, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestDispatcher_Dispatch(t *testing.T) {
+	t.Run("publishes claimed events and marks them published", func(t *testing.T) {
+		repo := new(MockOutboxEventRepository)
+		publisher := &recordingPublisher{}
+		occurred := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+		repo.On("Claim", mock.Anything, 2, claimLease).Return([]models.OutboxEvent{{
+			ID:            7,
+			Type:          models.EventProductPriceChanged,
+			AggregateType: models.AuditEntityProduct,
+			AggregateID:   "PROD001",
+			Payload:       json.RawMessage(`{"changes":{"price":{"before":"10.00","after":"12.00"}}}`),
+			CreatedAt:     occurred,
+			Attempts:      1,
+		}}, nil).Once()
+		repo.On("MarkPublished", mock.Anything, uint(7)).Return(nil).Once()
+
+		n, err := newTestDispatcher(repo, publisher).dispatch(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+		assert.Equal(t, []Event{{
+			ID:            7,
+			Type:          "product.price_changed",
+			AggregateType: "product",
+			AggregateID:   "PROD001",
+			OccurredAt:    occurred,
+			Payload:       json.RawMessage(`{"changes":{"price":{"before":"10.00","after":"12.00"}}}`),
+		}}, publisher.published)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("reschedules events that fail to publish", func(t *testing.T) {
+		repo := new(MockOutboxEventRepository)
+		publisher := &recordingPublisher{failing: map[uint]bool{1: true}}
+		repo.On("Claim", mock.Anything, 2, claimLease).Return([]models.OutboxEvent{
+			{ID: 1, AggregateID: "PROD001", Attempts: 3},
+			{ID: 2, AggregateID: "PROD002", Attempts: 1},
+		}, nil).Once()
+		before := time.Now()
+		repo.On("MarkFailed", mock.Anything, uint(1), mock.MatchedBy(func(retryAt *time.Time) bool {
+			return retryAt != nil && !retryAt.Before(before.Add(4*time.Second)) && retryAt.Before(time.Now().Add(5*time.Second))
+		}), "broker unavailable").Return(nil).Once()
+		repo.On("MarkPublished", mock.Anything, uint(2)).Return(nil).Once()
+
+		n, err := newTestDispatcher(repo, publisher).dispatch(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, 2, n)
+		require.Len(t, publisher.published, 1)
+		assert.Equal(t, uint(2), publisher.published[0].ID)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("gives up on events out of attempts", func(t *testing.T) {
+		repo := new(MockOutboxEventRepository)
+		publisher := &recordingPublisher{failing: map[uint]bool{1: true}}
+		repo.On("Claim", mock.Anything, 2, claimLease).Return([]models.OutboxEvent{
+			{ID: 1, AggregateID: "PROD001", Attempts: 5},
+		}, nil).Once()
+		repo.On("MarkFailed", mock.Anything, uint(1), (*time.Time)(nil), "broker unavailable").Return(nil).Once()
+
+		n, err := newTestDispatcher(repo, publisher).dispatch(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+		assert.Empty(t, publisher.published)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("returns claim errors", func(t *testing.T) {
+		repo := new(MockOutboxEventRepository)
+		repo.On("Claim", mock.Anything, 2, claimLease).Return([]models.OutboxEvent(nil), errors.New("connection refused")).Once()
+
+		_, err := newTestDispatcher(repo, &recordingPublisher{}).dispatch(context.Background())
+
+		assert.Error(t, err)
+	})
+}
+
+func TestDispatcher_Drain(t *testing.T) {
+	t.Run("claims batches until none is due", func(t *testing.T) {
+		repo := new(MockOutboxEventRepository)
+		publisher := &recordingPublisher{}
+		repo.On("Claim", mock.Anything, 2, claimLease).Return([]models.OutboxEvent{{ID: 1}, {ID: 2}}, nil).Once()
+		repo.On("Claim", mock.Anything, 2, claimLease).Return([]models.OutboxEvent{{ID: 3}}, nil).Once()
+		repo.On("Claim", mock.Anything, 2, claimLease).Return([]models.OutboxEvent{}, nil).Once()
+		repo.On("MarkPublished", mock.Anything, mock.Anything).Return(nil)
+
+		newTestDispatcher(repo, publisher).drain(context.Background())
+
+		assert.Len(t, publisher.published, 3)
+		repo.AssertExpectations(t)
+	})
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, time.Second, backoff(0))
+	assert.Equal(t, time.Second, backoff(1))
+	assert.Equal(t, 2*time.Second, backoff(2))
+	assert.Equal(t, 8*time.Second, backoff(4))
+	assert.Equal(t, maxBackoff, backoff(10))
+	assert.Equal(t, maxBackoff, backoff(1000))
+}
@@ -0,0 +1,80 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+// Event is the message published for an outbox event. AggregateID is the
+// product or category code, or the SKU for variants; events of one aggregate
+// are published in the order they happened.
+type Event struct {
+	ID            uint            `json:"id"`
+	Type          string          `json:"type"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+	Payload       json.RawMessage `json:"payload"`
+}
+
+func newEvent(e models.OutboxEvent) Event {
+	return Event{
+		ID:            e.ID,
+		Type:          e.Type,
+		AggregateType: e.AggregateType,
+		AggregateID:   e.AggregateID,
+		OccurredAt:    e.CreatedAt,
+		Payload:       e.Payload,
+	}
+}
+
+// Publisher delivers events to their consumers. A nil error means the event
+// was accepted and will not be offered again; an event may still be delivered
+// more than once if the dispatcher stops before recording that.
+type Publisher interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// Fanout publishes every event to each of its publishers. The event fails if
+// any of them fails, and is then offered to all of them again, so they must
+// tolerate duplicates.
+type Fanout []Publisher
+
+func (f Fanout) Publish(ctx context.Context, event Event) error {
+	var errs []error
+	for _, p := range f {
+		if err := p.Publish(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WriterPublisher writes each event as a line of JSON, for local use with
+// stdout or a file.
+type WriterPublisher struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewWriterPublisher(w io.Writer) *WriterPublisher {
+	return &WriterPublisher{w: w}
+}
+
+func (p *WriterPublisher) Publish(_ context.Context, event Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, err = p.w.Write(append(line, '\n'))
+	return err
+}
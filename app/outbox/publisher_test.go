@@ -0,0 +1,49 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriterPublisher_Publish(t *testing.T) {
+	var out bytes.Buffer
+	publisher := NewWriterPublisher(&out)
+
+	require.NoError(t, publisher.Publish(context.Background(), Event{
+		ID:            1,
+		Type:          "category.deleted",
+		AggregateType: "category",
+		AggregateID:   "shoes",
+		OccurredAt:    time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		Payload:       json.RawMessage(`{"state":{"name":"Shoes"}}`),
+	}))
+	require.NoError(t, publisher.Publish(context.Background(), Event{ID: 2, Payload: json.RawMessage(`{}`)}))
+
+	lines := bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2)
+	assert.JSONEq(t, `{
+		"id": 1,
+		"type": "category.deleted",
+		"aggregate_type": "category",
+		"aggregate_id": "shoes",
+		"occurred_at": "2024-03-01T12:00:00Z",
+		"payload": {"state": {"name": "Shoes"}}
+	}`, string(lines[0]))
+}
+
+func TestFanout_Publish(t *testing.T) {
+	first, second := &recordingPublisher{}, &recordingPublisher{failing: map[uint]bool{2: true}}
+	fanout := Fanout{first, second}
+
+	require.NoError(t, fanout.Publish(context.Background(), Event{ID: 1}))
+	assert.Error(t, fanout.Publish(context.Background(), Event{ID: 2}))
+
+	assert.Len(t, first.published, 2)
+	assert.Len(t, second.published, 1)
+}
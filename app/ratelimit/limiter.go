@@ -0,0 +1,104 @@
+package ratelimit
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mytheresa/go-hiring-challenge/app/api"
+	"github.com/mytheresa/go-hiring-challenge/app/auth"
+	"github.com/mytheresa/go-hiring-challenge/app/config"
+)
+
+// Limiter applies per-route token buckets to each client.
+type Limiter struct {
+	store             Store
+	enabled           bool
+	fallback          Limit
+	routes            map[string]Limit
+	trustForwardedFor bool
+	logger            *slog.Logger
+}
+
+// New creates a limiter backed by store. Routes without their own limit in
+// cfg.Routes share the default rate and burst.
+func New(store Store, cfg config.RateLimitConfig, logger *slog.Logger) *Limiter {
+	routes := make(map[string]Limit, len(cfg.Routes))
+	for pattern, route := range cfg.Routes {
+		routes[pattern] = Limit{Rate: route.Rate, Burst: route.Burst}
+	}
+	return &Limiter{
+		store:             store,
+		enabled:           cfg.Enabled,
+		fallback:          Limit{Rate: cfg.Rate, Burst: cfg.Burst},
+		routes:            routes,
+		trustForwardedFor: cfg.TrustForwardedFor,
+		logger:            logger,
+	}
+}
+
+// Wrap limits h by the route pattern the mux matched and the calling client:
+// the authenticated principal when there is one, the client IP otherwise.
+// All API versions of a route share a limit. It must run after
+// authentication so the principal is known.
+func (l *Limiter) Wrap(h http.HandlerFunc) http.HandlerFunc {
+	if !l.enabled {
+		return h
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		route := api.RoutePattern(r)
+		limit, ok := l.routes[route]
+		if !ok {
+			limit = l.fallback
+		}
+
+		result, err := l.store.Take(r.Context(), route+" "+l.client(r), limit)
+		if err != nil {
+			// Fail open: an unavailable shared backend must not take the API down with it.
+			l.logger.WarnContext(r.Context(), "rate limiter unavailable", "error", err)
+			h(w, r)
+			return
+		}
+
+		header := w.Header()
+		header.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+		header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		header.Set("RateLimit-Reset", ceilSeconds(result.Reset))
+		header.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%s", limit.Burst, ceilSeconds(seconds(float64(limit.Burst)/limit.Rate))))
+
+		if !result.Allowed {
+			header.Set("Retry-After", ceilSeconds(result.RetryAfter))
+			api.ErrorResponse(w, r, http.StatusTooManyRequests, api.CodeRateLimited,
+				fmt.Sprintf("rate limit of %d requests exceeded, retry in %s seconds", result.Limit, ceilSeconds(result.RetryAfter)))
+			return
+		}
+		h(w, r)
+	}
+}
+
+func (l *Limiter) client(r *http.Request) string {
+	if p := auth.PrincipalFromContext(r.Context()); p != nil {
+		return "principal:" + p.Subject
+	}
+	if l.trustForwardedFor {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			first, _, _ := strings.Cut(forwarded, ",")
+			return "ip:" + strings.TrimSpace(first)
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// ceilSeconds formats d as whole seconds, rounded up and at least 1 when d is positive.
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,135 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mytheresa/go-hiring-challenge/app/auth"
+	"github.com/mytheresa/go-hiring-challenge/app/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockStore struct {
+	mock.Mock
+}
+
+func (m *MockStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	args := m.Called(ctx, key, limit)
+	return args.Get(0).(Result), args.Error(1)
+}
+
+func TestLimiter_Wrap(t *testing.T) {
+	cfg := config.RateLimitConfig{
+		Enabled: true,
+		Rate:    10,
+		Burst:   20,
+		Routes:  map[string]config.RouteLimit{"GET /catalog": {Rate: 1, Burst: 1}},
+	}
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+
+	serve := func(l *Limiter, pattern string, req *http.Request) *httptest.ResponseRecorder {
+		mux := http.NewServeMux()
+		mux.HandleFunc(pattern, l.Wrap(ok))
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	t.Run("returns 429 with Retry-After once the route limit is exhausted", func(t *testing.T) {
+		limiter := New(NewMemoryStore(), cfg, slog.Default())
+
+		first := serve(limiter, "GET /catalog", httptest.NewRequest("GET", "/catalog", nil))
+		second := serve(limiter, "GET /catalog", httptest.NewRequest("GET", "/catalog", nil))
+
+		assert.Equal(t, http.StatusOK, first.Code)
+		assert.Equal(t, "1", first.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, "0", first.Header().Get("RateLimit-Remaining"))
+		assert.Equal(t, "1", first.Header().Get("RateLimit-Reset"))
+		assert.Equal(t, "1;w=1", first.Header().Get("RateLimit-Policy"))
+
+		assert.Equal(t, http.StatusTooManyRequests, second.Code)
+		assert.Equal(t, "1", second.Header().Get("Retry-After"))
+		assert.Equal(t, "application/problem+json", second.Header().Get("Content-Type"))
+		assert.Contains(t, second.Body.String(), `"code":"rate_limited"`)
+	})
+
+	t.Run("shares the route limit between API versions", func(t *testing.T) {
+		limiter := New(NewMemoryStore(), cfg, slog.Default())
+
+		first := serve(limiter, "GET /v1/catalog", httptest.NewRequest("GET", "/v1/catalog", nil))
+		second := serve(limiter, "GET /v2/catalog", httptest.NewRequest("GET", "/v2/catalog", nil))
+
+		assert.Equal(t, "1", first.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, http.StatusTooManyRequests, second.Code)
+	})
+
+	t.Run("uses the default limit for other routes", func(t *testing.T) {
+		limiter := New(NewMemoryStore(), cfg, slog.Default())
+
+		recorder := serve(limiter, "GET /categories", httptest.NewRequest("GET", "/categories", nil))
+
+		assert.Equal(t, "20", recorder.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, "19", recorder.Header().Get("RateLimit-Remaining"))
+	})
+
+	t.Run("keys by principal when authenticated and by client IP otherwise", func(t *testing.T) {
+		store := new(MockStore)
+		store.On("Take", mock.Anything, "GET /catalog principal:api-key:ci", Limit{Rate: 1, Burst: 1}).
+			Return(Result{Allowed: true, Limit: 1}, nil).Once()
+		store.On("Take", mock.Anything, "GET /catalog ip:203.0.113.7", Limit{Rate: 1, Burst: 1}).
+			Return(Result{Allowed: true, Limit: 1}, nil).Once()
+		limiter := New(store, cfg, slog.Default())
+
+		authenticated := httptest.NewRequest("GET", "/catalog", nil)
+		authenticated = authenticated.WithContext(auth.WithPrincipal(authenticated.Context(), &auth.Principal{Subject: "api-key:ci"}))
+		serve(limiter, "GET /catalog", authenticated)
+
+		anonymous := httptest.NewRequest("GET", "/catalog", nil)
+		anonymous.RemoteAddr = "203.0.113.7:51234"
+		anonymous.Header.Set("X-Forwarded-For", "198.51.100.1")
+		serve(limiter, "GET /catalog", anonymous)
+
+		store.AssertExpectations(t)
+	})
+
+	t.Run("trusts X-Forwarded-For only when configured", func(t *testing.T) {
+		store := new(MockStore)
+		store.On("Take", mock.Anything, "GET /catalog ip:198.51.100.1", mock.Anything).
+			Return(Result{Allowed: true, Limit: 1}, nil).Once()
+		trusting := cfg
+		trusting.TrustForwardedFor = true
+		limiter := New(store, trusting, slog.Default())
+
+		req := httptest.NewRequest("GET", "/catalog", nil)
+		req.Header.Set("X-Forwarded-For", "198.51.100.1, 10.0.0.1")
+		serve(limiter, "GET /catalog", req)
+
+		store.AssertExpectations(t)
+	})
+
+	t.Run("fails open when the store is unavailable", func(t *testing.T) {
+		store := new(MockStore)
+		store.On("Take", mock.Anything, mock.Anything, mock.Anything).Return(Result{}, errors.New("connection refused"))
+		limiter := New(store, cfg, slog.Default())
+
+		recorder := serve(limiter, "GET /catalog", httptest.NewRequest("GET", "/catalog", nil))
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Empty(t, recorder.Header().Get("RateLimit-Limit"))
+	})
+
+	t.Run("does nothing when disabled", func(t *testing.T) {
+		disabled := cfg
+		disabled.Enabled = false
+		limiter := New(new(MockStore), disabled, slog.Default())
+
+		recorder := serve(limiter, "GET /catalog", httptest.NewRequest("GET", "/catalog", nil))
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	})
+}
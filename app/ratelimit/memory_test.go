@@ -0,0 +1,69 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore_Take(t *testing.T) {
+	limit := Limit{Rate: 1, Burst: 2}
+	newStore := func(now *time.Time) *MemoryStore {
+		store := NewMemoryStore()
+		store.now = func() time.Time { return *now }
+		return store
+	}
+
+	t.Run("allows a burst and then rejects until a token refills", func(t *testing.T) {
+		now := time.Unix(1000, 0)
+		store := newStore(&now)
+
+		for remaining := 1; remaining >= 0; remaining-- {
+			result, err := store.Take(context.Background(), "client", limit)
+			require.NoError(t, err)
+			assert.True(t, result.Allowed)
+			assert.Equal(t, remaining, result.Remaining)
+		}
+
+		result, err := store.Take(context.Background(), "client", limit)
+		require.NoError(t, err)
+		assert.False(t, result.Allowed)
+		assert.Equal(t, 2, result.Limit)
+		assert.Equal(t, time.Second, result.RetryAfter)
+		assert.Equal(t, 2*time.Second, result.Reset)
+
+		now = now.Add(time.Second)
+		result, err = store.Take(context.Background(), "client", limit)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+	})
+
+	t.Run("keeps separate buckets per key", func(t *testing.T) {
+		now := time.Unix(1000, 0)
+		store := newStore(&now)
+
+		for range 2 {
+			store.Take(context.Background(), "noisy", limit)
+		}
+		noisy, _ := store.Take(context.Background(), "noisy", limit)
+		quiet, _ := store.Take(context.Background(), "quiet", limit)
+
+		assert.False(t, noisy.Allowed)
+		assert.True(t, quiet.Allowed)
+	})
+
+	t.Run("drops buckets that have refilled", func(t *testing.T) {
+		now := time.Unix(1000, 0)
+		store := newStore(&now)
+		store.Take(context.Background(), "idle", limit)
+
+		now = now.Add(2 * sweepInterval)
+		store.Take(context.Background(), "active", limit)
+
+		assert.NotContains(t, store.buckets, "idle")
+		assert.Contains(t, store.buckets, "active")
+	})
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/mytheresa/go-hiring-challenge/app/api"
+	"github.com/mytheresa/go-hiring-challenge/app/config"
+)
+
+// Server wraps http.Server with bounded timeouts and a graceful drain on shutdown.
+type Server struct {
+	srv             *http.Server
+	shutdownDelay   time.Duration
+	shutdownTimeout time.Duration
+	draining        atomic.Bool
+	closing         atomic.Bool
+}
+
+func New(cfg config.HTTPConfig, handler http.Handler) *Server {
+	s := &Server{shutdownDelay: cfg.ShutdownDelay, shutdownTimeout: cfg.ShutdownTimeout}
+	s.srv = &http.Server{
+		Addr:              cfg.Addr(),
+		Handler:           s.refuseWhileDraining(api.LimitBody(cfg.MaxBodyBytes)(handler)),
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+	}
+	return s
+}
+
+// Addr returns the configured listen address.
+func (s *Server) Addr() string {
+	return s.srv.Addr
+}
+
+// Draining reports whether the server has started shutting down.
+func (s *Server) Draining() bool {
+	return s.draining.Load()
+}
+
+// Run listens on the configured address and serves until ctx is cancelled.
+func (s *Server) Run(ctx context.Context) error {
+	ln, err := net.Listen("tcp", s.srv.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, ln)
+}
+
+// Serve accepts connections on ln until ctx is cancelled. It then reports
+// draining for the shutdown delay while still serving, so load balancers see
+// readiness fail before connections are refused, and finally stops accepting
+// new work and waits up to the shutdown timeout for in-flight requests to finish.
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("shutting down server", "delay", s.shutdownDelay)
+	s.draining.Store(true)
+	select {
+	case err := <-errCh:
+		return err
+	case <-time.After(s.shutdownDelay):
+	}
+
+	s.closing.Store(true)
+	s.srv.SetKeepAlivesEnabled(false)
+
+	// The signal context is already cancelled, so drain against a fresh deadline.
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+
+	if err := s.srv.Shutdown(shutdownCtx); err != nil {
+		s.srv.Close()
+		return fmt.Errorf("draining in-flight requests: %w", err)
+	}
+
+	if err := <-errCh; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	slog.Info("server stopped gracefully")
+	return nil
+}
+
+// probes are the health routes, which keep answering while the server
+// drains: liveness stays up so the orchestrator lets in-flight requests
+// finish, and readiness reports the drain itself.
+var probes = map[string]bool{"/healthz": true, "/readyz": true}
+
+// refuseWhileDraining rejects requests that arrive on kept-alive connections
+// once the server stopped accepting connections, asking the client to retry
+// elsewhere. Health probes are let through.
+func (s *Server) refuseWhileDraining(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.closing.Load() && !probes[r.URL.Path] {
+			w.Header().Set("Connection", "close")
+			w.Header().Set("Retry-After", "1")
+			api.ErrorResponse(w, r, http.StatusServiceUnavailable, api.CodeShuttingDown, "server is shutting down")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mytheresa/go-hiring-challenge/app/config"
+	"github.com/mytheresa/go-hiring-challenge/app/health"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testConfig() config.HTTPConfig {
+	cfg := config.Default().HTTP
+	cfg.ShutdownDelay = 0
+	cfg.ShutdownTimeout = 2 * time.Second
+	cfg.MaxBodyBytes = 8
+	return cfg
+}
+
+func TestServer_Serve(t *testing.T) {
+	t.Run("drains in-flight requests on shutdown", func(t *testing.T) {
+		started := make(chan struct{})
+		release := make(chan struct{})
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-release
+			w.Write([]byte("done"))
+		})
+
+		srv := New(testConfig(), handler)
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		served := make(chan error, 1)
+		go func() { served <- srv.Serve(ctx, ln) }()
+
+		respCh := make(chan *http.Response, 1)
+		go func() {
+			resp, err := http.Get("http://" + ln.Addr().String())
+			if err == nil {
+				respCh <- resp
+			}
+			close(respCh)
+		}()
+
+		<-started
+		cancel()
+		assert.Eventually(t, srv.Draining, time.Second, 10*time.Millisecond)
+		close(release)
+
+		resp, ok := <-respCh
+		require.True(t, ok, "in-flight request should complete")
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "done", string(body))
+		assert.NoError(t, <-served)
+	})
+
+	t.Run("keeps serving and reports draining during the shutdown delay", func(t *testing.T) {
+		var srv *Server
+		healthHandler := health.NewHandler(func() bool { return srv.Draining() }, time.Second)
+		mux := http.NewServeMux()
+		mux.HandleFunc("GET /readyz", healthHandler.HandleReadiness)
+		mux.HandleFunc("GET /catalog", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("catalog")) })
+		cfg := testConfig()
+		cfg.ShutdownDelay = 500 * time.Millisecond
+		srv = New(cfg, mux)
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		served := make(chan error, 1)
+		go func() { served <- srv.Serve(ctx, ln) }()
+		cancel()
+		require.Eventually(t, srv.Draining, time.Second, 10*time.Millisecond)
+
+		readiness, err := http.Get("http://" + ln.Addr().String() + "/readyz")
+		require.NoError(t, err, "readiness should be answered during the shutdown delay")
+		body, _ := io.ReadAll(readiness.Body)
+		readiness.Body.Close()
+		assert.Equal(t, http.StatusServiceUnavailable, readiness.StatusCode)
+		assert.Contains(t, string(body), `"status":"draining"`)
+
+		catalog, err := http.Get("http://" + ln.Addr().String() + "/catalog")
+		require.NoError(t, err)
+		catalog.Body.Close()
+		assert.Equal(t, http.StatusOK, catalog.StatusCode)
+
+		assert.NoError(t, <-served)
+		_, err = net.Dial("tcp", ln.Addr().String())
+		assert.Error(t, err, "connections should be refused after the shutdown delay")
+	})
+
+	t.Run("refuses new requests once it stops accepting connections", func(t *testing.T) {
+		srv := New(testConfig(), http.NotFoundHandler())
+		srv.closing.Store(true)
+
+		recorder := httptest.NewRecorder()
+		srv.srv.Handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/catalog", nil))
+
+		assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+		assert.Equal(t, "close", recorder.Header().Get("Connection"))
+	})
+
+	t.Run("lets health probes through while draining", func(t *testing.T) {
+		var srv *Server
+		healthHandler := health.NewHandler(func() bool { return srv.Draining() }, time.Second)
+		mux := http.NewServeMux()
+		mux.HandleFunc("GET /healthz", healthHandler.HandleLiveness)
+		mux.HandleFunc("GET /readyz", healthHandler.HandleReadiness)
+		srv = New(testConfig(), mux)
+		srv.draining.Store(true)
+		srv.closing.Store(true)
+
+		liveness := httptest.NewRecorder()
+		srv.srv.Handler.ServeHTTP(liveness, httptest.NewRequest("GET", "/healthz", nil))
+		readiness := httptest.NewRecorder()
+		srv.srv.Handler.ServeHTTP(readiness, httptest.NewRequest("GET", "/readyz", nil))
+
+		assert.Equal(t, http.StatusOK, liveness.Code)
+		assert.Equal(t, http.StatusServiceUnavailable, readiness.Code)
+		assert.Contains(t, readiness.Body.String(), `"status":"draining"`)
+	})
+
+	t.Run("limits request bodies", func(t *testing.T) {
+		var readErr error
+		srv := New(testConfig(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, readErr = io.ReadAll(r.Body)
+		}))
+
+		req := httptest.NewRequest("POST", "/categories", strings.NewReader(`{"code":"too-long"}`))
+		srv.srv.Handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		var maxBytesErr *http.MaxBytesError
+		assert.ErrorAs(t, readErr, &maxBytesErr)
+	})
+}
@@ -0,0 +1,107 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mytheresa/go-hiring-challenge/app/api"
+	"github.com/mytheresa/go-hiring-challenge/app/config"
+	"github.com/mytheresa/go-hiring-challenge/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func setupRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func TestMiddleware(t *testing.T) {
+	spans := setupRecorder(t)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /catalog/{code}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	h := api.Chain(mux, api.AssignRequestID(), Middleware())
+
+	req := httptest.NewRequest("GET", "/catalog/PROD001", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	ended := spans.Ended()
+	require.Len(t, ended, 1)
+	span := ended[0]
+	assert.Equal(t, "GET /catalog/{code}", span.Name())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", span.Parent().SpanID().String())
+	assert.Equal(t, codes.Error, span.Status().Code)
+
+	attrs := map[string]any{}
+	for _, kv := range span.Attributes() {
+		attrs[string(kv.Key)] = kv.Value.AsInterface()
+	}
+	assert.Equal(t, "GET /catalog/{code}", attrs["http.route"])
+	assert.EqualValues(t, http.StatusServiceUnavailable, attrs["http.response.status_code"])
+	assert.NotEmpty(t, attrs["http.request.id"])
+}
+
+func TestInstrumentGORM(t *testing.T) {
+	spans := setupRecorder(t)
+
+	// DryRun builds statements without a live database, which is enough to fire callbacks.
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+	})
+	require.NoError(t, err)
+	require.NoError(t, InstrumentGORM(db))
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "GET /catalog")
+	var products []models.Product
+	db.WithContext(ctx).Where("code = ?", "PROD001").Find(&products)
+	parent.End()
+
+	ended := spans.Ended()
+	require.Len(t, ended, 2)
+	query := ended[0]
+	assert.Equal(t, "query products", query.Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), query.Parent().SpanID())
+
+	attrs := map[string]any{}
+	for _, kv := range query.Attributes() {
+		attrs[string(kv.Key)] = kv.Value.AsInterface()
+	}
+	assert.Equal(t, "postgresql", attrs["db.system"])
+	assert.Equal(t, "products", attrs["db.collection.name"])
+	assert.Contains(t, attrs["db.query.text"], `WHERE code = $1`)
+}
+
+func TestSetup(t *testing.T) {
+	t.Run("none installs no exporter", func(t *testing.T) {
+		shutdown, err := Setup(context.Background(), config.TracingConfig{Exporter: ExporterNone})
+		require.NoError(t, err)
+		assert.NoError(t, shutdown(context.Background()))
+	})
+
+	t.Run("rejects unknown exporters", func(t *testing.T) {
+		_, err := Setup(context.Background(), config.TracingConfig{Exporter: "zipkin"})
+		assert.Error(t, err)
+	})
+}
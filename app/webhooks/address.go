@@ -0,0 +1,55 @@
+package webhooks
+
+import (
+	"fmt"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+
+	"github.com/mytheresa/go-hiring-challenge/app/api"
+)
+
+// sharedAddressSpace is the carrier-grade NAT range, which is not routable
+// on the internet either.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// publicAddr reports whether deliveries may be sent to addr. Loopback,
+// private, link-local (which includes cloud metadata endpoints), multicast
+// and unspecified addresses would let a subscription reach internal services.
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() && !addr.IsPrivate() && !sharedAddressSpace.Contains(addr)
+}
+
+// publicURL rejects URLs whose host is an internal address or names the
+// local machine. Other names are checked when deliveries are sent, since
+// what they resolve to can change.
+func publicURL() api.Rule {
+	return func(value string) *api.FieldError {
+		u, err := url.Parse(value)
+		if err != nil {
+			return nil
+		}
+		host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+		addr, err := netip.ParseAddr(host)
+		internal := err == nil && !publicAddr(addr)
+		if internal || host == "localhost" || strings.HasSuffix(host, ".localhost") {
+			return &api.FieldError{Code: "format", Message: "must not point to a loopback, link-local or private address"}
+		}
+		return nil
+	}
+}
+
+// refuseInternal is the dialer's Control hook. It sees the address after
+// name resolution, so it also stops names that resolve to internal addresses.
+func refuseInternal(_, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !publicAddr(addrPort.Addr()) {
+		return fmt.Errorf("webhook address %s is not public", addrPort.Addr())
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package webhooks
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublicAddr(t *testing.T) {
+	for _, addr := range []string{"93.184.216.34", "2606:2800:220:1:248:1893:25c8:1946"} {
+		assert.True(t, publicAddr(netip.MustParseAddr(addr)), addr)
+	}
+	for _, addr := range []string{
+		"127.0.0.1", "::1", "0.0.0.0", "10.1.2.3", "172.16.0.1", "192.168.1.1",
+		"169.254.169.254", "100.64.0.1", "fd00::1", "fe80::1", "224.0.0.1", "::ffff:127.0.0.1",
+	} {
+		assert.False(t, publicAddr(netip.MustParseAddr(addr)), addr)
+	}
+}
+
+func TestPublicURL(t *testing.T) {
+	rule := publicURL()
+	for _, url := range []string{"https://partner.example.com/hooks", "http://93.184.216.34:8080/hooks"} {
+		assert.Nil(t, rule(url), url)
+	}
+	for _, url := range []string{
+		"http://localhost:8080/hooks", "http://api.localhost/hooks", "http://127.0.0.1/hooks",
+		"http://169.254.169.254/latest/meta-data", "http://[::1]/hooks", "http://10.0.0.5/hooks",
+	} {
+		assert.NotNil(t, rule(url), url)
+	}
+}
@@ -0,0 +1,212 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mytheresa/go-hiring-challenge/app/config"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+// Headers of every delivery. The signature is the hex HMAC-SHA256 of the
+// timestamp, a dot and the body, keyed with the subscription secret.
+const (
+	DeliveryHeader  = "X-Webhook-Delivery"
+	EventHeader     = "X-Webhook-Event"
+	TimestampHeader = "X-Webhook-Timestamp"
+	SignatureHeader = "X-Webhook-Signature"
+)
+
+const (
+	minBackoff = 10 * time.Second
+	maxBackoff = time.Hour
+
+	pruneInterval = time.Hour
+)
+
+// Sign returns the signature header value for body sent at timestamp.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte{'.'})
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Deliverer POSTs pending deliveries to their subscriptions. Any 2xx response
+// accepts a delivery. Failed deliveries are retried with exponential backoff
+// and hold back the later deliveries of their aggregate to the same
+// subscription, until they run out of attempts and are left dead.
+type Deliverer struct {
+	repo         models.WebhookDeliveryRepository
+	client       *http.Client
+	lease        time.Duration
+	pollInterval time.Duration
+	batchSize    int
+	maxAttempts  int
+	retention    time.Duration
+	logger       *slog.Logger
+}
+
+func NewDeliverer(repo models.WebhookDeliveryRepository, cfg config.WebhooksConfig, logger *slog.Logger) *Deliverer {
+	return &Deliverer{
+		repo: repo,
+		client: &http.Client{
+			Transport: transport(),
+			Timeout:   cfg.Timeout,
+			// Redirects are not followed; a subscription must name its final URL.
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		lease:        cfg.Timeout + time.Minute,
+		pollInterval: cfg.PollInterval,
+		batchSize:    cfg.BatchSize,
+		maxAttempts:  cfg.MaxAttempts,
+		retention:    cfg.Retention,
+		logger:       logger,
+	}
+}
+
+// transport connects to subscriptions directly, without a proxy, and only to
+// public addresses.
+func transport() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = nil
+	t.DialContext = (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: refuseInternal}).DialContext
+	return t
+}
+
+// Run sends due deliveries until ctx is done, and removes finished ones once
+// they are older than the retention period.
+func (d *Deliverer) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.pollInterval)
+	defer ticker.Stop()
+	var pruned time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.drain(ctx)
+			if time.Since(pruned) >= pruneInterval {
+				d.prune(ctx)
+				pruned = time.Now()
+			}
+		}
+	}
+}
+
+// drain sends batches until no delivery is due.
+func (d *Deliverer) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		n, err := d.deliver(ctx)
+		if err != nil {
+			d.logger.ErrorContext(ctx, "failed to claim webhook deliveries", "error", err)
+			return
+		}
+		if n == 0 {
+			return
+		}
+	}
+}
+
+// deliver sends one batch of due deliveries concurrently and returns how many it claimed.
+func (d *Deliverer) deliver(ctx context.Context) (int, error) {
+	deliveries, err := d.repo.Claim(ctx, d.batchSize, d.lease)
+	if err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	for _, delivery := range deliveries {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.send(ctx, delivery)
+		}()
+	}
+	wg.Wait()
+	return len(deliveries), nil
+}
+
+// send makes one attempt at delivery and records its outcome.
+func (d *Deliverer) send(ctx context.Context, delivery models.WebhookDelivery) {
+	status, err := d.post(ctx, delivery)
+	if err == nil {
+		if err := d.repo.MarkDelivered(ctx, delivery.ID, status); err != nil {
+			d.logger.ErrorContext(ctx, "failed to mark webhook delivery delivered", "id", delivery.ID, "error", err)
+		}
+		return
+	}
+
+	var retryAt *time.Time
+	if delivery.Attempts < d.maxAttempts {
+		at := time.Now().Add(backoff(delivery.Attempts))
+		retryAt = &at
+	}
+	d.logger.WarnContext(ctx, "failed to deliver webhook",
+		"id", delivery.ID, "subscription", delivery.SubscriptionID, "attempts", delivery.Attempts, "dead", retryAt == nil, "error", err)
+	if err := d.repo.MarkFailed(ctx, delivery.ID, retryAt, status, err.Error()); err != nil {
+		d.logger.ErrorContext(ctx, "failed to record webhook delivery failure", "id", delivery.ID, "error", err)
+	}
+}
+
+// post sends the signed payload and returns the response status, which is
+// zero when there was no response.
+func (d *Deliverer) post(ctx context.Context, delivery models.WebhookDelivery) (int, error) {
+	subscription := delivery.Subscription
+	if subscription == nil {
+		return 0, fmt.Errorf("subscription %d not found", delivery.SubscriptionID)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(DeliveryHeader, strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set(EventHeader, delivery.EventType)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(subscription.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+func (d *Deliverer) prune(ctx context.Context) {
+	if n, err := d.repo.DeleteFinishedBefore(ctx, time.Now().Add(-d.retention)); err != nil {
+		d.logger.ErrorContext(ctx, "failed to delete finished webhook deliveries", "error", err)
+	} else if n > 0 {
+		d.logger.DebugContext(ctx, "deleted finished webhook deliveries", "count", n)
+	}
+}
+
+// backoff returns the delay before retrying a delivery that failed attempts
+// times, doubling from minBackoff up to maxBackoff.
+func backoff(attempts int) time.Duration {
+	delay := minBackoff
+	for i := 1; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, maxBackoff)
+}
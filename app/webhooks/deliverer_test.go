@@ -0,0 +1,170 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mytheresa/go-hiring-challenge/app/config"
+	"github.com/mytheresa/go-hiring-challenge/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var testConfig = config.WebhooksConfig{Timeout: time.Second, PollInterval: time.Second, BatchSize: 5, MaxAttempts: 3, Retention: time.Hour}
+
+// newTestDeliverer returns a deliverer that may reach the test receivers,
+// which listen on loopback.
+func newTestDeliverer(repo models.WebhookDeliveryRepository) *Deliverer {
+	d := NewDeliverer(repo, testConfig, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	d.client.Transport = http.DefaultTransport
+	return d
+}
+
+func testDelivery(url string, attempts int) models.WebhookDelivery {
+	return models.WebhookDelivery{
+		ID:             9,
+		SubscriptionID: 4,
+		EventType:      models.EventCategoryDeleted,
+		Payload:        json.RawMessage(`{"id":31,"type":"category.deleted"}`),
+		Attempts:       attempts,
+		Subscription:   &models.WebhookSubscription{ID: 4, URL: url, Secret: "0123456789abcdef"},
+	}
+}
+
+func TestDeliverer_Deliver(t *testing.T) {
+	t.Run("posts signed payloads and marks them delivered", func(t *testing.T) {
+		var received *http.Request
+		var body []byte
+		receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r
+			body, _ = io.ReadAll(r.Body)
+			w.WriteHeader(http.StatusAccepted)
+		}))
+		defer receiver.Close()
+
+		repo := new(MockWebhookDeliveryRepository)
+		repo.On("Claim", mock.Anything, 5, 61*time.Second).Return([]models.WebhookDelivery{testDelivery(receiver.URL, 1)}, nil)
+		repo.On("MarkDelivered", mock.Anything, uint(9), http.StatusAccepted).Return(nil)
+
+		n, err := newTestDeliverer(repo).deliver(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+		require.NotNil(t, received)
+		assert.Equal(t, "POST", received.Method)
+		assert.JSONEq(t, `{"id":31,"type":"category.deleted"}`, string(body))
+		assert.Equal(t, "application/json", received.Header.Get("Content-Type"))
+		assert.Equal(t, "9", received.Header.Get(DeliveryHeader))
+		assert.Equal(t, "category.deleted", received.Header.Get(EventHeader))
+		timestamp, err := strconv.ParseInt(received.Header.Get(TimestampHeader), 10, 64)
+		require.NoError(t, err)
+		assert.Equal(t, Sign("0123456789abcdef", timestamp, body), received.Header.Get(SignatureHeader))
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("schedules a retry after a failed attempt", func(t *testing.T) {
+		receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer receiver.Close()
+
+		repo := new(MockWebhookDeliveryRepository)
+		repo.On("Claim", mock.Anything, 5, mock.Anything).Return([]models.WebhookDelivery{testDelivery(receiver.URL, 2)}, nil)
+		before := time.Now()
+		repo.On("MarkFailed", mock.Anything, uint(9), mock.MatchedBy(func(retryAt *time.Time) bool {
+			return retryAt != nil && !retryAt.Before(before.Add(20*time.Second)) && retryAt.Before(time.Now().Add(21*time.Second))
+		}), http.StatusServiceUnavailable, "unexpected response status 503").Return(nil)
+
+		_, err := newTestDeliverer(repo).deliver(context.Background())
+
+		require.NoError(t, err)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("gives up after the last attempt", func(t *testing.T) {
+		receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer receiver.Close()
+
+		repo := new(MockWebhookDeliveryRepository)
+		repo.On("Claim", mock.Anything, 5, mock.Anything).Return([]models.WebhookDelivery{testDelivery(receiver.URL, 3)}, nil)
+		repo.On("MarkFailed", mock.Anything, uint(9), (*time.Time)(nil), http.StatusInternalServerError, "unexpected response status 500").Return(nil)
+
+		_, err := newTestDeliverer(repo).deliver(context.Background())
+
+		require.NoError(t, err)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("does not follow redirects", func(t *testing.T) {
+		receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, "/elsewhere", http.StatusFound)
+		}))
+		defer receiver.Close()
+
+		repo := new(MockWebhookDeliveryRepository)
+		repo.On("Claim", mock.Anything, 5, mock.Anything).Return([]models.WebhookDelivery{testDelivery(receiver.URL, 1)}, nil)
+		repo.On("MarkFailed", mock.Anything, uint(9), mock.Anything, http.StatusFound, "unexpected response status 302").Return(nil)
+
+		_, err := newTestDeliverer(repo).deliver(context.Background())
+
+		require.NoError(t, err)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("records unreachable receivers without a status", func(t *testing.T) {
+		receiver := httptest.NewServer(http.NotFoundHandler())
+		url := receiver.URL
+		receiver.Close()
+
+		repo := new(MockWebhookDeliveryRepository)
+		repo.On("Claim", mock.Anything, 5, mock.Anything).Return([]models.WebhookDelivery{testDelivery(url, 1)}, nil)
+		repo.On("MarkFailed", mock.Anything, uint(9), mock.Anything, 0, mock.Anything).Return(nil)
+
+		_, err := newTestDeliverer(repo).deliver(context.Background())
+
+		require.NoError(t, err)
+		repo.AssertExpectations(t)
+	})
+}
+
+func TestDeliverer_RefusesInternalAddresses(t *testing.T) {
+	var called bool
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer receiver.Close()
+
+	repo := new(MockWebhookDeliveryRepository)
+	repo.On("Claim", mock.Anything, 5, mock.Anything).Return([]models.WebhookDelivery{testDelivery(receiver.URL, 1)}, nil)
+	repo.On("MarkFailed", mock.Anything, uint(9), mock.Anything, 0, mock.MatchedBy(func(reason string) bool {
+		return strings.Contains(reason, "webhook address 127.0.0.1 is not public")
+	})).Return(nil)
+
+	_, err := NewDeliverer(repo, testConfig, slog.New(slog.NewTextHandler(io.Discard, nil))).deliver(context.Background())
+
+	require.NoError(t, err)
+	assert.False(t, called)
+	repo.AssertExpectations(t)
+}
+
+func TestSign(t *testing.T) {
+	// HMAC-SHA256 of "1700000000.{}" keyed with "secret"
+	assert.Equal(t, "sha256=b8569b78799ff9e3cbff0fc2d63a33a2b57f3282abd07c37ae5e8e7d79a5f163", Sign("secret", 1700000000, []byte("{}")))
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, 10*time.Second, backoff(1))
+	assert.Equal(t, 40*time.Second, backoff(3))
+	assert.Equal(t, maxBackoff, backoff(20))
+}
@@ -0,0 +1,355 @@
+package webhooks
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mytheresa/go-hiring-challenge/app/api"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+// SubscriptionResponse describes a subscription. The secret is only returned
+// when the subscription is created.
+type SubscriptionResponse struct {
+	ID         uint      `json:"id"`
+	URL        string    `json:"url"`
+	EventTypes []string  `json:"event_types"`
+	Active     bool      `json:"active"`
+	Secret     string    `json:"secret,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type DeliveriesResponse struct {
+	Deliveries []DeliveryResponse `json:"deliveries"`
+	Total      int64              `json:"total"`
+}
+
+type DeliveryResponse struct {
+	ID             uint            `json:"id"`
+	EventID        uint            `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	AggregateType  string          `json:"aggregate_type"`
+	AggregateID    string          `json:"aggregate_id"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	ResponseStatus int             `json:"response_status,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	Payload        json.RawMessage `json:"payload"`
+}
+
+type CreateSubscriptionRequest struct {
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	// Secret signs the deliveries; a random one is generated when it is empty.
+	Secret string `json:"secret"`
+	Active *bool  `json:"active"`
+}
+
+// Validate checks the request against the limits of the webhook_subscriptions table.
+func (req CreateSubscriptionRequest) Validate() error {
+	var v api.Validator
+	v.Field("url", req.URL, api.Required(), api.Length(1, 2048), api.HTTPURL(), publicURL())
+	validateEventTypes(&v, req.EventTypes)
+	v.Field("secret", req.Secret, api.Length(16, 255))
+	return v.Err()
+}
+
+type UpdateSubscriptionRequest struct {
+	URL        *string   `json:"url"`
+	EventTypes *[]string `json:"event_types"`
+	Secret     *string   `json:"secret"`
+	Active     *bool     `json:"active"`
+}
+
+// Validate checks the fields the request sets.
+func (req UpdateSubscriptionRequest) Validate() error {
+	var v api.Validator
+	if req.URL != nil {
+		v.Field("url", *req.URL, api.Required(), api.Length(1, 2048), api.HTTPURL(), publicURL())
+	}
+	if req.EventTypes != nil {
+		validateEventTypes(&v, *req.EventTypes)
+	}
+	if req.Secret != nil {
+		v.Field("secret", *req.Secret, api.Required(), api.Length(16, 255))
+	}
+	return v.Err()
+}
+
+func validateEventTypes(v *api.Validator, eventTypes []string) {
+	if len(eventTypes) == 0 {
+		v.Field("event_types", "", api.Required())
+		return
+	}
+	allowed := append([]string{models.AllEvents}, models.EventTypes...)
+	for i, eventType := range eventTypes {
+		v.Field(fmt.Sprintf("event_types[%d]", i), eventType, api.Required(), api.OneOf(allowed...))
+	}
+}
+
+type Handler struct {
+	subscriptions models.WebhookSubscriptionRepository
+	deliveries    models.WebhookDeliveryRepository
+}
+
+func NewHandler(subscriptions models.WebhookSubscriptionRepository, deliveries models.WebhookDeliveryRepository) *Handler {
+	return &Handler{
+		subscriptions: subscriptions,
+		deliveries:    deliveries,
+	}
+}
+
+func (h *Handler) HandleGet(w http.ResponseWriter, r *http.Request) {
+	subscriptions, err := h.subscriptions.GetAll(r.Context())
+	if err != nil {
+		api.HandleError(w, r, err, "failed to fetch webhooks")
+		return
+	}
+
+	responses := make([]SubscriptionResponse, len(subscriptions))
+	for i, s := range subscriptions {
+		responses[i] = subscriptionResponse(&s)
+	}
+	api.OKResponse(w, responses)
+}
+
+// HandleCreate subscribes a URL to events. The response carries the secret
+// the deliveries are signed with.
+func (h *Handler) HandleCreate(w http.ResponseWriter, r *http.Request) {
+	var req CreateSubscriptionRequest
+	if err := api.DecodeJSON(r, &req); err != nil {
+		api.HandleError(w, r, err, "invalid request body")
+		return
+	}
+	if err := req.Validate(); err != nil {
+		api.HandleError(w, r, err, "invalid request body")
+		return
+	}
+
+	subscription := &models.WebhookSubscription{
+		URL:        req.URL,
+		EventTypes: req.EventTypes,
+		Secret:     req.Secret,
+		Active:     req.Active == nil || *req.Active,
+	}
+	if subscription.Secret == "" {
+		subscription.Secret = newSecret()
+	}
+
+	if err := h.subscriptions.Create(r.Context(), subscription); err != nil {
+		api.HandleError(w, r, err, "failed to create webhook")
+		return
+	}
+
+	response := subscriptionResponse(subscription)
+	response.Secret = subscription.Secret
+	w.Header().Set("ETag", api.VersionETag(subscription.Version))
+	api.OKResponse(w, response)
+}
+
+func (h *Handler) HandleGetByID(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id", "webhook")
+	if err != nil {
+		api.HandleError(w, r, err, "failed to fetch webhook")
+		return
+	}
+
+	subscription, err := h.subscriptions.GetByID(r.Context(), id)
+	if err != nil {
+		api.HandleError(w, r, err, "failed to fetch webhook")
+		return
+	}
+
+	api.VersionedResponse(w, r, subscriptionResponse(subscription), api.VersionETag(subscription.Version), subscription.UpdatedAt)
+}
+
+// HandlePatch changes the fields of a subscription that are given.
+func (h *Handler) HandlePatch(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id", "webhook")
+	if err != nil {
+		api.HandleError(w, r, err, "failed to update webhook")
+		return
+	}
+	version, err := api.IfMatchVersion(r)
+	if err != nil {
+		api.HandleError(w, r, err, "invalid If-Match header")
+		return
+	}
+
+	var req UpdateSubscriptionRequest
+	if err := api.DecodeJSON(r, &req); err != nil {
+		api.HandleError(w, r, err, "invalid request body")
+		return
+	}
+	if err := req.Validate(); err != nil {
+		api.HandleError(w, r, err, "invalid request body")
+		return
+	}
+
+	subscription, err := h.subscriptions.Update(r.Context(), id, version, models.WebhookChanges{
+		URL:        req.URL,
+		EventTypes: req.EventTypes,
+		Secret:     req.Secret,
+		Active:     req.Active,
+	})
+	if err != nil {
+		api.HandleError(w, r, err, "failed to update webhook")
+		return
+	}
+
+	w.Header().Set("ETag", api.VersionETag(subscription.Version))
+	api.OKResponse(w, subscriptionResponse(subscription))
+}
+
+// HandleDelete removes a subscription and its delivery log.
+func (h *Handler) HandleDelete(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id", "webhook")
+	if err != nil {
+		api.HandleError(w, r, err, "failed to delete webhook")
+		return
+	}
+	version, err := api.IfMatchVersion(r)
+	if err != nil {
+		api.HandleError(w, r, err, "invalid If-Match header")
+		return
+	}
+
+	if err := h.subscriptions.Delete(r.Context(), id, version); err != nil {
+		api.HandleError(w, r, err, "failed to delete webhook")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleGetDeliveries lists the deliveries of a subscription newest first,
+// optionally only those in one status.
+func (h *Handler) HandleGetDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id", "webhook")
+	if err != nil {
+		api.HandleError(w, r, err, "failed to fetch webhook deliveries")
+		return
+	}
+
+	query := r.URL.Query()
+	var v api.Validator
+	v.Field("status", query.Get("status"), api.OneOf(models.DeliveryPending, models.DeliveryDelivered, models.DeliveryDead))
+	v.Field("offset", query.Get("offset"), api.IntRange(0, math.MaxInt32))
+	v.Field("limit", query.Get("limit"), api.IntRange(1, 100))
+	if err := v.Err(); err != nil {
+		api.HandleError(w, r, err, "invalid query parameters")
+		return
+	}
+
+	offset := 0
+	if offsetStr := query.Get("offset"); offsetStr != "" {
+		offset, _ = strconv.Atoi(offsetStr)
+	}
+
+	limit := 10
+	if limitStr := query.Get("limit"); limitStr != "" {
+		limit, _ = strconv.Atoi(limitStr)
+	}
+
+	if _, err := h.subscriptions.GetByID(r.Context(), id); err != nil {
+		api.HandleError(w, r, err, "failed to fetch webhook")
+		return
+	}
+
+	deliveries, total, err := h.deliveries.GetAll(r.Context(), id, query.Get("status"), offset, limit)
+	if err != nil {
+		api.HandleError(w, r, err, "failed to fetch webhook deliveries")
+		return
+	}
+
+	responses := make([]DeliveryResponse, len(deliveries))
+	for i, d := range deliveries {
+		responses[i] = deliveryResponse(&d)
+	}
+	api.OKResponse(w, DeliveriesResponse{
+		Deliveries: responses,
+		Total:      total,
+	})
+}
+
+// HandleRedeliver sends a delivered or dead delivery again.
+func (h *Handler) HandleRedeliver(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id", "webhook")
+	if err != nil {
+		api.HandleError(w, r, err, "failed to redeliver")
+		return
+	}
+	deliveryID, err := pathID(r, "delivery", "webhook delivery")
+	if err != nil {
+		api.HandleError(w, r, err, "failed to redeliver")
+		return
+	}
+
+	delivery, err := h.deliveries.Redeliver(r.Context(), id, deliveryID)
+	if err != nil {
+		api.HandleError(w, r, err, "failed to redeliver")
+		return
+	}
+	api.OKResponse(w, deliveryResponse(delivery))
+}
+
+// pathID parses the numeric path value name. IDs that cannot exist are
+// reported as a missing entity.
+func pathID(r *http.Request, name, entity string) (uint, error) {
+	id, err := strconv.ParseUint(r.PathValue(name), 10, 32)
+	if err != nil || id == 0 {
+		return 0, &models.Error{Kind: models.ErrNotFound, Message: entity + " not found"}
+	}
+	return uint(id), nil
+}
+
+func newSecret() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func subscriptionResponse(s *models.WebhookSubscription) SubscriptionResponse {
+	eventTypes := []string(s.EventTypes)
+	if eventTypes == nil {
+		eventTypes = []string{}
+	}
+	return SubscriptionResponse{
+		ID:         s.ID,
+		URL:        s.URL,
+		EventTypes: eventTypes,
+		Active:     s.Active,
+		CreatedAt:  s.CreatedAt,
+		UpdatedAt:  s.UpdatedAt,
+	}
+}
+
+func deliveryResponse(d *models.WebhookDelivery) DeliveryResponse {
+	response := DeliveryResponse{
+		ID:             d.ID,
+		EventID:        d.EventID,
+		EventType:      d.EventType,
+		AggregateType:  d.AggregateType,
+		AggregateID:    d.AggregateID,
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		ResponseStatus: d.ResponseStatus,
+		LastError:      d.LastError,
+		DeliveredAt:    d.DeliveredAt,
+		CreatedAt:      d.CreatedAt,
+		Payload:        d.Payload,
+	}
+	if d.Status == models.DeliveryPending {
+		response.NextAttemptAt = &d.NextAttemptAt
+	}
+	return response
+}
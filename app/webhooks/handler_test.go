@@ -0,0 +1,326 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mytheresa/go-hiring-challenge/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockWebhookSubscriptionRepository struct {
+	mock.Mock
+}
+
+func (m *MockWebhookSubscriptionRepository) GetAll(ctx context.Context) ([]models.WebhookSubscription, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]models.WebhookSubscription), args.Error(1)
+}
+
+func (m *MockWebhookSubscriptionRepository) GetByID(ctx context.Context, id uint) (*models.WebhookSubscription, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.WebhookSubscription), args.Error(1)
+}
+
+func (m *MockWebhookSubscriptionRepository) Create(ctx context.Context, subscription *models.WebhookSubscription) error {
+	return m.Called(ctx, subscription).Error(0)
+}
+
+func (m *MockWebhookSubscriptionRepository) Update(ctx context.Context, id uint, version uint, changes models.WebhookChanges) (*models.WebhookSubscription, error) {
+	args := m.Called(ctx, id, version, changes)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.WebhookSubscription), args.Error(1)
+}
+
+func (m *MockWebhookSubscriptionRepository) Delete(ctx context.Context, id uint, version uint) error {
+	return m.Called(ctx, id, version).Error(0)
+}
+
+type MockWebhookDeliveryRepository struct {
+	mock.Mock
+}
+
+func (m *MockWebhookDeliveryRepository) Enqueue(ctx context.Context, deliveries []models.WebhookDelivery) error {
+	return m.Called(ctx, deliveries).Error(0)
+}
+
+func (m *MockWebhookDeliveryRepository) Claim(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookDelivery, error) {
+	args := m.Called(ctx, limit, lease)
+	return args.Get(0).([]models.WebhookDelivery), args.Error(1)
+}
+
+func (m *MockWebhookDeliveryRepository) MarkDelivered(ctx context.Context, id uint, responseStatus int) error {
+	return m.Called(ctx, id, responseStatus).Error(0)
+}
+
+func (m *MockWebhookDeliveryRepository) MarkFailed(ctx context.Context, id uint, retryAt *time.Time, responseStatus int, reason string) error {
+	return m.Called(ctx, id, retryAt, responseStatus, reason).Error(0)
+}
+
+func (m *MockWebhookDeliveryRepository) GetAll(ctx context.Context, subscriptionID uint, status string, offset, limit int) ([]models.WebhookDelivery, int64, error) {
+	args := m.Called(ctx, subscriptionID, status, offset, limit)
+	return args.Get(0).([]models.WebhookDelivery), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockWebhookDeliveryRepository) Redeliver(ctx context.Context, subscriptionID, id uint) (*models.WebhookDelivery, error) {
+	args := m.Called(ctx, subscriptionID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.WebhookDelivery), args.Error(1)
+}
+
+func (m *MockWebhookDeliveryRepository) DeleteFinishedBefore(ctx context.Context, t time.Time) (int64, error) {
+	args := m.Called(ctx, t)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+var created = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func TestHandler_HandleCreate(t *testing.T) {
+	t.Run("generates a secret and returns it once", func(t *testing.T) {
+		subs := new(MockWebhookSubscriptionRepository)
+		handler := NewHandler(subs, new(MockWebhookDeliveryRepository))
+		var stored *models.WebhookSubscription
+		subs.On("Create", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			stored = args.Get(1).(*models.WebhookSubscription)
+			stored.ID, stored.Version, stored.CreatedAt, stored.UpdatedAt = 4, 1, created, created
+		}).Return(nil)
+
+		recorder := httptest.NewRecorder()
+		handler.HandleCreate(recorder, httptest.NewRequest("POST", "/webhooks",
+			strings.NewReader(`{"url":"https://partner.example.com/hooks","event_types":["product.price_changed","category.deleted"]}`)))
+
+		require.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, `"1"`, recorder.Header().Get("ETag"))
+		assert.True(t, stored.Active)
+		assert.Len(t, stored.Secret, 64)
+
+		var response SubscriptionResponse
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		assert.Equal(t, SubscriptionResponse{
+			ID:         4,
+			URL:        "https://partner.example.com/hooks",
+			EventTypes: []string{"product.price_changed", "category.deleted"},
+			Active:     true,
+			Secret:     stored.Secret,
+			CreatedAt:  created,
+			UpdatedAt:  created,
+		}, response)
+	})
+
+	t.Run("rejects bad URLs, unknown event types and short secrets", func(t *testing.T) {
+		subs := new(MockWebhookSubscriptionRepository)
+		handler := NewHandler(subs, new(MockWebhookDeliveryRepository))
+
+		recorder := httptest.NewRecorder()
+		handler.HandleCreate(recorder, httptest.NewRequest("POST", "/webhooks",
+			strings.NewReader(`{"url":"partner.example.com","event_types":["*","order.created"],"secret":"short"}`)))
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"field":"url"`)
+		assert.Contains(t, recorder.Body.String(), `"field":"event_types[1]"`)
+		assert.Contains(t, recorder.Body.String(), `"field":"secret"`)
+		subs.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("rejects URLs pointing to internal addresses", func(t *testing.T) {
+		subs := new(MockWebhookSubscriptionRepository)
+		handler := NewHandler(subs, new(MockWebhookDeliveryRepository))
+
+		recorder := httptest.NewRecorder()
+		handler.HandleCreate(recorder, httptest.NewRequest("POST", "/webhooks",
+			strings.NewReader(`{"url":"http://169.254.169.254/latest/meta-data","event_types":["*"]}`)))
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), "url must not point to a loopback, link-local or private address")
+		subs.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("requires at least one event type", func(t *testing.T) {
+		handler := NewHandler(new(MockWebhookSubscriptionRepository), new(MockWebhookDeliveryRepository))
+
+		recorder := httptest.NewRecorder()
+		handler.HandleCreate(recorder, httptest.NewRequest("POST", "/webhooks",
+			strings.NewReader(`{"url":"https://partner.example.com/hooks","event_types":[]}`)))
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"field":"event_types"`)
+	})
+}
+
+func TestHandler_HandleGetByID(t *testing.T) {
+	t.Run("returns the subscription without its secret", func(t *testing.T) {
+		subs := new(MockWebhookSubscriptionRepository)
+		handler := NewHandler(subs, new(MockWebhookDeliveryRepository))
+		subs.On("GetByID", mock.Anything, uint(4)).Return(&models.WebhookSubscription{
+			ID: 4, URL: "https://partner.example.com/hooks", EventTypes: models.StringList{"*"},
+			Secret: "0123456789abcdef", Active: true, Version: 2, CreatedAt: created, UpdatedAt: created,
+		}, nil)
+
+		req := httptest.NewRequest("GET", "/webhooks/4", nil)
+		req.SetPathValue("id", "4")
+		recorder := httptest.NewRecorder()
+		handler.HandleGetByID(recorder, req)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, `"2"`, recorder.Header().Get("ETag"))
+		assert.NotContains(t, recorder.Body.String(), "secret")
+	})
+
+	t.Run("returns 404 for malformed IDs", func(t *testing.T) {
+		subs := new(MockWebhookSubscriptionRepository)
+		handler := NewHandler(subs, new(MockWebhookDeliveryRepository))
+
+		req := httptest.NewRequest("GET", "/webhooks/abc", nil)
+		req.SetPathValue("id", "abc")
+		recorder := httptest.NewRecorder()
+		handler.HandleGetByID(recorder, req)
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+		subs.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything)
+	})
+}
+
+func TestHandler_HandlePatch(t *testing.T) {
+	t.Run("updates the given fields at the If-Match version", func(t *testing.T) {
+		subs := new(MockWebhookSubscriptionRepository)
+		handler := NewHandler(subs, new(MockWebhookDeliveryRepository))
+		inactive := false
+		subs.On("Update", mock.Anything, uint(4), uint(2), models.WebhookChanges{Active: &inactive}).Return(&models.WebhookSubscription{
+			ID: 4, URL: "https://partner.example.com/hooks", EventTypes: models.StringList{"*"}, Version: 3,
+		}, nil)
+
+		req := httptest.NewRequest("PATCH", "/webhooks/4", strings.NewReader(`{"active":false}`))
+		req.SetPathValue("id", "4")
+		req.Header.Set("If-Match", `"2"`)
+		recorder := httptest.NewRecorder()
+		handler.HandlePatch(recorder, req)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, `"3"`, recorder.Header().Get("ETag"))
+		assert.Contains(t, recorder.Body.String(), `"active":false`)
+	})
+
+	t.Run("requires If-Match", func(t *testing.T) {
+		handler := NewHandler(new(MockWebhookSubscriptionRepository), new(MockWebhookDeliveryRepository))
+
+		req := httptest.NewRequest("PATCH", "/webhooks/4", strings.NewReader(`{"active":false}`))
+		req.SetPathValue("id", "4")
+		recorder := httptest.NewRecorder()
+		handler.HandlePatch(recorder, req)
+
+		assert.Equal(t, http.StatusPreconditionRequired, recorder.Code)
+	})
+}
+
+func TestHandler_HandleDelete(t *testing.T) {
+	subs := new(MockWebhookSubscriptionRepository)
+	handler := NewHandler(subs, new(MockWebhookDeliveryRepository))
+	subs.On("Delete", mock.Anything, uint(4), models.AnyVersion).Return(nil)
+
+	req := httptest.NewRequest("DELETE", "/webhooks/4", nil)
+	req.SetPathValue("id", "4")
+	req.Header.Set("If-Match", "*")
+	recorder := httptest.NewRecorder()
+	handler.HandleDelete(recorder, req)
+
+	assert.Equal(t, http.StatusNoContent, recorder.Code)
+	subs.AssertExpectations(t)
+}
+
+func TestHandler_HandleGetDeliveries(t *testing.T) {
+	t.Run("lists the deliveries of a subscription", func(t *testing.T) {
+		subs := new(MockWebhookSubscriptionRepository)
+		deliveries := new(MockWebhookDeliveryRepository)
+		handler := NewHandler(subs, deliveries)
+		subs.On("GetByID", mock.Anything, uint(4)).Return(&models.WebhookSubscription{ID: 4}, nil)
+		deliveries.On("GetAll", mock.Anything, uint(4), "dead", 0, 10).Return([]models.WebhookDelivery{{
+			ID: 9, SubscriptionID: 4, EventID: 31, EventType: "category.deleted", AggregateType: "category", AggregateID: "shoes",
+			Payload: json.RawMessage(`{"id":31}`), Status: models.DeliveryDead, Attempts: 10, ResponseStatus: 500,
+			LastError: "unexpected response status 500", CreatedAt: created,
+		}}, int64(1), nil)
+
+		req := httptest.NewRequest("GET", "/webhooks/4/deliveries?status=dead", nil)
+		req.SetPathValue("id", "4")
+		recorder := httptest.NewRecorder()
+		handler.HandleGetDeliveries(recorder, req)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.JSONEq(t, `{"deliveries":[{"id":9,"event_id":31,"event_type":"category.deleted","aggregate_type":"category",
+			"aggregate_id":"shoes","status":"dead","attempts":10,"response_status":500,"last_error":"unexpected response status 500",
+			"created_at":"2024-03-01T12:00:00Z","payload":{"id":31}}],"total":1}`, recorder.Body.String())
+	})
+
+	t.Run("returns 404 for unknown subscriptions", func(t *testing.T) {
+		subs := new(MockWebhookSubscriptionRepository)
+		deliveries := new(MockWebhookDeliveryRepository)
+		handler := NewHandler(subs, deliveries)
+		subs.On("GetByID", mock.Anything, uint(5)).Return(nil, &models.Error{Kind: models.ErrNotFound, Message: "webhook not found"})
+
+		req := httptest.NewRequest("GET", "/webhooks/5/deliveries", nil)
+		req.SetPathValue("id", "5")
+		recorder := httptest.NewRecorder()
+		handler.HandleGetDeliveries(recorder, req)
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+		deliveries.AssertNotCalled(t, "GetAll", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("rejects unknown statuses", func(t *testing.T) {
+		handler := NewHandler(new(MockWebhookSubscriptionRepository), new(MockWebhookDeliveryRepository))
+
+		req := httptest.NewRequest("GET", "/webhooks/4/deliveries?status=failed", nil)
+		req.SetPathValue("id", "4")
+		recorder := httptest.NewRecorder()
+		handler.HandleGetDeliveries(recorder, req)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+}
+
+func TestHandler_HandleRedeliver(t *testing.T) {
+	t.Run("schedules the delivery again", func(t *testing.T) {
+		deliveries := new(MockWebhookDeliveryRepository)
+		handler := NewHandler(new(MockWebhookSubscriptionRepository), deliveries)
+		deliveries.On("Redeliver", mock.Anything, uint(4), uint(9)).Return(&models.WebhookDelivery{
+			ID: 9, Status: models.DeliveryPending, NextAttemptAt: created, CreatedAt: created, Payload: json.RawMessage(`{}`),
+		}, nil)
+
+		req := httptest.NewRequest("POST", "/webhooks/4/deliveries/9/redeliver", nil)
+		req.SetPathValue("id", "4")
+		req.SetPathValue("delivery", "9")
+		recorder := httptest.NewRecorder()
+		handler.HandleRedeliver(recorder, req)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"status":"pending"`)
+		assert.Contains(t, recorder.Body.String(), `"next_attempt_at":"2024-03-01T12:00:00Z"`)
+	})
+
+	t.Run("returns 409 for pending deliveries", func(t *testing.T) {
+		deliveries := new(MockWebhookDeliveryRepository)
+		handler := NewHandler(new(MockWebhookSubscriptionRepository), deliveries)
+		deliveries.On("Redeliver", mock.Anything, uint(4), uint(9)).Return(nil, &models.Error{Kind: models.ErrConflict, Message: "webhook delivery is still pending"})
+
+		req := httptest.NewRequest("POST", "/webhooks/4/deliveries/9/redeliver", nil)
+		req.SetPathValue("id", "4")
+		req.SetPathValue("delivery", "9")
+		recorder := httptest.NewRecorder()
+		handler.HandleRedeliver(recorder, req)
+
+		assert.Equal(t, http.StatusConflict, recorder.Code)
+	})
+}
@@ -0,0 +1,56 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/mytheresa/go-hiring-challenge/app/outbox"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+// Publisher queues a delivery of each event for every active subscription
+// that wants it. It is an outbox publisher, so deliveries are only queued for
+// changes that committed.
+type Publisher struct {
+	subscriptions models.WebhookSubscriptionRepository
+	deliveries    models.WebhookDeliveryRepository
+}
+
+func NewPublisher(subscriptions models.WebhookSubscriptionRepository, deliveries models.WebhookDeliveryRepository) *Publisher {
+	return &Publisher{
+		subscriptions: subscriptions,
+		deliveries:    deliveries,
+	}
+}
+
+func (p *Publisher) Publish(ctx context.Context, event outbox.Event) error {
+	subscriptions, err := p.subscriptions.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var deliveries []models.WebhookDelivery
+	for _, s := range subscriptions {
+		if !s.Active || !s.Wants(event.Type) {
+			continue
+		}
+		deliveries = append(deliveries, models.WebhookDelivery{
+			SubscriptionID: s.ID,
+			EventID:        event.ID,
+			EventType:      event.Type,
+			AggregateType:  event.AggregateType,
+			AggregateID:    event.AggregateID,
+			Payload:        payload,
+			Status:         models.DeliveryPending,
+			NextAttemptAt:  now,
+		})
+	}
+	return p.deliveries.Enqueue(ctx, deliveries)
+}
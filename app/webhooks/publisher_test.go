@@ -0,0 +1,48 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/mytheresa/go-hiring-challenge/app/outbox"
+	"github.com/mytheresa/go-hiring-challenge/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublisher_Publish(t *testing.T) {
+	subs := new(MockWebhookSubscriptionRepository)
+	deliveries := new(MockWebhookDeliveryRepository)
+	subs.On("GetAll", mock.Anything).Return([]models.WebhookSubscription{
+		{ID: 1, EventTypes: models.StringList{"*"}, Active: true},
+		{ID: 2, EventTypes: models.StringList{"product.price_changed"}, Active: true},
+		{ID: 3, EventTypes: models.StringList{"category.deleted"}, Active: true},
+		{ID: 4, EventTypes: models.StringList{"*"}, Active: false},
+	}, nil)
+	var queued []models.WebhookDelivery
+	deliveries.On("Enqueue", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		queued = args.Get(1).([]models.WebhookDelivery)
+	}).Return(nil)
+
+	event := outbox.Event{
+		ID:            31,
+		Type:          models.EventProductPriceChanged,
+		AggregateType: models.AuditEntityProduct,
+		AggregateID:   "PROD001",
+		Payload:       json.RawMessage(`{}`),
+	}
+	require.NoError(t, NewPublisher(subs, deliveries).Publish(context.Background(), event))
+
+	require.Len(t, queued, 2)
+	assert.Equal(t, uint(1), queued[0].SubscriptionID)
+	assert.Equal(t, uint(2), queued[1].SubscriptionID)
+	for _, d := range queued {
+		assert.Equal(t, uint(31), d.EventID)
+		assert.Equal(t, models.DeliveryPending, d.Status)
+		assert.Equal(t, "PROD001", d.AggregateID)
+		assert.JSONEq(t, `{"id":31,"type":"product.price_changed","aggregate_type":"product","aggregate_id":"PROD001",
+			"occurred_at":"0001-01-01T00:00:00Z","payload":{}}`, string(d.Payload))
+	}
+}
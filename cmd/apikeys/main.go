@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mytheresa/go-hiring-challenge/app/auth"
+	"github.com/mytheresa/go-hiring-challenge/app/config"
+	"github.com/mytheresa/go-hiring-challenge/app/database"
+	"github.com/mytheresa/go-hiring-challenge/models"
+)
+
+const usage = `Manage API keys.
+
+Usage:
+  apikeys create -name <name> [-roles role1,role2]
+  apikeys list
+  apikeys revoke -id <id>
+
+Database settings are read from the environment and .env file.`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	// Load configuration from environment, .env and YAML file
+	cfg, err := config.Load(nil)
+	if err != nil {
+		slog.Error("invalid configuration", "error", err)
+		os.Exit(1)
+	}
+
+	// Initialize database connection
+	db, close := database.New(cfg.Database)
+	defer close()
+
+	repo := models.NewAPIKeysRepository(db, cfg.Database.QueryTimeout)
+	ctx := context.Background()
+
+	switch os.Args[1] {
+	case "create":
+		err = create(ctx, repo, os.Args[2:])
+	case "list":
+		err = list(ctx, repo)
+	case "revoke":
+		err = revoke(ctx, repo, os.Args[2:])
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		slog.Error("command failed", "command", os.Args[1], "error", err)
+		os.Exit(1)
+	}
+}
+
+func create(ctx context.Context, repo *models.APIKeysRepository, args []string) error {
+	flags := flag.NewFlagSet("create", flag.ExitOnError)
+	name := flags.String("name", "", "name identifying the key holder")
+	roles := flags.String("roles", "", "comma-separated roles granted to the key")
+	flags.Parse(args)
+	if *name == "" {
+		return fmt.Errorf("-name is required")
+	}
+
+	key, prefix, hash, err := auth.GenerateAPIKey()
+	if err != nil {
+		return err
+	}
+
+	apiKey := &models.APIKey{Name: *name, Prefix: prefix, Hash: hash, Roles: splitRoles(*roles)}
+	if err := repo.Create(ctx, apiKey); err != nil {
+		return err
+	}
+
+	fmt.Printf("Created API key %d for %s. Store it now, it cannot be shown again:\n%s\n", apiKey.ID, apiKey.Name, key)
+	return nil
+}
+
+func list(ctx context.Context, repo *models.APIKeysRepository) error {
+	keys, err := repo.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tPREFIX\tROLES\tCREATED\tREVOKED")
+	for _, k := range keys {
+		revoked := "-"
+		if k.RevokedAt != nil {
+			revoked = k.RevokedAt.Format("2006-01-02 15:04")
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", k.ID, k.Name, k.Prefix, strings.Join(k.Roles, ","), k.CreatedAt.Format("2006-01-02 15:04"), revoked)
+	}
+	return w.Flush()
+}
+
+func revoke(ctx context.Context, repo *models.APIKeysRepository, args []string) error {
+	flags := flag.NewFlagSet("revoke", flag.ExitOnError)
+	id := flags.Uint("id", 0, "ID of the key to revoke")
+	flags.Parse(args)
+	if *id == 0 {
+		return fmt.Errorf("-id is required")
+	}
+
+	if err := repo.Revoke(ctx, *id); err != nil {
+		return err
+	}
+	fmt.Printf("Revoked API key %d\n", *id)
+	return nil
+}
+
+func splitRoles(s string) []string {
+	roles := []string{}
+	for _, role := range strings.Split(s, ",") {
+		if role = strings.TrimSpace(role); role != "" {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
@@ -1,63 +1,53 @@
 package main
 
 import (
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
-	"sort"
-	"strings"
-
-	"github.com/joho/godotenv"
 
+	"github.com/mytheresa/go-hiring-challenge/app/config"
 	"github.com/mytheresa/go-hiring-challenge/app/database"
 )
 
 func main() {
-	// Load environment variables from .env file
-	if err := godotenv.Load(".env"); err != nil {
-		log.Fatalf("Error loading .env file: %s", err)
+	// Load configuration from flags, environment, .env and YAML file
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		slog.Error("invalid configuration", "error", err)
+		os.Exit(1)
 	}
+	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: cfg.Log.SlogLevel()})))
 
 	// Initialize database connection
-	db, close := database.New(
-		os.Getenv("POSTGRES_USER"),
-		os.Getenv("POSTGRES_PASSWORD"),
-		os.Getenv("POSTGRES_DB"),
-		os.Getenv("POSTGRES_PORT"),
-	)
+	db, close := database.New(cfg.Database)
 	defer close()
 
-	dir := os.Getenv("POSTGRES_SQL_DIR")
-	files, err := os.ReadDir(dir)
+	dir := cfg.Database.SQLDir
+	sqlFiles, err := database.Migrations(dir)
 	if err != nil {
-		log.Fatalf("reading directory failed: %v", err)
-	}
-
-	// Filter and sort .sql files
-	var sqlFiles []os.DirEntry
-	for _, file := range files {
-		if !file.IsDir() && strings.HasSuffix(file.Name(), ".sql") {
-			sqlFiles = append(sqlFiles, file)
-		}
+		slog.Error("reading directory failed", "dir", dir, "error", err)
+		os.Exit(1)
 	}
-	sort.Slice(sqlFiles, func(i, j int) bool {
-		return sqlFiles[i].Name() < sqlFiles[j].Name()
-	})
 
-	for _, file := range sqlFiles {
-		path := filepath.Join(dir, file.Name())
+	for _, name := range sqlFiles {
+		path := filepath.Join(dir, name)
 
 		content, err := os.ReadFile(path)
 		if err != nil {
-			log.Printf("reading file %s failed: %v", file.Name(), err)
+			slog.Error("reading file failed", "file", name, "error", err)
 		}
 
 		sql := string(content)
 		if err := db.Exec(sql).Error; err != nil {
-			log.Printf("executing %s failed: %v", file.Name(), err)
+			slog.Error("executing migration failed", "file", name, "error", err)
+			return
+		}
+
+		if err := database.RecordMigration(db, database.Version(name)); err != nil {
+			slog.Error("recording migration failed", "file", name, "error", err)
 			return
 		}
 
-		log.Printf("Executed %s successfully\n", file.Name())
+		slog.Info("executed migration", "file", name)
 	}
 }
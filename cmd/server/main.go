@@ -3,70 +3,263 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
-	"github.com/joho/godotenv"
+	"github.com/mytheresa/go-hiring-challenge/app/api"
+	"github.com/mytheresa/go-hiring-challenge/app/audit"
+	"github.com/mytheresa/go-hiring-challenge/app/auth"
+	"github.com/mytheresa/go-hiring-challenge/app/cache"
 	"github.com/mytheresa/go-hiring-challenge/app/catalog"
 	"github.com/mytheresa/go-hiring-challenge/app/categories"
+	"github.com/mytheresa/go-hiring-challenge/app/changes"
+	"github.com/mytheresa/go-hiring-challenge/app/config"
 	"github.com/mytheresa/go-hiring-challenge/app/database"
+	"github.com/mytheresa/go-hiring-challenge/app/graphql"
+	"github.com/mytheresa/go-hiring-challenge/app/health"
+	"github.com/mytheresa/go-hiring-challenge/app/idempotency"
+	"github.com/mytheresa/go-hiring-challenge/app/metrics"
+	"github.com/mytheresa/go-hiring-challenge/app/openapi"
+	"github.com/mytheresa/go-hiring-challenge/app/outbox"
+	"github.com/mytheresa/go-hiring-challenge/app/ratelimit"
+	"github.com/mytheresa/go-hiring-challenge/app/server"
+	"github.com/mytheresa/go-hiring-challenge/app/tracing"
+	"github.com/mytheresa/go-hiring-challenge/app/webhooks"
 	"github.com/mytheresa/go-hiring-challenge/models"
 )
 
 func main() {
-	// Load environment variables from .env file
-	if err := godotenv.Load(".env"); err != nil {
-		log.Fatalf("Error loading .env file: %s", err)
+	if err := run(); err != nil {
+		slog.Error("server failed", "error", err)
+		os.Exit(1)
+	}
+}
+
+// run serves until a signal arrives and returns why it could not, so that
+// main exits non-zero only after the deferred cleanup has run.
+func run() error {
+	// Load configuration from flags, environment, .env and YAML file
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
 	}
 
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: cfg.Log.SlogLevel()}))
+	slog.SetDefault(logger)
+
 	// signal handling for graceful shutdown
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
+	// Set up tracing before anything that creates spans
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing)
+	if err != nil {
+		return fmt.Errorf("setting up tracing: %w", err)
+	}
+	defer func() {
+		flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(flushCtx); err != nil {
+			slog.Error("failed to flush traces", "error", err)
+		}
+	}()
+
 	// Initialize database connection
-	db, close := database.New(
-		os.Getenv("POSTGRES_USER"),
-		os.Getenv("POSTGRES_PASSWORD"),
-		os.Getenv("POSTGRES_DB"),
-		os.Getenv("POSTGRES_PORT"),
-	)
+	db, close := database.New(cfg.Database)
 	defer close()
 
-	// Initialize handlers
-	prodRepo := models.NewProductsRepository(db)
-	catRepo := models.NewCategoriesRepository(db)
-	
-	catalogHandler := catalog.NewCatalogHandler(prodRepo)
+	if err := tracing.InstrumentGORM(db); err != nil {
+		return fmt.Errorf("tracing database: %w", err)
+	}
+
+	// Instrument the database and expose its connection pool statistics
+	appMetrics := metrics.New()
+	if err := appMetrics.InstrumentGORM(db); err != nil {
+		return fmt.Errorf("instrumenting database: %w", err)
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		if err := appMetrics.RegisterDBStats(sqlDB, cfg.Database.Name); err != nil {
+			return fmt.Errorf("registering database pool metrics: %w", err)
+		}
+	}
+
+	// Initialize repositories, behind a read-through cache unless disabled
+	var prodRepo models.ProductRepository = models.NewProductsRepository(db, cfg.Database.QueryTimeout)
+	var catRepo models.CategoryRepository = models.NewCategoriesRepository(db, cfg.Database.QueryTimeout)
+	if cfg.Cache.Enabled {
+		cachedProducts := cache.NewProductRepository(prodRepo, cfg.Cache)
+		prodRepo = cachedProducts
+		catRepo = cache.NewCategoryRepository(catRepo, cfg.Cache, cachedProducts)
+	}
+
+	// Roles map onto permissions; routes check them, and handlers check the
+	// ones that depend on the request body, such as prices:write
+	policy := auth.PolicyFromConfig(cfg.Auth)
+
+	// Initialize handlers; the catalog has one per API version, all sharing
+	// the repositories
+	changesRepo := models.NewChangesRepository(db, cfg.Database.QueryTimeout)
+	catalogHandler := catalog.NewCatalogHandler(prodRepo, policy)
+	catalogHandlerV2 := catalog.NewCatalogHandlerV2(prodRepo, policy)
+	changesHandler := changes.NewHandler(changesRepo)
+	changesHandlerV2 := changes.NewHandlerV2(changesRepo)
 	categoriesHandler := categories.NewCategoriesHandler(catRepo)
+	auditHandler := audit.NewHandler(models.NewAuditEntriesRepository(db, cfg.Database.QueryTimeout))
 
-	// Set up routing
-	mux := http.NewServeMux()
-	mux.HandleFunc("GET /catalog", catalogHandler.HandleGet)
-	mux.HandleFunc("GET /catalog/{code}", catalogHandler.HandleGetByCode)
-	mux.HandleFunc("GET /categories", categoriesHandler.HandleGet)
-	mux.HandleFunc("POST /categories", categoriesHandler.HandleCreate)
-
-	// Set up the HTTP server
-	srv := &http.Server{
-		Addr:    fmt.Sprintf("localhost:%s", os.Getenv("HTTP_PORT")),
-		Handler: mux,
-	}
-
-	// Start the server
-	go func() {
-		log.Printf("Starting server on http://%s", srv.Addr)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Server failed: %s", err)
+	// Authentication and authorization: reads may stay anonymous by config,
+	// writes always require credentials and the route's permission
+	var jwks *auth.JWKS
+	if cfg.Auth.JWKSFile != "" {
+		if jwks, err = auth.LoadJWKS(cfg.Auth.JWKSFile); err != nil {
+			return fmt.Errorf("loading JWKS %s: %w", cfg.Auth.JWKSFile, err)
 		}
+	}
+	guard := auth.Guard{
+		Authenticator: auth.NewAuthenticator(models.NewAPIKeysRepository(db, cfg.Database.QueryTimeout), jwks, cfg.Auth),
+		Policy:        policy,
+	}
 
-		log.Println("Server stopped gracefully")
-	}()
+	// Rate limits apply per route and per client, after authentication identifies the client
+	limiter := ratelimit.New(ratelimit.NewMemoryStore(), cfg.RateLimit, logger)
+
+	// POST requests may carry an Idempotency-Key so clients can retry them safely
+	idempotencyKeys := idempotency.New(models.NewIdempotencyKeysRepository(db, cfg.Database.QueryTimeout), cfg.Idempotency, logger)
+	go idempotencyKeys.Run(ctx, time.Hour)
+
+	// Catalog writes add their events to an outbox in the same transaction;
+	// the dispatcher publishes them in the background, to stdout or a file
+	// and to the webhook subscriptions that want them
+	webhookSubscriptions := models.NewWebhookSubscriptionsRepository(db, cfg.Database.QueryTimeout)
+	webhookDeliveries := models.NewWebhookDeliveriesRepository(db, cfg.Database.QueryTimeout)
+	webhooksHandler := webhooks.NewHandler(webhookSubscriptions, webhookDeliveries)
+
+	var publishers outbox.Fanout
+	if cfg.Outbox.Publisher != "none" {
+		out := os.Stdout
+		if cfg.Outbox.Publisher == "file" {
+			if out, err = os.OpenFile(cfg.Outbox.File, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644); err != nil {
+				return fmt.Errorf("opening outbox file: %w", err)
+			}
+			defer out.Close()
+		}
+		publishers = append(publishers, outbox.NewWriterPublisher(out))
+	}
+	if cfg.Webhooks.Enabled {
+		publishers = append(publishers, webhooks.NewPublisher(webhookSubscriptions, webhookDeliveries))
+		go webhooks.NewDeliverer(webhookDeliveries, cfg.Webhooks, logger).Run(ctx)
+	}
+	if len(publishers) > 0 {
+		dispatcher := outbox.NewDispatcher(models.NewOutboxEventsRepository(db, cfg.Database.QueryTimeout), publishers, cfg.Outbox, logger)
+		go dispatcher.Run(ctx)
+	}
+
+	// GraphQL serves the frontend products with their category and variants
+	// in one round trip, batching the lookups behind them
+	graphqlHandler, err := graphql.NewHandler(prodRepo, catRepo, policy, logger)
+	if err != nil {
+		return fmt.Errorf("loading GraphQL schema: %w", err)
+	}
+
+	// Set up routing; every catalog route declares the permission it needs,
+	// and seeing or restoring deleted data additionally needs catalog:admin.
+	// Writes are attributed to the caller in the audit log. API routes are
+	// served under /v1 and /v2, and without a prefix as a deprecated alias of
+	// /v1; only the catalog responses differ between the versions
+	mux := http.NewServeMux()
+	v1 := api.NewGroup(mux, "/v1")
+	v2 := api.NewGroup(mux, "/v2")
+	unversioned := api.NewGroup(mux, "", api.Deprecated(cfg.API.UnversionedDeprecatedAt, cfg.API.UnversionedSunset, "/v1"))
+	allVersions := api.Groups{unversioned, v1, v2}
+
+	catalogRoutes := func(r api.Router, catalogHandler *catalog.CatalogHandler, changesHandler *changes.Handler) {
+		r.Handle("GET /catalog", guard.Read(auth.PermCatalogRead, guard.Flag("include_deleted", auth.PermCatalogAdmin, limiter.Wrap(catalogHandler.HandleGet))))
+		r.Handle("GET /catalog/changes", guard.Read(auth.PermCatalogRead, limiter.Wrap(changesHandler.HandleGet)))
+		r.Handle("GET /catalog/{code}", guard.Read(auth.PermCatalogRead, guard.Flag("include_deleted", auth.PermCatalogAdmin, limiter.Wrap(catalogHandler.HandleGetByCode))))
+		r.Handle("PUT /catalog/{code}", guard.Write(auth.PermCatalogWrite, limiter.Wrap(audit.Attribute(catalogHandler.HandleUpdate))))
+		r.Handle("PATCH /catalog/{code}", guard.Write(auth.PermCatalogWrite, limiter.Wrap(audit.Attribute(catalogHandler.HandlePatch))))
+		r.Handle("DELETE /catalog/{code}", guard.Write(auth.PermCatalogWrite, limiter.Wrap(audit.Attribute(catalogHandler.HandleDelete))))
+		r.Handle("POST /catalog/{code}/restore", guard.Write(auth.PermCatalogAdmin, limiter.Wrap(idempotencyKeys.Wrap(audit.Attribute(catalogHandler.HandleRestore)))))
+		r.Handle("GET /catalog/{code}/variants/{sku}", guard.Read(auth.PermCatalogRead, guard.Flag("include_deleted", auth.PermCatalogAdmin, limiter.Wrap(catalogHandler.HandleGetVariant))))
+		r.Handle("PUT /catalog/{code}/variants/{sku}", guard.Write(auth.PermCatalogWrite, limiter.Wrap(audit.Attribute(catalogHandler.HandleUpdateVariant))))
+		r.Handle("PATCH /catalog/{code}/variants/{sku}", guard.Write(auth.PermCatalogWrite, limiter.Wrap(audit.Attribute(catalogHandler.HandlePatchVariant))))
+		r.Handle("DELETE /catalog/{code}/variants/{sku}", guard.Write(auth.PermCatalogWrite, limiter.Wrap(audit.Attribute(catalogHandler.HandleDeleteVariant))))
+		r.Handle("POST /catalog/{code}/variants/{sku}/restore", guard.Write(auth.PermCatalogAdmin, limiter.Wrap(idempotencyKeys.Wrap(audit.Attribute(catalogHandler.HandleRestoreVariant)))))
+	}
+	catalogRoutes(api.Groups{unversioned, v1}, catalogHandler, changesHandler)
+	catalogRoutes(v2, catalogHandlerV2, changesHandlerV2)
+
+	allVersions.Handle("GET /categories", guard.Read(auth.PermCatalogRead, guard.Flag("include_deleted", auth.PermCatalogAdmin, limiter.Wrap(categoriesHandler.HandleGet))))
+	allVersions.Handle("POST /categories", guard.Write(auth.PermCategoriesWrite, limiter.Wrap(idempotencyKeys.Wrap(audit.Attribute(categoriesHandler.HandleCreate)))))
+	allVersions.Handle("GET /categories/{code}", guard.Read(auth.PermCatalogRead, guard.Flag("include_deleted", auth.PermCatalogAdmin, limiter.Wrap(categoriesHandler.HandleGetByCode))))
+	allVersions.Handle("PUT /categories/{code}", guard.Write(auth.PermCategoriesWrite, limiter.Wrap(audit.Attribute(categoriesHandler.HandleUpdate))))
+	allVersions.Handle("PATCH /categories/{code}", guard.Write(auth.PermCategoriesWrite, limiter.Wrap(audit.Attribute(categoriesHandler.HandlePatch))))
+	allVersions.Handle("DELETE /categories/{code}", guard.Write(auth.PermCategoriesWrite, limiter.Wrap(audit.Attribute(categoriesHandler.HandleDelete))))
+	allVersions.Handle("POST /categories/{code}/restore", guard.Write(auth.PermCatalogAdmin, limiter.Wrap(idempotencyKeys.Wrap(audit.Attribute(categoriesHandler.HandleRestore)))))
+	allVersions.Handle("GET /audit", guard.Read(auth.PermAuditRead, limiter.Wrap(auditHandler.HandleGet)))
+	allVersions.Handle("GET /webhooks", guard.Read(auth.PermWebhooksManage, limiter.Wrap(webhooksHandler.HandleGet)))
+	allVersions.Handle("POST /webhooks", guard.Write(auth.PermWebhooksManage, limiter.Wrap(idempotencyKeys.Wrap(webhooksHandler.HandleCreate))))
+	allVersions.Handle("GET /webhooks/{id}", guard.Read(auth.PermWebhooksManage, limiter.Wrap(webhooksHandler.HandleGetByID)))
+	allVersions.Handle("PATCH /webhooks/{id}", guard.Write(auth.PermWebhooksManage, limiter.Wrap(webhooksHandler.HandlePatch)))
+	allVersions.Handle("DELETE /webhooks/{id}", guard.Write(auth.PermWebhooksManage, limiter.Wrap(webhooksHandler.HandleDelete)))
+	allVersions.Handle("GET /webhooks/{id}/deliveries", guard.Read(auth.PermWebhooksManage, limiter.Wrap(webhooksHandler.HandleGetDeliveries)))
+	allVersions.Handle("POST /webhooks/{id}/deliveries/{delivery}/redeliver", guard.Write(auth.PermWebhooksManage, limiter.Wrap(idempotencyKeys.Wrap(webhooksHandler.HandleRedeliver))))
+	// GraphQL is versioned by its schema rather than the path. The route
+	// needs catalog:read; mutations check their own permissions and, like
+	// every POST, can be retried safely with an Idempotency-Key
+	mux.Handle("POST /graphql", guard.Read(auth.PermCatalogRead, limiter.Wrap(idempotencyKeys.Wrap(audit.Attribute(graphqlHandler.HandleQuery)))))
+	mux.Handle("GET /metrics", appMetrics.Handler())
+
+	// Set up the HTTP server; tracing is the last middleware to replace the
+	// request so the ones below it can read the matched route pattern, and
+	// recovery sits inside the access log and metrics so recovered panics are
+	// still recorded as 500 responses. Cache-Control is innermost, next to
+	// the mux, because it reads the matched pattern while the response is written
+	handler := api.Chain(mux,
+		api.AssignRequestID(),
+		tracing.Middleware(),
+		api.AccessLog(logger),
+		appMetrics.Middleware(),
+		api.Recover(logger),
+		api.CacheControl(cfg.HTTP.CacheControl),
+	)
+	srv := server.New(cfg.HTTP, handler)
+
+	// Health probes; readiness fails as soon as the server starts draining
+	healthHandler := health.NewHandler(srv.Draining, cfg.Health.CheckTimeout)
+	healthHandler.Register("database", health.DatabasePing(db))
+	if version, err := database.LatestMigration(cfg.Database.SQLDir); err != nil {
+		slog.Warn("skipping migration readiness check", "error", err)
+	} else {
+		healthHandler.Register("migrations", health.MigrationVersion(db, version))
+	}
+	mux.HandleFunc("GET /healthz", healthHandler.HandleLiveness)
+	mux.HandleFunc("GET /readyz", healthHandler.HandleReadiness)
+
+	// API description; the browsable docs load their renderer from a CDN, so
+	// they are opt-in
+	for _, version := range []struct {
+		name   string
+		router api.Router
+	}{
+		{"v1", api.Groups{unversioned, v1}},
+		{"v2", v2},
+	} {
+		openapiHandler, err := openapi.NewHandler(version.name)
+		if err != nil {
+			return fmt.Errorf("loading %s OpenAPI document: %w", version.name, err)
+		}
+		version.router.Handle("GET /openapi.json", http.HandlerFunc(openapiHandler.HandleSpec))
+		if cfg.HTTP.DocsUI {
+			version.router.Handle("GET /docs", http.HandlerFunc(openapiHandler.HandleDocs))
+		}
+	}
 
-	<-ctx.Done()
-	log.Println("Shutting down server...")
-	srv.Shutdown(ctx)
-	stop()
+	// Start the server; Run blocks until the signal context is cancelled
+	// and in-flight requests have drained.
+	slog.Info("starting server", "addr", srv.Addr())
+	return srv.Run(ctx)
 }
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// APIKey is a static credential for machine clients. Only the SHA-256 hash of
+// the key is stored; Prefix keeps the first characters so operators can tell keys apart.
+type APIKey struct {
+	ID        uint           `gorm:"primaryKey"`
+	Name      string         `gorm:"not null"`
+	Prefix    string         `gorm:"not null"`
+	Hash      string         `gorm:"uniqueIndex;not null"`
+	Roles     pq.StringArray `gorm:"type:text[];not null"`
+	CreatedAt time.Time
+	RevokedAt *time.Time
+}
+
+func (k *APIKey) TableName() string {
+	return "api_keys"
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type APIKeysRepository struct {
+	db           *gorm.DB
+	queryTimeout time.Duration
+}
+
+func NewAPIKeysRepository(db *gorm.DB, queryTimeout time.Duration) *APIKeysRepository {
+	return &APIKeysRepository{
+		db:           db,
+		queryTimeout: queryTimeout,
+	}
+}
+
+// GetActiveByHash returns the non-revoked key with the given hash.
+func (r *APIKeysRepository) GetActiveByHash(ctx context.Context, hash string) (*APIKey, error) {
+	db, cancel := withTimeout(ctx, r.db, r.queryTimeout)
+	defer cancel()
+
+	var key APIKey
+	if err := db.Where("hash = ? AND revoked_at IS NULL", hash).First(&key).Error; err != nil {
+		return nil, translateError(err, "api key")
+	}
+	return &key, nil
+}
+
+func (r *APIKeysRepository) GetAll(ctx context.Context) ([]APIKey, error) {
+	db, cancel := withTimeout(ctx, r.db, r.queryTimeout)
+	defer cancel()
+
+	var keys []APIKey
+	if err := db.Order("id").Find(&keys).Error; err != nil {
+		return nil, translateError(err, "api key")
+	}
+	return keys, nil
+}
+
+func (r *APIKeysRepository) Create(ctx context.Context, key *APIKey) error {
+	db, cancel := withTimeout(ctx, r.db, r.queryTimeout)
+	defer cancel()
+
+	return translateError(db.Create(key).Error, "api key")
+}
+
+// Revoke marks the key as revoked; it is kept for auditing.
+func (r *APIKeysRepository) Revoke(ctx context.Context, id uint) error {
+	db, cancel := withTimeout(ctx, r.db, r.queryTimeout)
+	defer cancel()
+
+	result := db.Model(&APIKey{}).Where("id = ? AND revoked_at IS NULL", id).Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return translateError(result.Error, "api key")
+	}
+	if result.RowsAffected == 0 {
+		return &Error{Kind: ErrNotFound, Message: "api key not found"}
+	}
+	return nil
+}
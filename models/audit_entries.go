@@ -0,0 +1,139 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Entities and actions recorded in the audit log.
+const (
+	AuditEntityProduct  = "product"
+	AuditEntityVariant  = "variant"
+	AuditEntityCategory = "category"
+
+	AuditActionCreate  = "create"
+	AuditActionUpdate  = "update"
+	AuditActionDelete  = "delete"
+	AuditActionRestore = "restore"
+)
+
+// AuditEntry records one change to a product, variant or category. Code is
+// the product or category code, or the SKU for variants. Changes maps every
+// field that changed onto its value before and after.
+type AuditEntry struct {
+	ID        uint            `gorm:"primaryKey"`
+	Entity    string          `gorm:"not null"`
+	Code      string          `gorm:"not null"`
+	Action    string          `gorm:"not null"`
+	Actor     string          `gorm:"not null"`
+	RequestID string          `gorm:"not null"`
+	Changes   json.RawMessage `gorm:"type:jsonb;not null"`
+	CreatedAt time.Time
+}
+
+func (e *AuditEntry) TableName() string {
+	return "audit_entries"
+}
+
+// FieldChange is the value of a field before and after a change; nil stands
+// for a field that was not set.
+type FieldChange struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+// Actor identifies who made a change and in which request.
+type Actor struct {
+	Subject   string
+	RequestID string
+}
+
+type actorKey struct{}
+
+// WithActor returns a copy of ctx whose writes are attributed to actor in the audit log.
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor stored in ctx, or the zero Actor.
+func ActorFromContext(ctx context.Context) Actor {
+	actor, _ := ctx.Value(actorKey{}).(Actor)
+	return actor
+}
+
+// diffStates lists the fields whose values differ between before and after.
+func diffStates(before, after map[string]any) map[string]FieldChange {
+	changes := make(map[string]FieldChange)
+	for field, value := range after {
+		if old, ok := before[field]; !ok || !reflect.DeepEqual(old, value) {
+			changes[field] = FieldChange{Before: before[field], After: value}
+		}
+	}
+	for field, old := range before {
+		if _, ok := after[field]; !ok {
+			changes[field] = FieldChange{Before: old}
+		}
+	}
+	return changes
+}
+
+// recordChange adds an audit entry and the outbox events for a change from
+// before to after, using tx so both commit or roll back with the change
+// itself. A nil before stands for a created row. Changes that touch no audited
+// field are not recorded.
+func recordChange(tx *gorm.DB, entity, code, action string, before, after map[string]any) error {
+	changes := diffStates(before, after)
+	if len(changes) == 0 {
+		return nil
+	}
+	body, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+
+	actor := ActorFromContext(tx.Statement.Context)
+	err = tx.Create(&AuditEntry{
+		Entity:    entity,
+		Code:      code,
+		Action:    action,
+		Actor:     actor.Subject,
+		RequestID: actor.RequestID,
+		Changes:   body,
+	}).Error
+	if err != nil {
+		return err
+	}
+	return enqueueEvents(tx, entity, code, action, changes, after)
+}
+
+// auditState returns the audited fields of the product. Its category must be preloaded.
+func (p *Product) auditState() map[string]any {
+	state := map[string]any{"price": p.Price.StringFixed(2), "category": nil}
+	if p.Category != nil {
+		state["category"] = p.Category.Code
+	}
+	return withDeletedAt(state, p.DeletedAt)
+}
+
+// auditState returns the audited fields of the variant of the product with productCode.
+func (v *Variant) auditState(productCode string) map[string]any {
+	state := map[string]any{"product": productCode, "name": v.Name, "price": v.Price.StringFixed(2)}
+	return withDeletedAt(state, v.DeletedAt)
+}
+
+// auditState returns the audited fields of the category.
+func (c *Category) auditState() map[string]any {
+	return withDeletedAt(map[string]any{"name": c.Name}, c.DeletedAt)
+}
+
+func withDeletedAt(state map[string]any, deletedAt gorm.DeletedAt) map[string]any {
+	state["deleted_at"] = nil
+	if deletedAt.Valid {
+		state["deleted_at"] = deletedAt.Time.UTC().Format(time.RFC3339)
+	}
+	return state
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type AuditEntriesRepository struct {
+	db           *gorm.DB
+	queryTimeout time.Duration
+}
+
+func NewAuditEntriesRepository(db *gorm.DB, queryTimeout time.Duration) *AuditEntriesRepository {
+	return &AuditEntriesRepository{
+		db:           db,
+		queryTimeout: queryTimeout,
+	}
+}
+
+// GetAll returns a page of audit entries, newest first. Empty entity and
+// code match every entity and code.
+func (r *AuditEntriesRepository) GetAll(ctx context.Context, offset, limit int, entity, code string) ([]AuditEntry, int64, error) {
+	db, cancel := withTimeout(ctx, r.db, r.queryTimeout)
+	defer cancel()
+
+	query := db.Model(&AuditEntry{})
+	if entity != "" {
+		query = query.Where("entity = ?", entity)
+	}
+	if code != "" {
+		query = query.Where("code = ?", code)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, translateError(err, "audit entry")
+	}
+
+	var entries []AuditEntry
+	if err := query.Order("id DESC").Offset(offset).Limit(limit).Find(&entries).Error; err != nil {
+		return nil, 0, translateError(err, "audit entry")
+	}
+	return entries, total, nil
+}
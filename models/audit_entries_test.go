@@ -0,0 +1,52 @@
+package models
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestDiffStates(t *testing.T) {
+	t.Run("lists only the fields that changed", func(t *testing.T) {
+		before := (&Product{Price: decimal.NewFromInt(10), Category: &Category{Code: "shoes"}}).auditState()
+		after := (&Product{Price: decimal.NewFromInt(12), Category: &Category{Code: "shoes"}}).auditState()
+
+		assert.Equal(t, map[string]FieldChange{"price": {Before: "10.00", After: "12.00"}}, diffStates(before, after))
+	})
+
+	t.Run("records every field of a created row", func(t *testing.T) {
+		changes := diffStates(nil, (&Category{Name: "Shoes"}).auditState())
+
+		assert.Equal(t, map[string]FieldChange{
+			"name":       {After: "Shoes"},
+			"deleted_at": {},
+		}, changes)
+	})
+
+	t.Run("shows deletion as a change of deleted_at", func(t *testing.T) {
+		variant := Variant{Name: "Red", Price: decimal.Zero}
+		deleted := variant
+		deleted.DeletedAt = gorm.DeletedAt{Time: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), Valid: true}
+
+		changes := diffStates(variant.auditState("PROD001"), deleted.auditState("PROD001"))
+
+		assert.Equal(t, map[string]FieldChange{"deleted_at": {Before: nil, After: "2024-03-01T12:00:00Z"}}, changes)
+	})
+
+	t.Run("finds nothing to record for unchanged rows", func(t *testing.T) {
+		category := &Category{Name: "Shoes"}
+
+		assert.Empty(t, diffStates(category.auditState(), category.auditState()))
+	})
+}
+
+func TestActorFromContext(t *testing.T) {
+	ctx := WithActor(context.Background(), Actor{Subject: "alice", RequestID: "req-1"})
+
+	assert.Equal(t, Actor{Subject: "alice", RequestID: "req-1"}, ActorFromContext(ctx))
+	assert.Equal(t, Actor{}, ActorFromContext(context.Background()))
+}
@@ -1,12 +1,23 @@
 package models
 
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
 type Category struct {
-	ID   uint   `gorm:"primaryKey"`
-	Code string `gorm:"uniqueIndex;not null"`
-	Name string `gorm:"not null"`
+	ID        uint   `gorm:"primaryKey"`
+	Code      string `gorm:"uniqueIndex;not null"`
+	Name      string `gorm:"not null"`
+	Version   uint   `gorm:"not null;default:1"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+	// ChangeXID is the transaction that last wrote the row; it orders the change feed.
+	ChangeXID uint64 `gorm:"->"`
 }
 
 func (c *Category) TableName() string {
 	return "categories"
 }
-
@@ -1,28 +1,174 @@
 package models
 
 import (
+	"context"
+	"time"
+
 	"gorm.io/gorm"
 )
 
 type CategoriesRepository struct {
-	db *gorm.DB
+	db           *gorm.DB
+	queryTimeout time.Duration
 }
 
-func NewCategoriesRepository(db *gorm.DB) *CategoriesRepository {
+func NewCategoriesRepository(db *gorm.DB, queryTimeout time.Duration) *CategoriesRepository {
 	return &CategoriesRepository{
-		db: db,
+		db:           db,
+		queryTimeout: queryTimeout,
 	}
 }
 
-func (r *CategoriesRepository) GetAll() ([]Category, error) {
+func (r *CategoriesRepository) GetAll(ctx context.Context, includeDeleted bool) ([]Category, error) {
+	db, cancel := withTimeout(ctx, r.db, r.queryTimeout)
+	defer cancel()
+
 	var categories []Category
-	if err := r.db.Find(&categories).Error; err != nil {
-		return nil, err
+	if err := scoped(db, includeDeleted).Find(&categories).Error; err != nil {
+		return nil, translateError(err, "category")
 	}
 	return categories, nil
 }
 
-func (r *CategoriesRepository) Create(category *Category) error {
-	return r.db.Create(category).Error
+func (r *CategoriesRepository) Create(ctx context.Context, category *Category) error {
+	db, cancel := withTimeout(ctx, r.db, r.queryTimeout)
+	defer cancel()
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(category).Error; err != nil {
+			return err
+		}
+		return recordChange(tx, AuditEntityCategory, category.Code, AuditActionCreate, nil, category.auditState())
+	})
+	return translateError(err, "category")
+}
+
+func (r *CategoriesRepository) GetByCode(ctx context.Context, code string, includeDeleted bool) (*Category, error) {
+	db, cancel := withTimeout(ctx, r.db, r.queryTimeout)
+	defer cancel()
+
+	var category Category
+	if err := scoped(db, includeDeleted).Where("code = ?", code).First(&category).Error; err != nil {
+		return nil, translateError(err, "category")
+	}
+	return &category, nil
 }
 
+// GetByIDs returns the live categories among ids in one query; ids with no
+// live category are left out.
+func (r *CategoriesRepository) GetByIDs(ctx context.Context, ids []uint) ([]Category, error) {
+	db, cancel := withTimeout(ctx, r.db, r.queryTimeout)
+	defer cancel()
+
+	var categories []Category
+	if err := db.Where("id IN ?", ids).Find(&categories).Error; err != nil {
+		return nil, translateError(err, "category")
+	}
+	return categories, nil
+}
+
+// Update applies changes to the category with code if it is still at version,
+// and returns the category as stored afterwards.
+func (r *CategoriesRepository) Update(ctx context.Context, code string, version uint, changes CategoryChanges) (*Category, error) {
+	db, cancel := withTimeout(ctx, r.db, r.queryTimeout)
+	defer cancel()
+
+	var category Category
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var before Category
+		if err := lockRow(tx, &before, "code = ?", code); err != nil {
+			return err
+		}
+
+		updates := map[string]any{"version": gorm.Expr("version + 1"), "updated_at": time.Now()}
+		if changes.Name != nil {
+			updates["name"] = *changes.Name
+		}
+
+		result := whereVersion(tx.Model(&Category{}).Where("code = ?", code), version).Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return staleOrMissing(tx, &Category{}, "category", "code = ?", code)
+		}
+		if err := tx.Where("code = ?", code).First(&category).Error; err != nil {
+			return err
+		}
+		return recordChange(tx, AuditEntityCategory, code, AuditActionUpdate, before.auditState(), category.auditState())
+	})
+	if err != nil {
+		return nil, translateError(err, "category")
+	}
+	return &category, nil
+}
+
+// Delete soft-deletes the category with code if it is still at version.
+// Categories that products are still assigned to cannot be deleted.
+func (r *CategoriesRepository) Delete(ctx context.Context, code string, version uint) error {
+	db, cancel := withTimeout(ctx, r.db, r.queryTimeout)
+	defer cancel()
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Locking the row first makes assignments that are in flight either
+		// finish before the count below or fail afterwards.
+		var before Category
+		if err := lockRow(tx, &before, "code = ?", code); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		result := whereVersion(tx.Model(&Category{}).Where("code = ?", code), version).
+			Updates(map[string]any{"deleted_at": now, "version": gorm.Expr("version + 1"), "updated_at": now})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return staleOrMissing(tx, &Category{}, "category", "code = ?", code)
+		}
+
+		var assigned int64
+		if err := tx.Model(&Product{}).Where("category_id = ?", before.ID).Count(&assigned).Error; err != nil {
+			return err
+		}
+		if assigned > 0 {
+			return &Error{Kind: ErrConflict, Message: "category is still assigned to products"}
+		}
+
+		after := before
+		after.DeletedAt = gorm.DeletedAt{Time: now, Valid: true}
+		return recordChange(tx, AuditEntityCategory, code, AuditActionDelete, before.auditState(), after.auditState())
+	})
+	return translateError(err, "category")
+}
+
+// Restore brings back the soft-deleted category with code.
+func (r *CategoriesRepository) Restore(ctx context.Context, code string) (*Category, error) {
+	db, cancel := withTimeout(ctx, r.db, r.queryTimeout)
+	defer cancel()
+
+	var category Category
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var before Category
+		if err := lockRow(tx.Unscoped(), &before, "code = ?", code); err != nil {
+			return err
+		}
+
+		result := tx.Unscoped().Model(&Category{}).Where("code = ? AND deleted_at IS NOT NULL", code).
+			Updates(map[string]any{"deleted_at": nil, "version": gorm.Expr("version + 1"), "updated_at": time.Now()})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return deletedOrMissing(tx, &Category{}, "category", "code = ?", code)
+		}
+		if err := tx.Where("code = ?", code).First(&category).Error; err != nil {
+			return err
+		}
+		return recordChange(tx, AuditEntityCategory, code, AuditActionRestore, before.auditState(), category.auditState())
+	})
+	if err != nil {
+		return nil, translateError(err, "category")
+	}
+	return &category, nil
+}
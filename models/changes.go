@@ -0,0 +1,142 @@
+package models
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// changeEntities are the entities of the change feed in the order it reads them.
+var changeEntities = []string{AuditEntityCategory, AuditEntityProduct, AuditEntityVariant}
+
+// ChangeCursor is a position in the change feed. The feed is read in windows
+// of transaction IDs: a window holds the rows last written by transactions
+// from From up to, not including, To. Every transaction below To had ended
+// when the window was opened, so no change can still appear in it later.
+// Within a window the entities are read one after the other, each ordered by
+// transaction and ID. The zero cursor starts from the beginning and leaves out
+// deleted rows, which a client that has nothing yet does not need.
+type ChangeCursor struct {
+	From uint64
+	// To is zero when the next read opens a new window starting at From.
+	To       uint64
+	Entity   string
+	AfterXID uint64
+	AfterID  uint
+}
+
+// Changes is a page of the change feed. Deleted rows carry their DeletedAt;
+// Next continues the feed, and More reports whether the window has rows left.
+type Changes struct {
+	Categories []Category
+	Products   []Product
+	Variants   []Variant
+	Next       ChangeCursor
+	More       bool
+}
+
+type ChangesRepository struct {
+	db           *gorm.DB
+	queryTimeout time.Duration
+}
+
+func NewChangesRepository(db *gorm.DB, queryTimeout time.Duration) *ChangesRepository {
+	return &ChangesRepository{
+		db:           db,
+		queryTimeout: queryTimeout,
+	}
+}
+
+// GetChanges returns up to limit rows changed after cursor. Products come
+// with their category and variants with their product, deleted or not.
+func (r *ChangesRepository) GetChanges(ctx context.Context, cursor ChangeCursor, limit int) (*Changes, error) {
+	db, cancel := withTimeout(ctx, r.db, r.queryTimeout)
+	defer cancel()
+
+	if cursor.To == 0 {
+		var xmin string
+		if err := db.Raw("SELECT pg_snapshot_xmin(pg_current_snapshot())::text").Scan(&xmin).Error; err != nil {
+			return nil, translateError(err, "change")
+		}
+		to, err := strconv.ParseUint(xmin, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		cursor = ChangeCursor{From: cursor.From, To: to, Entity: changeEntities[0]}
+	}
+
+	changes := &Changes{}
+	remaining := limit
+	for i, entity := range changeEntities {
+		if entity != cursor.Entity {
+			continue
+		}
+
+		lastXID, lastID, n, err := r.read(db, changes, cursor, remaining)
+		if err != nil {
+			return nil, translateError(err, entity)
+		}
+		if n == remaining {
+			changes.Next = ChangeCursor{From: cursor.From, To: cursor.To, Entity: entity, AfterXID: lastXID, AfterID: lastID}
+			changes.More = true
+			return changes, nil
+		}
+		remaining -= n
+		if i+1 < len(changeEntities) {
+			cursor = ChangeCursor{From: cursor.From, To: cursor.To, Entity: changeEntities[i+1]}
+		}
+	}
+
+	changes.Next = ChangeCursor{From: cursor.To}
+	return changes, nil
+}
+
+// read appends up to limit rows of cursor's entity to changes and returns the
+// position of the last one and how many there were.
+func (r *ChangesRepository) read(db *gorm.DB, changes *Changes, cursor ChangeCursor, limit int) (uint64, uint, int, error) {
+	query := db.Unscoped().
+		Where("change_xid >= ?::text::xid8 AND change_xid < ?::text::xid8", formatXID(cursor.From), formatXID(cursor.To)).
+		Where("(change_xid, id) > (?::text::xid8, ?)", formatXID(cursor.AfterXID), cursor.AfterID).
+		Order("change_xid, id").
+		Limit(limit)
+	initial := cursor.From == 0
+	if initial {
+		query = query.Where("deleted_at IS NULL")
+	}
+
+	switch cursor.Entity {
+	case AuditEntityCategory:
+		var rows []Category
+		if err := query.Find(&rows).Error; err != nil || len(rows) == 0 {
+			return 0, 0, 0, err
+		}
+		changes.Categories = append(changes.Categories, rows...)
+		last := rows[len(rows)-1]
+		return last.ChangeXID, last.ID, len(rows), nil
+	case AuditEntityProduct:
+		var rows []Product
+		if err := query.Preload("Category").Find(&rows).Error; err != nil || len(rows) == 0 {
+			return 0, 0, 0, err
+		}
+		changes.Products = append(changes.Products, rows...)
+		last := rows[len(rows)-1]
+		return last.ChangeXID, last.ID, len(rows), nil
+	default:
+		if initial {
+			query = query.Where("product_id IN (SELECT id FROM products WHERE deleted_at IS NULL)")
+		}
+		var rows []Variant
+		if err := query.Preload("Product").Find(&rows).Error; err != nil || len(rows) == 0 {
+			return 0, 0, 0, err
+		}
+		changes.Variants = append(changes.Variants, rows...)
+		last := rows[len(rows)-1]
+		return last.ChangeXID, last.ID, len(rows), nil
+	}
+}
+
+func formatXID(xid uint64) string {
+	return strconv.FormatUint(xid, 10)
+}
@@ -0,0 +1,111 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Sentinel domain errors. Repository methods wrap database failures in an
+// *Error whose Kind is one of these, so callers can use errors.Is.
+var (
+	ErrNotFound           = errors.New("not found")
+	ErrConflict           = errors.New("conflict")
+	ErrValidation         = errors.New("validation failed")
+	ErrUnavailable        = errors.New("unavailable")
+	ErrPreconditionFailed = errors.New("precondition failed")
+)
+
+// Error describes a failed repository operation in domain terms.
+// Message is safe to expose to API clients; Err keeps the underlying cause.
+type Error struct {
+	Kind    error
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Message
+	}
+	return e.Message + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// sqlStater is implemented by both pgconn.PgError and pq.Error.
+type sqlStater interface {
+	SQLState() string
+}
+
+// translateError maps GORM and driver errors onto domain errors for the given entity.
+// Errors it does not recognise are returned unchanged.
+func translateError(err error, entity string) error {
+	if err == nil {
+		return nil
+	}
+
+	var domainErr *Error
+	if errors.As(err, &domainErr) {
+		return err
+	}
+
+	switch kind := classify(err); kind {
+	case ErrNotFound:
+		return &Error{Kind: kind, Message: entity + " not found", Err: err}
+	case ErrConflict:
+		return &Error{Kind: kind, Message: entity + " already exists", Err: err}
+	case ErrValidation:
+		return &Error{Kind: kind, Message: "invalid " + entity, Err: err}
+	case ErrUnavailable:
+		return &Error{Kind: kind, Message: "database unavailable", Err: err}
+	}
+	return err
+}
+
+func classify(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrConflict
+	}
+	if errors.Is(err, gorm.ErrForeignKeyViolated) || errors.Is(err, gorm.ErrCheckConstraintViolated) {
+		return ErrValidation
+	}
+
+	var stater sqlStater
+	if errors.As(err, &stater) {
+		code := stater.SQLState()
+		switch {
+		case code == "23505":
+			return ErrConflict
+		case strings.HasPrefix(code, "22"), strings.HasPrefix(code, "23"):
+			// data exceptions and the remaining integrity constraint violations
+			return ErrValidation
+		case strings.HasPrefix(code, "08"), strings.HasPrefix(code, "53"), strings.HasPrefix(code, "57P"):
+			// connection exceptions, insufficient resources, operator intervention
+			return ErrUnavailable
+		}
+		return nil
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, sql.ErrConnDone) {
+		return ErrUnavailable
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return ErrUnavailable
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestTranslateError(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		kind    error
+		message string
+	}{
+		{"record not found", gorm.ErrRecordNotFound, ErrNotFound, "product not found"},
+		{"unique violation", &pq.Error{Code: "23505"}, ErrConflict, "product already exists"},
+		{"value too long", &pq.Error{Code: "22001"}, ErrValidation, "invalid product"},
+		{"connection refused", &net.OpError{Op: "dial", Err: errors.New("connection refused")}, ErrUnavailable, "database unavailable"},
+		{"admin shutdown", &pq.Error{Code: "57P01"}, ErrUnavailable, "database unavailable"},
+		{"query deadline", fmt.Errorf("query: %w", context.DeadlineExceeded), ErrUnavailable, "database unavailable"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := translateError(tt.err, "product")
+
+			assert.ErrorIs(t, err, tt.kind)
+			assert.ErrorIs(t, err, tt.err, "cause should stay reachable")
+			var domainErr *Error
+			if assert.ErrorAs(t, err, &domainErr) {
+				assert.Equal(t, tt.message, domainErr.Message)
+			}
+		})
+	}
+
+	t.Run("leaves unknown errors untouched", func(t *testing.T) {
+		cause := errors.New("boom")
+		assert.Same(t, cause, translateError(cause, "product"))
+	})
+
+	t.Run("passes nil through", func(t *testing.T) {
+		assert.NoError(t, translateError(nil, "product"))
+	})
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// IdempotencyKey records a POST request made with an Idempotency-Key header
+// and, once it has completed, the response to replay for retries. Status is
+// nil while the first request is still being processed.
+type IdempotencyKey struct {
+	ID          uint   `gorm:"primaryKey"`
+	Scope       string `gorm:"not null"`
+	Key         string `gorm:"not null"`
+	RequestHash string `gorm:"not null"`
+	Status      *int
+	Headers     json.RawMessage `gorm:"type:jsonb"`
+	Body        []byte
+	CreatedAt   time.Time
+	ExpiresAt   time.Time `gorm:"not null"`
+}
+
+func (k *IdempotencyKey) TableName() string {
+	return "idempotency_keys"
+}
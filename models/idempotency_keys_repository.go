@@ -0,0 +1,75 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IdempotencyKeysRepository struct {
+	db           *gorm.DB
+	queryTimeout time.Duration
+}
+
+func NewIdempotencyKeysRepository(db *gorm.DB, queryTimeout time.Duration) *IdempotencyKeysRepository {
+	return &IdempotencyKeysRepository{
+		db:           db,
+		queryTimeout: queryTimeout,
+	}
+}
+
+// Reserve stores key unless its scope and key are already taken by a record
+// that has not expired. It returns nil when the key was reserved, and the
+// existing record otherwise.
+func (r *IdempotencyKeysRepository) Reserve(ctx context.Context, key *IdempotencyKey) (*IdempotencyKey, error) {
+	db, cancel := withTimeout(ctx, r.db, r.queryTimeout)
+	defer cancel()
+
+	var existing *IdempotencyKey
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("scope = ? AND key = ? AND expires_at <= ?", key.Scope, key.Key, time.Now()).
+			Delete(&IdempotencyKey{}).Error; err != nil {
+			return err
+		}
+
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(key)
+		if result.Error != nil || result.RowsAffected == 1 {
+			return result.Error
+		}
+
+		existing = &IdempotencyKey{}
+		return tx.Where("scope = ? AND key = ?", key.Scope, key.Key).First(existing).Error
+	})
+	if err != nil {
+		return nil, translateError(err, "idempotency key")
+	}
+	return existing, nil
+}
+
+// Complete stores the response of a reserved key.
+func (r *IdempotencyKeysRepository) Complete(ctx context.Context, key *IdempotencyKey) error {
+	db, cancel := withTimeout(ctx, r.db, r.queryTimeout)
+	defer cancel()
+
+	err := db.Model(key).Select("status", "headers", "body").Updates(key).Error
+	return translateError(err, "idempotency key")
+}
+
+// Release deletes a reserved key so the request can be retried.
+func (r *IdempotencyKeysRepository) Release(ctx context.Context, id uint) error {
+	db, cancel := withTimeout(ctx, r.db, r.queryTimeout)
+	defer cancel()
+
+	return translateError(db.Delete(&IdempotencyKey{}, id).Error, "idempotency key")
+}
+
+// DeleteExpired removes every key whose replay window has passed.
+func (r *IdempotencyKeysRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	db, cancel := withTimeout(ctx, r.db, r.queryTimeout)
+	defer cancel()
+
+	result := db.Where("expires_at <= ?", time.Now()).Delete(&IdempotencyKey{})
+	return result.RowsAffected, translateError(result.Error, "idempotency key")
+}
@@ -0,0 +1,107 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Event types written to the outbox. Aggregates are products, variants and
+// categories; their events carry the aggregate's code, or the SKU for variants.
+const (
+	EventProductUpdated      = "product.updated"
+	EventProductPriceChanged = "product.price_changed"
+	EventProductDeleted      = "product.deleted"
+	EventProductRestored     = "product.restored"
+	EventVariantUpdated      = "variant.updated"
+	EventVariantPriceChanged = "variant.price_changed"
+	EventVariantDeleted      = "variant.deleted"
+	EventVariantRestored     = "variant.restored"
+	EventCategoryCreated     = "category.created"
+	EventCategoryUpdated     = "category.updated"
+	EventCategoryDeleted     = "category.deleted"
+	EventCategoryRestored    = "category.restored"
+)
+
+// EventTypes lists every event type, for clients that subscribe to them.
+var EventTypes = []string{
+	EventProductUpdated, EventProductPriceChanged, EventProductDeleted, EventProductRestored,
+	EventVariantUpdated, EventVariantPriceChanged, EventVariantDeleted, EventVariantRestored,
+	EventCategoryCreated, EventCategoryUpdated, EventCategoryDeleted, EventCategoryRestored,
+}
+
+// OutboxEvent is a domain event waiting to be published. It is written in the
+// transaction of the change it describes, so events exist exactly for the
+// changes that committed. PublishedAt stays nil until a publisher accepted it;
+// DeadAt is set instead when the dispatcher gave up on the event.
+type OutboxEvent struct {
+	ID            uint            `gorm:"primaryKey"`
+	Type          string          `gorm:"not null"`
+	AggregateType string          `gorm:"not null"`
+	AggregateID   string          `gorm:"not null"`
+	Payload       json.RawMessage `gorm:"type:jsonb;not null"`
+	CreatedAt     time.Time
+	Attempts      int       `gorm:"not null;default:0"`
+	NextAttemptAt time.Time `gorm:"not null"`
+	ClaimedUntil  *time.Time
+	LastError     string
+	PublishedAt   *time.Time
+	DeadAt        *time.Time
+}
+
+func (e *OutboxEvent) TableName() string {
+	return "outbox_events"
+}
+
+// EventPayload is the body of every catalog event: the fields that changed
+// and the state of the aggregate after the change.
+type EventPayload struct {
+	Changes map[string]FieldChange `json:"changes"`
+	State   map[string]any         `json:"state"`
+}
+
+// eventTypes maps an audited action on an entity onto the events it raises.
+func eventTypes(entity, action string, changes map[string]FieldChange) []string {
+	types := []string{entity + "." + eventSuffix(action)}
+	if _, repriced := changes["price"]; action == AuditActionUpdate && repriced && entity != AuditEntityCategory {
+		types = append(types, entity+".price_changed")
+	}
+	return types
+}
+
+func eventSuffix(action string) string {
+	switch action {
+	case AuditActionCreate:
+		return "created"
+	case AuditActionDelete:
+		return "deleted"
+	case AuditActionRestore:
+		return "restored"
+	default:
+		return "updated"
+	}
+}
+
+// enqueueEvents writes the events for a change to the outbox using tx.
+func enqueueEvents(tx *gorm.DB, entity, code, action string, changes map[string]FieldChange, after map[string]any) error {
+	payload, err := json.Marshal(EventPayload{Changes: changes, State: after})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, eventType := range eventTypes(entity, action, changes) {
+		event := &OutboxEvent{
+			Type:          eventType,
+			AggregateType: entity,
+			AggregateID:   code,
+			Payload:       payload,
+			NextAttemptAt: now,
+		}
+		if err := tx.Create(event).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type OutboxEventsRepository struct {
+	db           *gorm.DB
+	queryTimeout time.Duration
+}
+
+func NewOutboxEventsRepository(db *gorm.DB, queryTimeout time.Duration) *OutboxEventsRepository {
+	return &OutboxEventsRepository{
+		db:           db,
+		queryTimeout: queryTimeout,
+	}
+}
+
+// claimQuery leases the oldest due events that are the first pending event
+// of their aggregate, so an aggregate's events are handed out one at a time
+// and in order. Dead events are skipped, so they do not block later events
+// of the same aggregate. Writes to one aggregate are serialized by its row
+// lock, so their ids follow commit order. SKIP LOCKED lets several
+// dispatchers claim concurrently without waiting on each other.
+const claimQuery = `
+UPDATE outbox_events SET claimed_until = ?, attempts = attempts + 1
+WHERE id IN (
+    SELECT e.id FROM outbox_events e
+    WHERE e.published_at IS NULL
+      AND e.dead_at IS NULL
+      AND e.next_attempt_at <= ?
+      AND (e.claimed_until IS NULL OR e.claimed_until < ?)
+      AND NOT EXISTS (
+          SELECT 1 FROM outbox_events p
+          WHERE p.aggregate_type = e.aggregate_type
+            AND p.aggregate_id = e.aggregate_id
+            AND p.published_at IS NULL
+            AND p.dead_at IS NULL
+            AND p.id < e.id
+      )
+    ORDER BY e.id
+    LIMIT ?
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *`
+
+// Claim leases up to limit events for publishing. Claimed events are not
+// handed out again until they are marked or the lease runs out.
+func (r *OutboxEventsRepository) Claim(ctx context.Context, limit int, lease time.Duration) ([]OutboxEvent, error) {
+	db, cancel := withTimeout(ctx, r.db, r.queryTimeout)
+	defer cancel()
+
+	now := time.Now()
+	var events []OutboxEvent
+	if err := db.Raw(claimQuery, now.Add(lease), now, now, limit).Scan(&events).Error; err != nil {
+		return nil, translateError(err, "outbox event")
+	}
+	return events, nil
+}
+
+// MarkPublished records that the event was published.
+func (r *OutboxEventsRepository) MarkPublished(ctx context.Context, id uint) error {
+	db, cancel := withTimeout(ctx, r.db, r.queryTimeout)
+	defer cancel()
+
+	err := db.Model(&OutboxEvent{}).Where("id = ?", id).Updates(map[string]any{
+		"published_at":  time.Now(),
+		"claimed_until": nil,
+		"last_error":    "",
+	}).Error
+	return translateError(err, "outbox event")
+}
+
+// MarkFailed releases the event's lease and schedules its next attempt at
+// retryAt, or marks the event dead when retryAt is nil.
+func (r *OutboxEventsRepository) MarkFailed(ctx context.Context, id uint, retryAt *time.Time, reason string) error {
+	db, cancel := withTimeout(ctx, r.db, r.queryTimeout)
+	defer cancel()
+
+	updates := map[string]any{
+		"claimed_until": nil,
+		"last_error":    reason,
+	}
+	if retryAt != nil {
+		updates["next_attempt_at"] = *retryAt
+	} else {
+		updates["dead_at"] = time.Now()
+	}
+	err := db.Model(&OutboxEvent{}).Where("id = ?", id).Updates(updates).Error
+	return translateError(err, "outbox event")
+}
+
+// DeleteFinishedBefore removes events published or given up as dead before t.
+func (r *OutboxEventsRepository) DeleteFinishedBefore(ctx context.Context, t time.Time) (int64, error) {
+	db, cancel := withTimeout(ctx, r.db, r.queryTimeout)
+	defer cancel()
+
+	result := db.Where("published_at < ? OR dead_at < ?", t, t).Delete(&OutboxEvent{})
+	return result.RowsAffected, translateError(result.Error, "outbox event")
+}
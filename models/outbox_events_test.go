@@ -0,0 +1,28 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventTypes(t *testing.T) {
+	t.Run("names the event after the entity and action", func(t *testing.T) {
+		assert.Equal(t, []string{EventCategoryCreated}, eventTypes(AuditEntityCategory, AuditActionCreate, map[string]FieldChange{"name": {After: "Shoes"}}))
+		assert.Equal(t, []string{EventProductDeleted}, eventTypes(AuditEntityProduct, AuditActionDelete, map[string]FieldChange{"deleted_at": {After: "2024-03-01T12:00:00Z"}}))
+		assert.Equal(t, []string{EventVariantRestored}, eventTypes(AuditEntityVariant, AuditActionRestore, map[string]FieldChange{"deleted_at": {Before: "2024-03-01T12:00:00Z"}}))
+	})
+
+	t.Run("adds a price change event when the price changed", func(t *testing.T) {
+		changes := map[string]FieldChange{"price": {Before: "10.00", After: "12.00"}}
+
+		assert.Equal(t, []string{EventProductUpdated, EventProductPriceChanged}, eventTypes(AuditEntityProduct, AuditActionUpdate, changes))
+		assert.Equal(t, []string{EventVariantUpdated, EventVariantPriceChanged}, eventTypes(AuditEntityVariant, AuditActionUpdate, changes))
+	})
+
+	t.Run("raises no price change event for other fields", func(t *testing.T) {
+		changes := map[string]FieldChange{"category": {Before: "shoes", After: "bags"}}
+
+		assert.Equal(t, []string{EventProductUpdated}, eventTypes(AuditEntityProduct, AuditActionUpdate, changes))
+	})
+}
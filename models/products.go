@@ -1,7 +1,10 @@
 package models
 
 import (
+	"time"
+
 	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
 )
 
 type Product struct {
@@ -11,8 +14,28 @@ type Product struct {
 	CategoryID *uint           `gorm:"null"`
 	Category   *Category       `gorm:"foreignKey:CategoryID"`
 	Variants   []Variant       `gorm:"foreignKey:ProductID"`
+	Version    uint            `gorm:"not null;default:1"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	DeletedAt  gorm.DeletedAt `gorm:"index"`
+	// ChangeXID is the transaction that last wrote the row; it orders the change feed.
+	ChangeXID uint64 `gorm:"->"`
 }
 
 func (p *Product) TableName() string {
 	return "products"
 }
+
+// LastModified returns the latest update of the product, its category and its variants.
+func (p *Product) LastModified() time.Time {
+	latest := p.UpdatedAt
+	if p.Category != nil && p.Category.UpdatedAt.After(latest) {
+		latest = p.Category.UpdatedAt
+	}
+	for _, v := range p.Variants {
+		if v.UpdatedAt.After(latest) {
+			latest = v.UpdatedAt
+		}
+	}
+	return latest
+}
@@ -1,52 +1,370 @@
 package models
 
 import (
+	"context"
+	"errors"
+	"time"
+
 	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ProductsRepository struct {
-	db *gorm.DB
+	db           *gorm.DB
+	queryTimeout time.Duration
 }
 
-func NewProductsRepository(db *gorm.DB) *ProductsRepository {
+func NewProductsRepository(db *gorm.DB, queryTimeout time.Duration) *ProductsRepository {
 	return &ProductsRepository{
-		db: db,
+		db:           db,
+		queryTimeout: queryTimeout,
 	}
 }
 
-func (r *ProductsRepository) GetAll(offset, limit int, categoryCode string, priceLessThan *decimal.Decimal) ([]Product, int64, error) {
+// GetAll returns a page of products matching the filters. Soft-deleted
+// products, variants and categories are left out unless includeDeleted is set.
+func (r *ProductsRepository) GetAll(ctx context.Context, offset, limit int, categoryCode string, priceLessThan *decimal.Decimal, includeDeleted bool) ([]Product, int64, error) {
+	return r.find(ctx, offset, limit, ProductFilter{CategoryCode: categoryCode, PriceLessThan: priceLessThan, IncludeDeleted: includeDeleted}, "Category", "Variants")
+}
+
+// Find is GetAll without the category and variants of the products, for
+// callers that load those only when needed, see GetVariantsByProductIDs.
+func (r *ProductsRepository) Find(ctx context.Context, filter ProductFilter, offset, limit int) ([]Product, int64, error) {
+	return r.find(ctx, offset, limit, filter)
+}
+
+func (r *ProductsRepository) find(ctx context.Context, offset, limit int, filter ProductFilter, preloads ...string) ([]Product, int64, error) {
+	db, cancel := withTimeout(ctx, r.db, r.queryTimeout)
+	defer cancel()
+
 	var products []Product
 	var total int64
 
-	query := r.db.Model(&Product{})
+	query := scoped(db, filter.IncludeDeleted).Model(&Product{})
 
-	if categoryCode != "" {
+	if filter.CategoryCode != "" {
 		query = query.Joins("JOIN categories ON categories.id = products.category_id").
-			Where("categories.code = ?", categoryCode)
+			Where("categories.code = ?", filter.CategoryCode)
+		if !filter.IncludeDeleted {
+			query = query.Where("categories.deleted_at IS NULL")
+		}
 	}
 
-	if priceLessThan != nil {
-		query = query.Where("products.price < ?", priceLessThan)
+	if filter.PriceLessThan != nil {
+		query = query.Where("products.price < ?", filter.PriceLessThan)
 	}
 
 	if err := query.Count(&total).Error; err != nil {
-		return nil, 0, err
+		return nil, 0, translateError(err, "product")
 	}
 
-	if err := query.Preload("Category").Preload("Variants").
+	for _, preload := range preloads {
+		query = query.Preload(preload)
+	}
+	if err := query.Order("products.id").
 		Offset(offset).Limit(limit).
 		Find(&products).Error; err != nil {
-		return nil, 0, err
+		return nil, 0, translateError(err, "product")
 	}
 
 	return products, total, nil
 }
 
-func (r *ProductsRepository) GetByCode(code string) (*Product, error) {
+// GetVariantsByProductIDs returns the live variants of the given products in
+// one query, ordered by product.
+func (r *ProductsRepository) GetVariantsByProductIDs(ctx context.Context, productIDs []uint) ([]Variant, error) {
+	db, cancel := withTimeout(ctx, r.db, r.queryTimeout)
+	defer cancel()
+
+	var variants []Variant
+	if err := db.Where("product_id IN ?", productIDs).Order("product_id, id").Find(&variants).Error; err != nil {
+		return nil, translateError(err, "variant")
+	}
+	return variants, nil
+}
+
+func (r *ProductsRepository) GetByCode(ctx context.Context, code string, includeDeleted bool) (*Product, error) {
+	db, cancel := withTimeout(ctx, r.db, r.queryTimeout)
+	defer cancel()
+
 	var product Product
-	if err := r.db.Preload("Category").Preload("Variants").Where("code = ?", code).First(&product).Error; err != nil {
-		return nil, err
+	if err := scoped(db, includeDeleted).Preload("Category").Preload("Variants").Where("code = ?", code).First(&product).Error; err != nil {
+		return nil, translateError(err, "product")
+	}
+	return &product, nil
+}
+
+// Update applies changes to the product with code if it is still at version,
+// and returns the product as stored afterwards.
+func (r *ProductsRepository) Update(ctx context.Context, code string, version uint, changes ProductChanges) (*Product, error) {
+	db, cancel := withTimeout(ctx, r.db, r.queryTimeout)
+	defer cancel()
+
+	var product Product
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var before Product
+		if err := lockRow(tx.Preload("Category"), &before, "code = ?", code); err != nil {
+			return err
+		}
+
+		updates := map[string]any{"version": gorm.Expr("version + 1"), "updated_at": time.Now()}
+		if changes.Price != nil {
+			updates["price"] = *changes.Price
+		}
+		if changes.CategoryCode != nil {
+			categoryID, err := categoryIDByCode(tx, *changes.CategoryCode)
+			if err != nil {
+				return err
+			}
+			updates["category_id"] = categoryID
+		}
+
+		result := whereVersion(tx.Model(&Product{}).Where("code = ?", code), version).Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return staleOrMissing(tx, &Product{}, "product", "code = ?", code)
+		}
+		if err := tx.Preload("Category").Preload("Variants").Where("code = ?", code).First(&product).Error; err != nil {
+			return err
+		}
+		return recordChange(tx, AuditEntityProduct, code, AuditActionUpdate, before.auditState(), product.auditState())
+	})
+	if err != nil {
+		return nil, translateError(err, "product")
 	}
 	return &product, nil
 }
+
+// Delete soft-deletes the product with code if it is still at version. Its
+// variants are hidden with it and come back when it is restored.
+func (r *ProductsRepository) Delete(ctx context.Context, code string, version uint) error {
+	db, cancel := withTimeout(ctx, r.db, r.queryTimeout)
+	defer cancel()
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var before Product
+		if err := lockRow(tx.Preload("Category"), &before, "code = ?", code); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		result := whereVersion(tx.Model(&Product{}).Where("code = ?", code), version).
+			Updates(map[string]any{"deleted_at": now, "version": gorm.Expr("version + 1"), "updated_at": now})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return staleOrMissing(tx, &Product{}, "product", "code = ?", code)
+		}
+
+		after := before
+		after.DeletedAt = gorm.DeletedAt{Time: now, Valid: true}
+		return recordChange(tx, AuditEntityProduct, code, AuditActionDelete, before.auditState(), after.auditState())
+	})
+	return translateError(err, "product")
+}
+
+// Restore brings back the soft-deleted product with code. A product whose
+// category has been deleted since cannot be restored until the category is.
+func (r *ProductsRepository) Restore(ctx context.Context, code string) (*Product, error) {
+	db, cancel := withTimeout(ctx, r.db, r.queryTimeout)
+	defer cancel()
+
+	var product Product
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var before Product
+		if err := lockRow(tx.Unscoped().Preload("Category"), &before, "code = ?", code); err != nil {
+			return err
+		}
+
+		result := tx.Unscoped().Model(&Product{}).Where("code = ? AND deleted_at IS NOT NULL", code).
+			Updates(map[string]any{"deleted_at": nil, "version": gorm.Expr("version + 1"), "updated_at": time.Now()})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return deletedOrMissing(tx, &Product{}, "product", "code = ?", code)
+		}
+		if err := tx.Preload("Category").Preload("Variants").Where("code = ?", code).First(&product).Error; err != nil {
+			return err
+		}
+		if product.CategoryID != nil && product.Category == nil {
+			return &Error{Kind: ErrConflict, Message: "the product's category is deleted, restore it first"}
+		}
+		return recordChange(tx, AuditEntityProduct, code, AuditActionRestore, before.auditState(), product.auditState())
+	})
+	if err != nil {
+		return nil, translateError(err, "product")
+	}
+	return &product, nil
+}
+
+// GetVariant returns the variant with sku of the product with code, together
+// with its product. Soft-deleted variants and products are only found with includeDeleted.
+func (r *ProductsRepository) GetVariant(ctx context.Context, code, sku string, includeDeleted bool) (*Variant, error) {
+	db, cancel := withTimeout(ctx, r.db, r.queryTimeout)
+	defer cancel()
+
+	query := scoped(db, includeDeleted).Preload("Product").
+		Joins("JOIN products ON products.id = product_variants.product_id").
+		Where("products.code = ? AND product_variants.sku = ?", code, sku)
+	if !includeDeleted {
+		query = query.Where("products.deleted_at IS NULL")
+	}
+
+	var variant Variant
+	if err := query.First(&variant).Error; err != nil {
+		return nil, translateError(err, "variant")
+	}
+	return &variant, nil
+}
+
+// UpdateVariant applies changes to a variant if it is still at version. The
+// product's version is bumped as well, since variants are part of it.
+func (r *ProductsRepository) UpdateVariant(ctx context.Context, code, sku string, version uint, changes VariantChanges) (*Variant, error) {
+	db, cancel := withTimeout(ctx, r.db, r.queryTimeout)
+	defer cancel()
+
+	var variant Variant
+	err := db.Transaction(func(tx *gorm.DB) error {
+		productID, err := touchProduct(tx, code)
+		if err != nil {
+			return err
+		}
+
+		var before Variant
+		if err := lockRow(tx, &before, "product_id = ? AND sku = ?", productID, sku); err != nil {
+			return err
+		}
+
+		updates := map[string]any{"version": gorm.Expr("version + 1"), "updated_at": time.Now()}
+		if changes.Name != nil {
+			updates["name"] = *changes.Name
+		}
+		if changes.Price != nil {
+			updates["price"] = *changes.Price
+		}
+
+		result := whereVersion(tx.Model(&Variant{}).Where("product_id = ? AND sku = ?", productID, sku), version).Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return staleOrMissing(tx, &Variant{}, "variant", "product_id = ? AND sku = ?", productID, sku)
+		}
+		if err := tx.Preload("Product").Where("product_id = ? AND sku = ?", productID, sku).First(&variant).Error; err != nil {
+			return err
+		}
+		return recordChange(tx, AuditEntityVariant, sku, AuditActionUpdate, before.auditState(code), variant.auditState(code))
+	})
+	if err != nil {
+		return nil, translateError(err, "variant")
+	}
+	return &variant, nil
+}
+
+// DeleteVariant soft-deletes a variant if it is still at version and bumps the product's version.
+func (r *ProductsRepository) DeleteVariant(ctx context.Context, code, sku string, version uint) error {
+	db, cancel := withTimeout(ctx, r.db, r.queryTimeout)
+	defer cancel()
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		productID, err := touchProduct(tx, code)
+		if err != nil {
+			return err
+		}
+
+		var before Variant
+		if err := lockRow(tx, &before, "product_id = ? AND sku = ?", productID, sku); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		result := whereVersion(tx.Model(&Variant{}).Where("product_id = ? AND sku = ?", productID, sku), version).
+			Updates(map[string]any{"deleted_at": now, "version": gorm.Expr("version + 1"), "updated_at": now})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return staleOrMissing(tx, &Variant{}, "variant", "product_id = ? AND sku = ?", productID, sku)
+		}
+
+		after := before
+		after.DeletedAt = gorm.DeletedAt{Time: now, Valid: true}
+		return recordChange(tx, AuditEntityVariant, sku, AuditActionDelete, before.auditState(code), after.auditState(code))
+	})
+	return translateError(err, "variant")
+}
+
+// RestoreVariant brings back a soft-deleted variant of a product that is not
+// deleted itself, and bumps the product's version.
+func (r *ProductsRepository) RestoreVariant(ctx context.Context, code, sku string) (*Variant, error) {
+	db, cancel := withTimeout(ctx, r.db, r.queryTimeout)
+	defer cancel()
+
+	var variant Variant
+	err := db.Transaction(func(tx *gorm.DB) error {
+		productID, err := touchProduct(tx, code)
+		if err != nil {
+			return err
+		}
+
+		var before Variant
+		if err := lockRow(tx.Unscoped(), &before, "product_id = ? AND sku = ?", productID, sku); err != nil {
+			return err
+		}
+
+		result := tx.Unscoped().Model(&Variant{}).Where("product_id = ? AND sku = ? AND deleted_at IS NOT NULL", productID, sku).
+			Updates(map[string]any{"deleted_at": nil, "version": gorm.Expr("version + 1"), "updated_at": time.Now()})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return deletedOrMissing(tx, &Variant{}, "variant", "product_id = ? AND sku = ?", productID, sku)
+		}
+		if err := tx.Preload("Product").Where("product_id = ? AND sku = ?", productID, sku).First(&variant).Error; err != nil {
+			return err
+		}
+		return recordChange(tx, AuditEntityVariant, sku, AuditActionRestore, before.auditState(code), variant.auditState(code))
+	})
+	if err != nil {
+		return nil, translateError(err, "variant")
+	}
+	return &variant, nil
+}
+
+// touchProduct bumps the version of the product with code and returns its ID;
+// soft-deleted products count as missing.
+func touchProduct(tx *gorm.DB, code string) (uint, error) {
+	var product Product
+	result := tx.Model(&product).Clauses(clause.Returning{Columns: []clause.Column{{Name: "id"}}}).
+		Where("code = ?", code).
+		Updates(map[string]any{"version": gorm.Expr("version + 1"), "updated_at": time.Now()})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return 0, &Error{Kind: ErrNotFound, Message: "product not found"}
+	}
+	return product.ID, nil
+}
+
+// categoryIDByCode resolves a category code for assignment; "" resolves to no category.
+func categoryIDByCode(tx *gorm.DB, code string) (*uint, error) {
+	if code == "" {
+		return nil, nil
+	}
+	// The share lock makes a concurrent deletion of the category wait for
+	// this assignment, so it sees the product and refuses.
+	var category Category
+	if err := tx.Select("id").Clauses(clause.Locking{Strength: "SHARE"}).Where("code = ?", code).First(&category).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, &Error{Kind: ErrValidation, Message: "category " + code + " does not exist"}
+		}
+		return nil, err
+	}
+	return &category.ID, nil
+}
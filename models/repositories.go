@@ -1,14 +1,171 @@
 package models
 
-import "github.com/shopspring/decimal"
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AnyVersion makes an update or delete skip the version check, as for If-Match: *.
+const AnyVersion uint = 0
 
 type ProductRepository interface {
-	GetAll(offset, limit int, categoryCode string, priceLessThan *decimal.Decimal) ([]Product, int64, error)
-	GetByCode(code string) (*Product, error)
+	GetAll(ctx context.Context, offset, limit int, categoryCode string, priceLessThan *decimal.Decimal, includeDeleted bool) ([]Product, int64, error)
+	Find(ctx context.Context, filter ProductFilter, offset, limit int) ([]Product, int64, error)
+	GetByCode(ctx context.Context, code string, includeDeleted bool) (*Product, error)
+	GetVariantsByProductIDs(ctx context.Context, productIDs []uint) ([]Variant, error)
+	Update(ctx context.Context, code string, version uint, changes ProductChanges) (*Product, error)
+	Delete(ctx context.Context, code string, version uint) error
+	Restore(ctx context.Context, code string) (*Product, error)
+	GetVariant(ctx context.Context, code, sku string, includeDeleted bool) (*Variant, error)
+	UpdateVariant(ctx context.Context, code, sku string, version uint, changes VariantChanges) (*Variant, error)
+	DeleteVariant(ctx context.Context, code, sku string, version uint) error
+	RestoreVariant(ctx context.Context, code, sku string) (*Variant, error)
 }
 
 type CategoryRepository interface {
-	GetAll() ([]Category, error)
-	Create(category *Category) error
+	GetAll(ctx context.Context, includeDeleted bool) ([]Category, error)
+	GetByCode(ctx context.Context, code string, includeDeleted bool) (*Category, error)
+	GetByIDs(ctx context.Context, ids []uint) ([]Category, error)
+	Create(ctx context.Context, category *Category) error
+	Update(ctx context.Context, code string, version uint, changes CategoryChanges) (*Category, error)
+	Delete(ctx context.Context, code string, version uint) error
+	Restore(ctx context.Context, code string) (*Category, error)
+}
+
+// ProductFilter narrows a product listing; zero fields match every product.
+type ProductFilter struct {
+	CategoryCode   string
+	PriceLessThan  *decimal.Decimal
+	IncludeDeleted bool
+}
+
+// ProductChanges lists the fields an update sets; nil fields are left unchanged.
+type ProductChanges struct {
+	Price *decimal.Decimal
+	// CategoryCode assigns the product to another category; "" removes it from its category.
+	CategoryCode *string
+}
+
+// VariantChanges lists the fields an update sets; nil fields are left unchanged.
+type VariantChanges struct {
+	Name *string
+	// Price overrides the product price; zero makes the variant inherit it.
+	Price *decimal.Decimal
+}
+
+// CategoryChanges lists the fields an update sets; nil fields are left unchanged.
+type CategoryChanges struct {
+	Name *string
+}
+
+type ChangeRepository interface {
+	GetChanges(ctx context.Context, cursor ChangeCursor, limit int) (*Changes, error)
+}
+
+type APIKeyRepository interface {
+	GetActiveByHash(ctx context.Context, hash string) (*APIKey, error)
+}
+
+type AuditEntryRepository interface {
+	GetAll(ctx context.Context, offset, limit int, entity, code string) ([]AuditEntry, int64, error)
+}
+
+type OutboxEventRepository interface {
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]OutboxEvent, error)
+	MarkPublished(ctx context.Context, id uint) error
+	MarkFailed(ctx context.Context, id uint, retryAt *time.Time, reason string) error
+	DeleteFinishedBefore(ctx context.Context, t time.Time) (int64, error)
 }
 
+type WebhookSubscriptionRepository interface {
+	GetAll(ctx context.Context) ([]WebhookSubscription, error)
+	GetByID(ctx context.Context, id uint) (*WebhookSubscription, error)
+	Create(ctx context.Context, subscription *WebhookSubscription) error
+	Update(ctx context.Context, id uint, version uint, changes WebhookChanges) (*WebhookSubscription, error)
+	Delete(ctx context.Context, id uint, version uint) error
+}
+
+type WebhookDeliveryRepository interface {
+	Enqueue(ctx context.Context, deliveries []WebhookDelivery) error
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]WebhookDelivery, error)
+	MarkDelivered(ctx context.Context, id uint, responseStatus int) error
+	MarkFailed(ctx context.Context, id uint, retryAt *time.Time, responseStatus int, reason string) error
+	GetAll(ctx context.Context, subscriptionID uint, status string, offset, limit int) ([]WebhookDelivery, int64, error)
+	Redeliver(ctx context.Context, subscriptionID, id uint) (*WebhookDelivery, error)
+	DeleteFinishedBefore(ctx context.Context, t time.Time) (int64, error)
+}
+
+type IdempotencyKeyRepository interface {
+	Reserve(ctx context.Context, key *IdempotencyKey) (*IdempotencyKey, error)
+	Complete(ctx context.Context, key *IdempotencyKey) error
+	Release(ctx context.Context, id uint) error
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+// whereVersion restricts query to rows still at version, unless it is AnyVersion.
+func whereVersion(query *gorm.DB, version uint) *gorm.DB {
+	if version == AnyVersion {
+		return query
+	}
+	return query.Where("version = ?", version)
+}
+
+// staleOrMissing explains why a compare-and-swap statement matched no row:
+// either the row does not exist or it has moved past the expected version.
+func staleOrMissing(tx *gorm.DB, model any, entity string, query string, args ...any) error {
+	var count int64
+	if err := tx.Model(model).Where(query, args...).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return &Error{Kind: ErrNotFound, Message: entity + " not found"}
+	}
+	return &Error{Kind: ErrPreconditionFailed, Message: entity + " has been modified since it was read"}
+}
+
+// lockRow loads the row matching query into dest and locks it until the
+// transaction ends, so the state the audit log records as before is the one
+// the change replaces. A missing row leaves dest untouched; the change that
+// follows reports it.
+func lockRow(tx *gorm.DB, dest any, query string, args ...any) error {
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where(query, args...).First(dest).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	return err
+}
+
+// deletedOrMissing explains why a restore matched no soft-deleted row: either
+// the row does not exist at all or it was never deleted.
+func deletedOrMissing(tx *gorm.DB, model any, entity string, query string, args ...any) error {
+	var count int64
+	if err := tx.Unscoped().Model(model).Where(query, args...).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return &Error{Kind: ErrNotFound, Message: entity + " not found"}
+	}
+	return &Error{Kind: ErrConflict, Message: entity + " is not deleted"}
+}
+
+// scoped drops the soft-delete filter from db when deleted rows were asked for.
+func scoped(db *gorm.DB, includeDeleted bool) *gorm.DB {
+	if includeDeleted {
+		return db.Unscoped()
+	}
+	return db
+}
+
+// withTimeout scopes db to ctx, bounding every statement by timeout when it is positive.
+func withTimeout(ctx context.Context, db *gorm.DB, timeout time.Duration) (*gorm.DB, context.CancelFunc) {
+	if timeout <= 0 {
+		return db.WithContext(ctx), func() {}
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return db.WithContext(ctx), cancel
+}
@@ -1,7 +1,10 @@
 package models
 
 import (
+	"time"
+
 	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
 )
 
 // Variant represents a product variant in the catalog.
@@ -13,8 +16,24 @@ type Variant struct {
 	Name      string          `gorm:"not null"`
 	SKU       string          `gorm:"uniqueIndex;not null"`
 	Price     decimal.Decimal `gorm:"type:decimal(10,2);null"`
+	Product   *Product        `gorm:"foreignKey:ProductID"`
+	Version   uint            `gorm:"not null;default:1"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+	// ChangeXID is the transaction that last wrote the row; it orders the change feed.
+	ChangeXID uint64 `gorm:"->"`
 }
 
 func (v *Variant) TableName() string {
 	return "product_variants"
 }
+
+// LastModified returns the latest update of the variant and of the product
+// whose price it inherits.
+func (v *Variant) LastModified() time.Time {
+	if v.Product != nil && v.Product.UpdatedAt.After(v.UpdatedAt) {
+		return v.Product.UpdatedAt
+	}
+	return v.UpdatedAt
+}
@@ -0,0 +1,206 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type WebhookDeliveriesRepository struct {
+	db           *gorm.DB
+	queryTimeout time.Duration
+}
+
+func NewWebhookDeliveriesRepository(db *gorm.DB, queryTimeout time.Duration) *WebhookDeliveriesRepository {
+	return &WebhookDeliveriesRepository{
+		db:           db,
+		queryTimeout: queryTimeout,
+	}
+}
+
+// Enqueue stores pending deliveries. A delivery of an event the subscription
+// already has is skipped, so an event published twice is delivered once.
+func (r *WebhookDeliveriesRepository) Enqueue(ctx context.Context, deliveries []WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	db, cancel := withTimeout(ctx, r.db, r.queryTimeout)
+	defer cancel()
+
+	err := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "subscription_id"}, {Name: "event_id"}},
+		DoNothing: true,
+	}).Create(&deliveries).Error
+	return translateError(err, "webhook delivery")
+}
+
+// claimDeliveriesQuery leases the oldest due deliveries of active
+// subscriptions that are the first pending delivery of their aggregate to
+// their subscription, so every subscriber gets an aggregate's events in order.
+const claimDeliveriesQuery = `
+UPDATE webhook_deliveries SET claimed_until = ?, attempts = attempts + 1, updated_at = ?
+WHERE id IN (
+    SELECT d.id FROM webhook_deliveries d
+    JOIN webhook_subscriptions s ON s.id = d.subscription_id AND s.active
+    WHERE d.status = 'pending'
+      AND d.next_attempt_at <= ?
+      AND (d.claimed_until IS NULL OR d.claimed_until < ?)
+      AND NOT EXISTS (
+          SELECT 1 FROM webhook_deliveries p
+          WHERE p.subscription_id = d.subscription_id
+            AND p.aggregate_type = d.aggregate_type
+            AND p.aggregate_id = d.aggregate_id
+            AND p.status = 'pending'
+            AND p.id < d.id
+      )
+    ORDER BY d.id
+    LIMIT ?
+    FOR UPDATE OF d SKIP LOCKED
+)
+RETURNING *`
+
+// Claim leases up to limit due deliveries together with their subscriptions.
+// Claimed deliveries are not handed out again until they are marked or the
+// lease runs out.
+func (r *WebhookDeliveriesRepository) Claim(ctx context.Context, limit int, lease time.Duration) ([]WebhookDelivery, error) {
+	db, cancel := withTimeout(ctx, r.db, r.queryTimeout)
+	defer cancel()
+
+	now := time.Now()
+	var deliveries []WebhookDelivery
+	if err := db.Raw(claimDeliveriesQuery, now.Add(lease), now, now, now, limit).Scan(&deliveries).Error; err != nil {
+		return nil, translateError(err, "webhook delivery")
+	}
+	if len(deliveries) == 0 {
+		return deliveries, nil
+	}
+
+	ids := make([]uint, len(deliveries))
+	for i, d := range deliveries {
+		ids[i] = d.SubscriptionID
+	}
+	var subscriptions []WebhookSubscription
+	if err := db.Where("id IN ?", ids).Find(&subscriptions).Error; err != nil {
+		return nil, translateError(err, "webhook")
+	}
+	byID := make(map[uint]*WebhookSubscription, len(subscriptions))
+	for i := range subscriptions {
+		byID[subscriptions[i].ID] = &subscriptions[i]
+	}
+	for i := range deliveries {
+		deliveries[i].Subscription = byID[deliveries[i].SubscriptionID]
+	}
+	return deliveries, nil
+}
+
+// MarkDelivered records that the subscriber accepted the delivery.
+func (r *WebhookDeliveriesRepository) MarkDelivered(ctx context.Context, id uint, responseStatus int) error {
+	db, cancel := withTimeout(ctx, r.db, r.queryTimeout)
+	defer cancel()
+
+	now := time.Now()
+	err := db.Model(&WebhookDelivery{}).Where("id = ?", id).Updates(map[string]any{
+		"status":          DeliveryDelivered,
+		"response_status": responseStatus,
+		"last_error":      "",
+		"claimed_until":   nil,
+		"delivered_at":    now,
+		"updated_at":      now,
+	}).Error
+	return translateError(err, "webhook delivery")
+}
+
+// MarkFailed records a failed attempt and schedules the next one at retryAt,
+// or marks the delivery dead when retryAt is nil.
+func (r *WebhookDeliveriesRepository) MarkFailed(ctx context.Context, id uint, retryAt *time.Time, responseStatus int, reason string) error {
+	db, cancel := withTimeout(ctx, r.db, r.queryTimeout)
+	defer cancel()
+
+	updates := map[string]any{
+		"response_status": responseStatus,
+		"last_error":      reason,
+		"claimed_until":   nil,
+		"updated_at":      time.Now(),
+	}
+	if retryAt != nil {
+		updates["next_attempt_at"] = *retryAt
+	} else {
+		updates["status"] = DeliveryDead
+	}
+	err := db.Model(&WebhookDelivery{}).Where("id = ?", id).Updates(updates).Error
+	return translateError(err, "webhook delivery")
+}
+
+// GetAll returns a page of the subscription's deliveries, newest first. An
+// empty status matches every status.
+func (r *WebhookDeliveriesRepository) GetAll(ctx context.Context, subscriptionID uint, status string, offset, limit int) ([]WebhookDelivery, int64, error) {
+	db, cancel := withTimeout(ctx, r.db, r.queryTimeout)
+	defer cancel()
+
+	query := db.Model(&WebhookDelivery{}).Where("subscription_id = ?", subscriptionID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, translateError(err, "webhook delivery")
+	}
+
+	var deliveries []WebhookDelivery
+	if err := query.Order("id DESC").Offset(offset).Limit(limit).Find(&deliveries).Error; err != nil {
+		return nil, 0, translateError(err, "webhook delivery")
+	}
+	return deliveries, total, nil
+}
+
+// Redeliver schedules a delivered or dead delivery of the subscription to be
+// sent again right away, with a fresh set of attempts.
+func (r *WebhookDeliveriesRepository) Redeliver(ctx context.Context, subscriptionID, id uint) (*WebhookDelivery, error) {
+	db, cancel := withTimeout(ctx, r.db, r.queryTimeout)
+	defer cancel()
+
+	var delivery WebhookDelivery
+	err := db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&WebhookDelivery{}).
+			Where("id = ? AND subscription_id = ? AND status <> ?", id, subscriptionID, DeliveryPending).
+			Updates(map[string]any{
+				"status":          DeliveryPending,
+				"attempts":        0,
+				"next_attempt_at": now,
+				"claimed_until":   nil,
+				"delivered_at":    nil,
+				"updated_at":      now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			var count int64
+			if err := tx.Model(&WebhookDelivery{}).Where("id = ? AND subscription_id = ?", id, subscriptionID).Count(&count).Error; err != nil {
+				return err
+			}
+			if count == 0 {
+				return &Error{Kind: ErrNotFound, Message: "webhook delivery not found"}
+			}
+			return &Error{Kind: ErrConflict, Message: "webhook delivery is still pending"}
+		}
+		return tx.Where("id = ?", id).First(&delivery).Error
+	})
+	if err != nil {
+		return nil, translateError(err, "webhook delivery")
+	}
+	return &delivery, nil
+}
+
+// DeleteFinishedBefore removes delivered and dead deliveries last attempted before t.
+func (r *WebhookDeliveriesRepository) DeleteFinishedBefore(ctx context.Context, t time.Time) (int64, error) {
+	db, cancel := withTimeout(ctx, r.db, r.queryTimeout)
+	defer cancel()
+
+	result := db.Where("status <> ? AND updated_at < ?", DeliveryPending, t).Delete(&WebhookDelivery{})
+	return result.RowsAffected, translateError(result.Error, "webhook delivery")
+}
@@ -0,0 +1,105 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type WebhookSubscriptionsRepository struct {
+	db           *gorm.DB
+	queryTimeout time.Duration
+}
+
+func NewWebhookSubscriptionsRepository(db *gorm.DB, queryTimeout time.Duration) *WebhookSubscriptionsRepository {
+	return &WebhookSubscriptionsRepository{
+		db:           db,
+		queryTimeout: queryTimeout,
+	}
+}
+
+func (r *WebhookSubscriptionsRepository) GetAll(ctx context.Context) ([]WebhookSubscription, error) {
+	db, cancel := withTimeout(ctx, r.db, r.queryTimeout)
+	defer cancel()
+
+	var subscriptions []WebhookSubscription
+	if err := db.Order("id").Find(&subscriptions).Error; err != nil {
+		return nil, translateError(err, "webhook")
+	}
+	return subscriptions, nil
+}
+
+func (r *WebhookSubscriptionsRepository) GetByID(ctx context.Context, id uint) (*WebhookSubscription, error) {
+	db, cancel := withTimeout(ctx, r.db, r.queryTimeout)
+	defer cancel()
+
+	var subscription WebhookSubscription
+	if err := db.Where("id = ?", id).First(&subscription).Error; err != nil {
+		return nil, translateError(err, "webhook")
+	}
+	return &subscription, nil
+}
+
+func (r *WebhookSubscriptionsRepository) Create(ctx context.Context, subscription *WebhookSubscription) error {
+	db, cancel := withTimeout(ctx, r.db, r.queryTimeout)
+	defer cancel()
+
+	return translateError(db.Create(subscription).Error, "webhook")
+}
+
+// Update applies changes to the subscription with id if it is still at
+// version, and returns the subscription as stored afterwards.
+func (r *WebhookSubscriptionsRepository) Update(ctx context.Context, id uint, version uint, changes WebhookChanges) (*WebhookSubscription, error) {
+	db, cancel := withTimeout(ctx, r.db, r.queryTimeout)
+	defer cancel()
+
+	updates := map[string]any{"version": gorm.Expr("version + 1"), "updated_at": time.Now()}
+	if changes.URL != nil {
+		updates["url"] = *changes.URL
+	}
+	if changes.EventTypes != nil {
+		updates["event_types"] = StringList(*changes.EventTypes)
+	}
+	if changes.Secret != nil {
+		updates["secret"] = *changes.Secret
+	}
+	if changes.Active != nil {
+		updates["active"] = *changes.Active
+	}
+
+	var subscription WebhookSubscription
+	err := db.Transaction(func(tx *gorm.DB) error {
+		result := whereVersion(tx.Model(&WebhookSubscription{}).Where("id = ?", id), version).Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return staleOrMissing(tx, &WebhookSubscription{}, "webhook", "id = ?", id)
+		}
+		return tx.Where("id = ?", id).First(&subscription).Error
+	})
+	if err != nil {
+		return nil, translateError(err, "webhook")
+	}
+	return &subscription, nil
+}
+
+// Delete removes the subscription with id if it is still at version, together
+// with its deliveries.
+func (r *WebhookSubscriptionsRepository) Delete(ctx context.Context, id uint, version uint) error {
+	db, cancel := withTimeout(ctx, r.db, r.queryTimeout)
+	defer cancel()
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		result := whereVersion(tx.Where("id = ?", id), version).Delete(&WebhookSubscription{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return staleOrMissing(tx, &WebhookSubscription{}, "webhook", "id = ?", id)
+		}
+		return nil
+	})
+	return translateError(err, "webhook")
+}
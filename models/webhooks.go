@@ -0,0 +1,103 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"slices"
+	"time"
+)
+
+// AllEvents subscribes a webhook to every event type.
+const AllEvents = "*"
+
+// Delivery states. Pending deliveries are retried until they succeed or run
+// out of attempts, which leaves them dead.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryDead      = "dead"
+)
+
+// WebhookSubscription asks for the events of EventTypes to be POSTed to URL,
+// signed with Secret. Inactive subscriptions receive no new events, and their
+// pending deliveries wait until they are activated again.
+type WebhookSubscription struct {
+	ID         uint       `gorm:"primaryKey"`
+	URL        string     `gorm:"not null"`
+	EventTypes StringList `gorm:"type:jsonb;not null"`
+	Secret     string     `gorm:"not null"`
+	Active     bool       `gorm:"not null"`
+	Version    uint       `gorm:"not null;default:1"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+func (s *WebhookSubscription) TableName() string {
+	return "webhook_subscriptions"
+}
+
+// Wants reports whether the subscription is for events of eventType.
+func (s *WebhookSubscription) Wants(eventType string) bool {
+	return slices.Contains(s.EventTypes, AllEvents) || slices.Contains(s.EventTypes, eventType)
+}
+
+// WebhookChanges lists the fields an update sets; nil fields are left unchanged.
+type WebhookChanges struct {
+	URL        *string
+	EventTypes *[]string
+	Secret     *string
+	Active     *bool
+}
+
+// WebhookDelivery is one event to be POSTed to one subscription. Payload is
+// the request body; ResponseStatus is the status of the last attempt, or zero
+// when it got no response.
+type WebhookDelivery struct {
+	ID             uint            `gorm:"primaryKey"`
+	SubscriptionID uint            `gorm:"not null"`
+	EventID        uint            `gorm:"not null"`
+	EventType      string          `gorm:"not null"`
+	AggregateType  string          `gorm:"not null"`
+	AggregateID    string          `gorm:"not null"`
+	Payload        json.RawMessage `gorm:"type:jsonb;not null"`
+	Status         string          `gorm:"not null"`
+	Attempts       int             `gorm:"not null;default:0"`
+	NextAttemptAt  time.Time       `gorm:"not null"`
+	ClaimedUntil   *time.Time
+	ResponseStatus int
+	LastError      string
+	DeliveredAt    *time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+
+	Subscription *WebhookSubscription `gorm:"-"`
+}
+
+func (d *WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+// StringList is a list of strings stored as a JSON array.
+type StringList []string
+
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal([]string(l))
+	return string(b), err
+}
+
+func (l *StringList) Scan(src any) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, l)
+	case string:
+		return json.Unmarshal([]byte(v), l)
+	case nil:
+		*l = nil
+		return nil
+	}
+	return errors.New("unsupported type for StringList")
+}
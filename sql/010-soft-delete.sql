@@ -0,0 +1,7 @@
+ALTER TABLE products ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP NULL;
+ALTER TABLE product_variants ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP NULL;
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP NULL;
+
+CREATE INDEX IF NOT EXISTS products_deleted_at ON products (deleted_at);
+CREATE INDEX IF NOT EXISTS product_variants_deleted_at ON product_variants (deleted_at);
+CREATE INDEX IF NOT EXISTS categories_deleted_at ON categories (deleted_at);
@@ -0,0 +1,18 @@
+ALTER TABLE products ADD COLUMN IF NOT EXISTS change_xid xid8 NOT NULL DEFAULT pg_current_xact_id();
+ALTER TABLE product_variants ADD COLUMN IF NOT EXISTS change_xid xid8 NOT NULL DEFAULT pg_current_xact_id();
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS change_xid xid8 NOT NULL DEFAULT pg_current_xact_id();
+
+CREATE OR REPLACE FUNCTION track_change_xid() RETURNS trigger AS $$
+BEGIN
+    NEW.change_xid := pg_current_xact_id();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE TRIGGER products_change_xid BEFORE UPDATE ON products FOR EACH ROW EXECUTE FUNCTION track_change_xid();
+CREATE OR REPLACE TRIGGER product_variants_change_xid BEFORE UPDATE ON product_variants FOR EACH ROW EXECUTE FUNCTION track_change_xid();
+CREATE OR REPLACE TRIGGER categories_change_xid BEFORE UPDATE ON categories FOR EACH ROW EXECUTE FUNCTION track_change_xid();
+
+CREATE INDEX IF NOT EXISTS products_change_xid ON products (change_xid, id);
+CREATE INDEX IF NOT EXISTS product_variants_change_xid ON product_variants (change_xid, id);
+CREATE INDEX IF NOT EXISTS categories_change_xid ON categories (change_xid, id);
@@ -0,0 +1,7 @@
+ALTER TABLE outbox_events ADD COLUMN IF NOT EXISTS dead_at TIMESTAMP;
+
+DROP INDEX IF EXISTS outbox_events_pending;
+DROP INDEX IF EXISTS outbox_events_aggregate;
+CREATE INDEX IF NOT EXISTS outbox_events_pending ON outbox_events (id) WHERE published_at IS NULL AND dead_at IS NULL;
+CREATE INDEX IF NOT EXISTS outbox_events_aggregate ON outbox_events (aggregate_type, aggregate_id, id) WHERE published_at IS NULL AND dead_at IS NULL;
+CREATE INDEX IF NOT EXISTS outbox_events_dead_at ON outbox_events (dead_at);